@@ -1,11 +1,20 @@
 package main
 
 import (
+	"context"
+	"github.com/erdemcemal/basket-service/internal/admin"
+	"github.com/erdemcemal/basket-service/internal/auth"
 	"github.com/erdemcemal/basket-service/internal/basket"
+	"github.com/erdemcemal/basket-service/internal/config"
+	"github.com/erdemcemal/basket-service/internal/customer"
 	"github.com/erdemcemal/basket-service/internal/database"
 	basketstore "github.com/erdemcemal/basket-service/internal/store/basket"
+	campaignstore "github.com/erdemcemal/basket-service/internal/store/campaign"
+	customerstore "github.com/erdemcemal/basket-service/internal/store/customer"
 	transportHttp "github.com/erdemcemal/basket-service/internal/transport/http"
 	log "github.com/siruspen/logrus"
+	"os"
+	"time"
 )
 
 // App - contains the application configuration.
@@ -24,20 +33,41 @@ func (a *App) Run() error {
 		},
 	).Info("Setting up application")
 
-	var err error
-	db, err := database.NewDatabase()
+	cfg, err := config.Load(os.Args[1:])
 	if err != nil {
 		log.Error(err)
+		return err
+	}
+	db, err := database.NewDatabase(cfg.Database)
+	if err != nil {
+		log.Error(err)
+		return err
 	}
 	err = database.MigrateDB(db)
 	if err != nil {
 		log.Error(err)
 		return err
 	}
-	bs := basketstore.NewBasketStore(db)
-	basketService := basket.NewService(bs)
+	bs := basketstore.NewBasketStore(db, cfg.Store)
+	cs := campaignstore.NewCampaignStore(db)
+	campaignService := admin.NewService(cs)
+	// campaigns of the campaign file are stored only once, after that they are managed with the admin api
+	if err := campaignService.SeedCampaigns(context.Background(), cfg.Campaign.Definitions.Campaigns); err != nil {
+		log.Error(err)
+		return err
+	}
+	customerService := customer.NewService(customerstore.NewCustomerStore(db), cfg.Customer)
+	basketService := basket.NewService(bs, cs, customerService, cfg.Campaign, cfg.Tax, cfg.Currency, cfg.Basket)
+	// expired stock reservations of the abandoned baskets are released in the background
+	go basketService.SweepReservations(context.Background(), time.Duration(cfg.Store.ReservationSweepInterval))
+
+	verifier, err := auth.NewVerifier(cfg.Auth)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
 
-	handler := transportHttp.NewHandler(basketService)
+	handler := transportHttp.NewHandler(basketService, campaignService, customerService, verifier, cfg.Server)
 	if err := handler.Serve(); err != nil {
 		log.Error("Failed to set up server")
 		return err
@@ -0,0 +1,196 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/campaign"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/erdemcemal/basket-service/internal/models"
+	campaignstore "github.com/erdemcemal/basket-service/internal/store/campaign"
+	log "github.com/siruspen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCampaignNotFound      = errors.New("campaign not found")
+	ErrCampaignAlreadyExists = errors.New("campaign already exists")
+	ErrCampaignArchived      = errors.New("campaign is archived")
+	ErrInvalidCampaign       = errors.New("invalid campaign definition")
+	ErrInvalidCampaignStatus = errors.New("invalid campaign status")
+	ErrGettingCampaigns      = errors.New("error getting campaigns")
+	ErrSavingCampaign        = errors.New("error saving campaign")
+)
+
+// CampaignService - represents the campaign administration service
+type CampaignService interface {
+	GetCampaigns(ctx context.Context, status string) ([]dto.CampaignDTO, error)
+	GetCampaign(ctx context.Context, id string) (dto.CampaignDTO, error)
+	CreateCampaign(ctx context.Context, newCampaign dto.CreateCampaignDTO) (dto.CampaignDTO, error)
+	EnableCampaign(ctx context.Context, id string) (dto.CampaignDTO, error)
+	DisableCampaign(ctx context.Context, id string) (dto.CampaignDTO, error)
+	ArchiveCampaign(ctx context.Context, id string) (dto.CampaignDTO, error)
+}
+
+// Service - represents the campaign administration service implementation
+type Service struct {
+	store campaignstore.CampaignStore
+}
+
+// NewService - creates a new campaign administration service with the given store
+func NewService(store campaignstore.CampaignStore) *Service {
+	return &Service{store: store}
+}
+
+// SeedCampaigns - stores the given campaign definitions as active campaigns if there is no campaign in the store yet,
+// otherwise the stored campaigns are used and the definitions are ignored with a warning
+func (s *Service) SeedCampaigns(ctx context.Context, definitions []campaign.Definition) error {
+	count, err := s.store.CountCampaigns(ctx)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		if len(definitions) > 0 {
+			log.Warnf("%d campaigns are already stored, the %d campaigns of the campaign file are not seeded and changes of the file are ignored, "+
+				"the stored campaigns are managed with the admin api", count, len(definitions))
+		}
+		return nil
+	}
+	for _, definition := range definitions {
+		storedCampaign, err := definition.ToCampaign(models.CampaignStatusActive)
+		if err != nil {
+			return err
+		}
+		if err := s.store.CreateCampaign(ctx, storedCampaign); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetCampaigns - returns the campaigns with the given status, campaigns which are not archived if the status is empty
+func (s *Service) GetCampaigns(ctx context.Context, status string) ([]dto.CampaignDTO, error) {
+	statuses := []models.CampaignStatus{models.CampaignStatusActive, models.CampaignStatusDisabled}
+	if status != "" {
+		if !models.CampaignStatus(status).IsValid() {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCampaignStatus, status)
+		}
+		statuses = []models.CampaignStatus{models.CampaignStatus(status)}
+	}
+	storedCampaigns, err := s.store.GetCampaigns(ctx, statuses...)
+	if err != nil {
+		log.Errorf("error getting campaigns: %v", err)
+		return nil, ErrGettingCampaigns
+	}
+	dtoCampaigns := []dto.CampaignDTO{}
+	for _, storedCampaign := range storedCampaigns {
+		dtoCampaign, err := fromCampaign(storedCampaign)
+		if err != nil {
+			log.Error(err)
+			return nil, ErrGettingCampaigns
+		}
+		dtoCampaigns = append(dtoCampaigns, dtoCampaign)
+	}
+	return dtoCampaigns, nil
+}
+
+// GetCampaign - returns the campaign with the given id
+func (s *Service) GetCampaign(ctx context.Context, id string) (dto.CampaignDTO, error) {
+	storedCampaign, err := s.getCampaign(ctx, id)
+	if err != nil {
+		return dto.CampaignDTO{}, err
+	}
+	dtoCampaign, err := fromCampaign(storedCampaign)
+	if err != nil {
+		log.Error(err)
+		return dto.CampaignDTO{}, ErrGettingCampaigns
+	}
+	return dtoCampaign, nil
+}
+
+// CreateCampaign - validates the campaign definition and stores it, the campaign is active unless it is created disabled
+func (s *Service) CreateCampaign(ctx context.Context, newCampaign dto.CreateCampaignDTO) (dto.CampaignDTO, error) {
+	if err := newCampaign.Definition.Validate(); err != nil {
+		return dto.CampaignDTO{}, fmt.Errorf("%w: %v", ErrInvalidCampaign, err)
+	}
+	if _, err := s.store.GetCampaignByKey(ctx, newCampaign.Definition.ID); err == nil {
+		return dto.CampaignDTO{}, ErrCampaignAlreadyExists
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Error(err)
+		return dto.CampaignDTO{}, ErrGettingCampaigns
+	}
+	status := models.CampaignStatusActive
+	if newCampaign.Disabled {
+		status = models.CampaignStatusDisabled
+	}
+	storedCampaign, err := newCampaign.Definition.ToCampaign(status)
+	if err != nil {
+		return dto.CampaignDTO{}, fmt.Errorf("%w: %v", ErrInvalidCampaign, err)
+	}
+	if err := s.store.CreateCampaign(ctx, storedCampaign); err != nil {
+		log.Error(err)
+		return dto.CampaignDTO{}, ErrSavingCampaign
+	}
+	return s.GetCampaign(ctx, storedCampaign.Key)
+}
+
+// EnableCampaign - activates the campaign with the given id, so it is applied on baskets
+func (s *Service) EnableCampaign(ctx context.Context, id string) (dto.CampaignDTO, error) {
+	return s.changeStatus(ctx, id, models.CampaignStatusActive)
+}
+
+// DisableCampaign - disables the campaign with the given id, so it is not applied on baskets until it is enabled
+func (s *Service) DisableCampaign(ctx context.Context, id string) (dto.CampaignDTO, error) {
+	return s.changeStatus(ctx, id, models.CampaignStatusDisabled)
+}
+
+// ArchiveCampaign - archives the campaign with the given id, archived campaigns are kept but can't be enabled anymore
+func (s *Service) ArchiveCampaign(ctx context.Context, id string) (dto.CampaignDTO, error) {
+	return s.changeStatus(ctx, id, models.CampaignStatusArchived)
+}
+
+// changeStatus - changes the status of the campaign with the given id unless it is archived
+func (s *Service) changeStatus(ctx context.Context, id string, status models.CampaignStatus) (dto.CampaignDTO, error) {
+	storedCampaign, err := s.getCampaign(ctx, id)
+	if err != nil {
+		return dto.CampaignDTO{}, err
+	}
+	if storedCampaign.Status == models.CampaignStatusArchived {
+		return dto.CampaignDTO{}, ErrCampaignArchived
+	}
+	if err := s.store.UpdateCampaignStatus(ctx, id, status); err != nil {
+		log.Error(err)
+		return dto.CampaignDTO{}, ErrSavingCampaign
+	}
+	return s.GetCampaign(ctx, id)
+}
+
+// getCampaign - returns the stored campaign with the given id
+func (s *Service) getCampaign(ctx context.Context, id string) (models.Campaign, error) {
+	storedCampaign, err := s.store.GetCampaignByKey(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Campaign{}, ErrCampaignNotFound
+		}
+		log.Error(err)
+		return models.Campaign{}, ErrGettingCampaigns
+	}
+	return storedCampaign, nil
+}
+
+// fromCampaign - converts a stored campaign to a campaign dto
+func fromCampaign(storedCampaign models.Campaign) (dto.CampaignDTO, error) {
+	definition, err := campaign.DefinitionOf(storedCampaign)
+	if err != nil {
+		return dto.CampaignDTO{}, err
+	}
+	return dto.CampaignDTO{
+		ID:              storedCampaign.Key,
+		Status:          string(storedCampaign.Status),
+		Definition:      definition,
+		SpentAmount:     storedCampaign.SpentAmount,
+		RedemptionCount: storedCampaign.RedemptionCount,
+		CreatedAt:       storedCampaign.CreatedAt,
+		UpdatedAt:       storedCampaign.UpdatedAt,
+	}, nil
+}
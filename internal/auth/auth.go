@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/config"
+	"strings"
+	"time"
+)
+
+const (
+	// AlgorithmHS256 - tokens signed with hmac sha256 and the shared secret
+	AlgorithmHS256 = "HS256"
+	// AlgorithmRS256 - tokens signed with rsa sha256 and a key of the key set
+	AlgorithmRS256 = "RS256"
+	// defaultRolesClaim - claim the roles of the user are read from if no claim is configured
+	defaultRolesClaim = "roles"
+)
+
+var (
+	ErrMissingToken         = errors.New("bearer token is required")
+	ErrInvalidToken         = errors.New("invalid bearer token")
+	ErrMalformedToken       = fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	ErrUnsupportedAlgorithm = fmt.Errorf("%w: unsupported signing algorithm", ErrInvalidToken)
+	ErrInvalidSignature     = fmt.Errorf("%w: signature is not valid", ErrInvalidToken)
+	ErrUnknownKey           = fmt.Errorf("%w: signing key is not known", ErrInvalidToken)
+	ErrTokenExpired         = fmt.Errorf("%w: token is expired", ErrInvalidToken)
+	ErrTokenNotYetValid     = fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	ErrInvalidIssuer        = fmt.Errorf("%w: issuer is not accepted", ErrInvalidToken)
+	ErrInvalidAudience      = fmt.Errorf("%w: audience is not accepted", ErrInvalidToken)
+	ErrMissingSubject       = fmt.Errorf("%w: subject is required", ErrInvalidToken)
+	ErrReservedSubject      = fmt.Errorf("%w: subject is in the guest namespace", ErrInvalidToken)
+)
+
+// TokenVerifier - verifies bearer tokens and returns their claims
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (Claims, error)
+}
+
+// Claims - represents the registered claims of a token the service checks
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  Audience `json:"aud"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf"`
+	IssuedAt  int64    `json:"iat"`
+	// Roles - roles of the user read from the roles claim of the verifier
+	Roles []Role `json:"-"`
+}
+
+// Audience - represents the audience claim, which is a single string or a list of strings
+type Audience []string
+
+// header - represents the header of a token
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Verifier - verifies the signature, the issuer, the audience and the validity window of tokens
+type Verifier struct {
+	issuer   string
+	audience string
+	// secret - shared secret of the HS256 tokens, HS256 tokens are not accepted if it is empty
+	secret []byte
+	// keys - public keys of the RS256 tokens, RS256 tokens are not accepted if it is nil
+	keys KeySet
+	// leeway - clock skew allowed when the expiry and not before times are checked
+	leeway time.Duration
+	// rolesClaim - claim the roles of the user are read from, a string or a list of strings
+	rolesClaim string
+	now        func() time.Time
+}
+
+// NewVerifier - creates a token verifier with the given auth settings, the key set is loaded from the jwks file or url
+func NewVerifier(cfg config.AuthConfig) (*Verifier, error) {
+	verifier := &Verifier{
+		issuer:     cfg.Issuer,
+		audience:   cfg.Audience,
+		secret:     []byte(cfg.Secret),
+		leeway:     time.Duration(cfg.Leeway),
+		rolesClaim: cfg.RolesClaim,
+		now:        time.Now,
+	}
+	if verifier.rolesClaim == "" {
+		verifier.rolesClaim = defaultRolesClaim
+	}
+	switch {
+	case cfg.JWKSFile != "":
+		keys, err := LoadKeySetFile(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier.keys = keys
+	case cfg.JWKSURL != "":
+		verifier.keys = NewRemoteKeySet(cfg.JWKSURL, time.Duration(cfg.JWKSCacheTTL))
+	}
+	return verifier, nil
+}
+
+// Verify - checks the signature and the registered claims of the token and returns its claims
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformedToken
+	}
+	var tokenHeader header
+	if err := decodeSegment(parts[0], &tokenHeader); err != nil {
+		return Claims{}, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrMalformedToken
+	}
+	if err := v.verifySignature(ctx, tokenHeader, parts[0]+"."+parts[1], signature); err != nil {
+		return Claims{}, err
+	}
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, err
+	}
+	if err := v.verifyClaims(claims); err != nil {
+		return Claims{}, err
+	}
+	var rawClaims map[string]json.RawMessage
+	if err := decodeSegment(parts[1], &rawClaims); err != nil {
+		return Claims{}, err
+	}
+	if rolesClaim, ok := rawClaims[v.rolesClaim]; ok {
+		var roles stringOrList
+		if err := json.Unmarshal(rolesClaim, &roles); err != nil {
+			return Claims{}, ErrMalformedToken
+		}
+		for _, role := range roles {
+			claims.Roles = append(claims.Roles, Role(role))
+		}
+	}
+	return claims, nil
+}
+
+// verifySignature - checks the signature of the signed part of the token with the key of its algorithm
+func (v *Verifier) verifySignature(ctx context.Context, tokenHeader header, signed string, signature []byte) error {
+	switch tokenHeader.Algorithm {
+	case AlgorithmHS256:
+		if len(v.secret) == 0 {
+			return ErrUnsupportedAlgorithm
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrInvalidSignature
+		}
+		return nil
+	case AlgorithmRS256:
+		if v.keys == nil {
+			return ErrUnsupportedAlgorithm
+		}
+		key, err := v.keys.Key(ctx, tokenHeader.KeyID)
+		if err != nil {
+			return err
+		}
+		hashed := sha256.Sum256([]byte(signed))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature); err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, tokenHeader.Algorithm)
+}
+
+// verifyClaims - checks the issuer, the audience, the subject and the validity window of the claims
+func (v *Verifier) verifyClaims(claims Claims) error {
+	now := v.now()
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(v.leeway)) {
+		return ErrTokenExpired
+	}
+	if claims.NotBefore != 0 && now.Add(v.leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return ErrTokenNotYetValid
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return ErrInvalidIssuer
+	}
+	if v.audience != "" && !claims.Audience.Contains(v.audience) {
+		return ErrInvalidAudience
+	}
+	if claims.Subject == "" {
+		return ErrMissingSubject
+	}
+	// guest baskets are stored with the guest prefix, so a user can't take the basket of a guest
+	if IsGuestUserID(claims.Subject) {
+		return ErrReservedSubject
+	}
+	return nil
+}
+
+// Contains - checks if the audience contains the given audience
+func (a Audience) Contains(audience string) bool {
+	for _, value := range a {
+		if value == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalJSON - reads the audience from a json string or a list of strings
+func (a *Audience) UnmarshalJSON(data []byte) error {
+	return (*stringOrList)(a).UnmarshalJSON(data)
+}
+
+// stringOrList - represents a claim which is a single string or a list of strings
+type stringOrList []string
+
+// UnmarshalJSON - reads the claim from a json string or a list of strings
+func (l *stringOrList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = stringOrList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// decodeSegment - decodes a base64url encoded json segment of the token
+func decodeSegment(segment string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+// principalKey - is the context key of the authenticated user
+type principalKey struct{}
+
+// WithPrincipal - returns a copy of the context with the authenticated user
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom - returns the authenticated user of the context, false if the request is not authenticated
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// UserID - returns the id of the authenticated user of the context, empty if the request is not authenticated
+func UserID(ctx context.Context) string {
+	principal, _ := PrincipalFrom(ctx)
+	return principal.UserID
+}
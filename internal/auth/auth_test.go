@@ -0,0 +1,269 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func signHS256(t *testing.T, secret string, header map[string]interface{}, claims map[string]interface{}) string {
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, keyID string, claims map[string]interface{}) string {
+	signed := encodeSegment(t, map[string]interface{}{"alg": AlgorithmRS256, "kid": keyID}) + "." + encodeSegment(t, claims)
+	hashed := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatalf("Expected token to be signed, got %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeSegment(t *testing.T, value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Expected segment to be encoded, got %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func keySetJSON(key *rsa.PublicKey, keyID string) []byte {
+	data, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": keyID,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	return data
+}
+
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"sub": "7f6c43bc-14a2-4b3a-898c-ae27a1d41b8d",
+		"iss": "https://auth.example.com/",
+		"aud": "basket-service",
+		"exp": testNow.Add(time.Hour).Unix(),
+	}
+}
+
+func newTestVerifier(keys KeySet) *Verifier {
+	return &Verifier{
+		issuer:     "https://auth.example.com/",
+		audience:   "basket-service",
+		secret:     []byte("secret"),
+		keys:       keys,
+		leeway:     30 * time.Second,
+		rolesClaim: defaultRolesClaim,
+		now:        func() time.Time { return testNow },
+	}
+}
+
+func TestVerifier_Verify_HS256(t *testing.T) {
+	hs256 := map[string]interface{}{"alg": AlgorithmHS256, "typ": "JWT"}
+	tests := []struct {
+		name     string
+		header   map[string]interface{}
+		secret   string
+		claims   func(claims map[string]interface{})
+		expected error
+	}{
+		{name: "valid token", header: hs256, secret: "secret"},
+		{name: "audience list", header: hs256, secret: "secret", claims: func(c map[string]interface{}) { c["aud"] = []string{"other", "basket-service"} }},
+		{name: "expired within leeway", header: hs256, secret: "secret", claims: func(c map[string]interface{}) { c["exp"] = testNow.Add(-10 * time.Second).Unix() }},
+		{name: "expired", header: hs256, secret: "secret", claims: func(c map[string]interface{}) { c["exp"] = testNow.Add(-time.Minute).Unix() }, expected: ErrTokenExpired},
+		{name: "without expiry", header: hs256, secret: "secret", claims: func(c map[string]interface{}) { delete(c, "exp") }, expected: ErrTokenExpired},
+		{name: "not valid yet", header: hs256, secret: "secret", claims: func(c map[string]interface{}) { c["nbf"] = testNow.Add(time.Hour).Unix() }, expected: ErrTokenNotYetValid},
+		{name: "other issuer", header: hs256, secret: "secret", claims: func(c map[string]interface{}) { c["iss"] = "https://evil.example.com/" }, expected: ErrInvalidIssuer},
+		{name: "other audience", header: hs256, secret: "secret", claims: func(c map[string]interface{}) { c["aud"] = "other" }, expected: ErrInvalidAudience},
+		{name: "without subject", header: hs256, secret: "secret", claims: func(c map[string]interface{}) { delete(c, "sub") }, expected: ErrMissingSubject},
+		{name: "guest subject", header: hs256, secret: "secret", claims: func(c map[string]interface{}) { c["sub"] = GuestUserIDPrefix + "6f1e" }, expected: ErrReservedSubject},
+		{name: "wrong secret", header: hs256, secret: "guess", expected: ErrInvalidSignature},
+		{name: "none algorithm", header: map[string]interface{}{"alg": "none"}, secret: "secret", expected: ErrUnsupportedAlgorithm},
+	}
+	for _, test := range tests {
+		claims := validClaims()
+		if test.claims != nil {
+			test.claims(claims)
+		}
+		token := signHS256(t, test.secret, test.header, claims)
+		verified, err := newTestVerifier(nil).Verify(context.Background(), token)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("Expected %s to fail with %v, got %v", test.name, test.expected, err)
+			continue
+		}
+		if test.expected != nil && !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Expected %s error to be an invalid token error, got %v", test.name, err)
+		}
+		if err == nil && verified.Subject != claims["sub"] {
+			t.Errorf("Expected %s subject to be %s, got %s", test.name, claims["sub"], verified.Subject)
+		}
+	}
+}
+
+func TestVerifier_Verify_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ParseKeySet(keySetJSON(&key.PublicKey, "key-1"))
+	if err != nil {
+		t.Fatalf("Expected key set to be parsed, got %v", err)
+	}
+	verifier := newTestVerifier(keys)
+	if _, err := verifier.Verify(context.Background(), signRS256(t, key, "key-1", validClaims())); err != nil {
+		t.Errorf("Expected RS256 token to be valid, got %v", err)
+	}
+	if _, err := verifier.Verify(context.Background(), signRS256(t, key, "key-2", validClaims())); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Expected token of an unknown key to be rejected, got %v", err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifier.Verify(context.Background(), signRS256(t, otherKey, "key-1", validClaims())); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected token signed with another key to be rejected, got %v", err)
+	}
+	// a HS256 token signed with the public key must not be accepted as RS256
+	noSecret := newTestVerifier(keys)
+	noSecret.secret = nil
+	token := signHS256(t, string(keySetJSON(&key.PublicKey, "key-1")), map[string]interface{}{"alg": AlgorithmHS256, "kid": "key-1"}, validClaims())
+	if _, err := noSecret.Verify(context.Background(), token); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expected HS256 token to be rejected without a secret, got %v", err)
+	}
+}
+
+func TestRemoteKeySet_Key(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySet := keySetJSON(&oldKey.PublicKey, "old")
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write(keySet)
+	}))
+	defer server.Close()
+
+	now := testNow
+	remote := NewRemoteKeySet(server.URL, 10*time.Minute)
+	remote.now = func() time.Time { return now }
+	ctx := context.Background()
+	if _, err := remote.Key(ctx, "old"); err != nil {
+		t.Fatalf("Expected key to be fetched, got %v", err)
+	}
+	if _, err := remote.Key(ctx, "old"); err != nil || requests != 1 {
+		t.Errorf("Expected cached key to be used, got %d requests and %v", requests, err)
+	}
+
+	// the keys are rotated, the unknown key is fetched at most once a minute
+	keySet = keySetJSON(&newKey.PublicKey, "new")
+	if _, err := remote.Key(ctx, "new"); !errors.Is(err, ErrUnknownKey) || requests != 1 {
+		t.Errorf("Expected unknown key not to be fetched again within a minute, got %d requests and %v", requests, err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := remote.Key(ctx, "new"); err != nil || requests != 2 {
+		t.Errorf("Expected rotated key to be fetched, got %d requests and %v", requests, err)
+	}
+
+	now = now.Add(11 * time.Minute)
+	if _, err := remote.Key(ctx, "new"); err != nil || requests != 3 {
+		t.Errorf("Expected key set to be fetched again after the cache ttl, got %d requests and %v", requests, err)
+	}
+}
+
+func TestVerifier_Verify_Roles(t *testing.T) {
+	tests := []struct {
+		name     string
+		roles    interface{}
+		expected []Role
+	}{
+		{name: "without roles", expected: []Role{RoleShopper}},
+		{name: "single role", roles: "support", expected: []Role{RoleSupport}},
+		{name: "role list", roles: []string{"support", "merchandiser"}, expected: []Role{RoleSupport, RoleMerchandiser}},
+	}
+	for _, test := range tests {
+		claims := validClaims()
+		if test.roles != nil {
+			claims["roles"] = test.roles
+		}
+		token := signHS256(t, "secret", map[string]interface{}{"alg": AlgorithmHS256}, claims)
+		verified, err := newTestVerifier(nil).Verify(context.Background(), token)
+		if err != nil {
+			t.Fatalf("Expected token %s to be valid, got %v", test.name, err)
+		}
+		principal := NewPrincipal(verified)
+		if len(principal.Roles) != len(test.expected) {
+			t.Errorf("Expected %s roles to be %v, got %v", test.name, test.expected, principal.Roles)
+			continue
+		}
+		for i, role := range test.expected {
+			if principal.Roles[i] != role {
+				t.Errorf("Expected %s roles to be %v, got %v", test.name, test.expected, principal.Roles)
+			}
+		}
+	}
+}
+
+func TestPrincipal_HasAnyRole(t *testing.T) {
+	tests := []struct {
+		roles    []Role
+		required []Role
+		expected bool
+	}{
+		{roles: []Role{RoleShopper}, required: []Role{RoleSupport}, expected: false},
+		{roles: []Role{RoleSupport}, required: []Role{RoleSupport, RoleMerchandiser}, expected: true},
+		{roles: []Role{RoleMerchandiser}, required: []Role{RoleAdmin}, expected: false},
+		{roles: []Role{RoleAdmin}, required: []Role{RoleSupport}, expected: true},
+	}
+	for _, test := range tests {
+		if allowed := (Principal{Roles: test.roles}).HasAnyRole(test.required...); allowed != test.expected {
+			t.Errorf("Expected %v to be allowed for %v: %t, got %t", test.roles, test.required, test.expected, allowed)
+		}
+	}
+}
+
+func TestGuestPrincipal(t *testing.T) {
+	token, err := NewCartToken()
+	if err != nil {
+		t.Fatalf("Expected cart token to be created, got %v", err)
+	}
+	guest, err := GuestPrincipal(token)
+	if err != nil {
+		t.Fatalf("Expected cart token to be valid, got %v", err)
+	}
+	if !guest.Guest || !IsGuestUserID(guest.UserID) || strings.Contains(guest.UserID, token) || len(guest.Roles) != 0 {
+		t.Errorf("Expected a guest without roles identified with the hash of the token, got %+v", guest)
+	}
+	if again, _ := GuestPrincipal(token); again.UserID != guest.UserID {
+		t.Errorf("Expected the same cart token to belong to the same guest, got %s and %s", guest.UserID, again.UserID)
+	}
+	for _, invalid := range []string{"", "short", token + "a", strings.Repeat("*", len(token))} {
+		if _, err := GuestPrincipal(invalid); !errors.Is(err, ErrInvalidCartToken) {
+			t.Errorf("Expected cart token %q to be invalid, got %v", invalid, err)
+		}
+	}
+}
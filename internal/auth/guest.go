@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+const (
+	// GuestUserIDPrefix - prefix of the user ids the guest baskets are stored with
+	GuestUserIDPrefix = "guest:"
+	// cartTokenSize - random bytes of a cart token
+	cartTokenSize = 32
+)
+
+var (
+	ErrMissingCartToken = errors.New("cart token is required")
+	ErrInvalidCartToken = errors.New("invalid cart token")
+)
+
+// NewCartToken - creates a random opaque token the basket of a guest is identified with
+func NewCartToken() (string, error) {
+	token := make([]byte, cartTokenSize)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// GuestPrincipal - returns the guest the given cart token belongs to, the user id of the guest is a hash of the token,
+// so the stored baskets don't reveal the tokens
+func GuestPrincipal(cartToken string) (Principal, error) {
+	token, err := base64.RawURLEncoding.DecodeString(cartToken)
+	if err != nil || len(token) != cartTokenSize {
+		return Principal{}, ErrInvalidCartToken
+	}
+	hash := sha256.Sum256(token)
+	return Principal{UserID: GuestUserIDPrefix + hex.EncodeToString(hash[:]), Guest: true}, nil
+}
+
+// IsGuestUserID - checks if the user id belongs to a guest basket
+func IsGuestUserID(userId string) bool {
+	return strings.HasPrefix(userId, GuestUserIDPrefix)
+}
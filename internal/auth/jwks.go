@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultKeySetCacheTTL = 10 * time.Minute
+	// minKeySetRefreshInterval - the remote key set is fetched at most once in the interval for tokens of unknown keys
+	minKeySetRefreshInterval = time.Minute
+	keySetRequestTimeout     = 10 * time.Second
+)
+
+var (
+	ErrInvalidKeySet = errors.New("invalid json web key set")
+)
+
+// KeySet - returns the public keys of the RS256 tokens by their key id
+type KeySet interface {
+	Key(ctx context.Context, keyID string) (*rsa.PublicKey, error)
+}
+
+// jsonWebKey - represents a key of a json web key set
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+// StaticKeySet - represents a key set which doesn't change, such as a key set loaded from a file
+type StaticKeySet map[string]*rsa.PublicKey
+
+// RemoteKeySet - represents a key set fetched from a jwks url, keys are cached for the cache ttl
+// and fetched again earlier when a token is signed with an unknown key
+type RemoteKeySet struct {
+	url      string
+	cacheTTL time.Duration
+	client   *http.Client
+	mu       sync.Mutex
+	keys     StaticKeySet
+	// fetchedAt - time the keys are fetched at, attemptedAt - time the key set is requested at the last time
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	now         func() time.Time
+}
+
+// LoadKeySetFile - reads the json web key set of the given file
+func LoadKeySetFile(path string) (StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks file %s: %w", path, err)
+	}
+	return ParseKeySet(data)
+}
+
+// ParseKeySet - parses the rsa signing keys of a json web key set, keys of other types and uses are skipped
+func ParseKeySet(data []byte) (StaticKeySet, error) {
+	var keySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &keySet); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeySet, err)
+	}
+	keys := StaticKeySet{}
+	for _, key := range keySet.Keys {
+		if key.KeyType != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		publicKey, err := key.rsaPublicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q: %v", ErrInvalidKeySet, key.KeyID, err)
+		}
+		keys[key.KeyID] = publicKey
+	}
+	return keys, nil
+}
+
+// rsaPublicKey - decodes the modulus and the exponent of the key
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, err
+	}
+	exponent, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, err
+	}
+	e := new(big.Int).SetBytes(exponent)
+	if len(modulus) == 0 || !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid rsa modulus or exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(modulus), E: int(e.Int64())}, nil
+}
+
+// Key - returns the key with the given id, the only key of the set if the token has no key id
+func (s StaticKeySet) Key(_ context.Context, keyID string) (*rsa.PublicKey, error) {
+	if key, ok := s[keyID]; ok {
+		return key, nil
+	}
+	if keyID == "" && len(s) == 1 {
+		for _, key := range s {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// NewRemoteKeySet - creates a key set fetched from the given jwks url and cached for the given ttl, 10 minutes if it is not set
+func NewRemoteKeySet(url string, cacheTTL time.Duration) *RemoteKeySet {
+	if cacheTTL <= 0 {
+		cacheTTL = defaultKeySetCacheTTL
+	}
+	return &RemoteKeySet{
+		url:      url,
+		cacheTTL: cacheTTL,
+		client:   &http.Client{Timeout: keySetRequestTimeout},
+		now:      time.Now,
+	}
+}
+
+// Key - returns the key with the given id, the key set is fetched if the cached keys are expired or don't have the key
+func (s *RemoteKeySet) Key(ctx context.Context, keyID string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.keys != nil {
+		key, err := s.keys.Key(ctx, keyID)
+		if (err == nil && now.Sub(s.fetchedAt) < s.cacheTTL) || now.Sub(s.attemptedAt) < minKeySetRefreshInterval {
+			return key, err
+		}
+	}
+	s.attemptedAt = now
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		if s.keys == nil {
+			return nil, err
+		}
+		// the cached keys are used until the key set can be fetched again
+		return s.keys.Key(ctx, keyID)
+	}
+	s.keys = keys
+	s.fetchedAt = now
+	return s.keys.Key(ctx, keyID)
+}
+
+// fetch - reads the key set from the jwks url
+func (s *RemoteKeySet) fetch(ctx context.Context) (StaticKeySet, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks %s: %w", s.url, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch jwks %s: status %d", s.url, response.StatusCode)
+	}
+	var data json.RawMessage
+	if err := json.NewDecoder(response.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeySet, err)
+	}
+	return ParseKeySet(data)
+}
@@ -0,0 +1,47 @@
+package auth
+
+// Role - represents what a user is allowed to do
+type Role string
+
+const (
+	// RoleShopper - uses their own basket, users without any role are shoppers
+	RoleShopper Role = "shopper"
+	// RoleSupport - views the baskets and the profiles of the customers without changing them
+	RoleSupport Role = "support"
+	// RoleMerchandiser - manages and simulates the campaigns
+	RoleMerchandiser Role = "merchandiser"
+	// RoleAdmin - is allowed to do everything, such as changing the stock of the products
+	RoleAdmin Role = "admin"
+)
+
+// Principal - represents the authenticated user of a request
+type Principal struct {
+	UserID string
+	Roles  []Role
+	// Guest - the request is made with the cart token of a guest basket instead of a bearer token, guests have no roles
+	Guest bool
+}
+
+// NewPrincipal - creates the authenticated user of the verified claims, users without any role are shoppers
+func NewPrincipal(claims Claims) Principal {
+	roles := claims.Roles
+	if len(roles) == 0 {
+		roles = []Role{RoleShopper}
+	}
+	return Principal{UserID: claims.Subject, Roles: roles}
+}
+
+// HasAnyRole - checks if the user has one of the given roles, admins have every role
+func (p Principal) HasAnyRole(roles ...Role) bool {
+	for _, userRole := range p.Roles {
+		if userRole == RoleAdmin {
+			return true
+		}
+		for _, role := range roles {
+			if userRole == role {
+				return true
+			}
+		}
+	}
+	return false
+}
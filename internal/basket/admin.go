@@ -0,0 +1,42 @@
+package basket
+
+import (
+	"context"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/erdemcemal/basket-service/internal/models"
+	log "github.com/siruspen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrBasketNotFound     = errors.New("basket not found")
+	ErrUpdateProductStock = errors.New("error updating product stock")
+)
+
+// ViewBasket - returns the shopping cart of the given user as it is stored, the cart is not created or repriced,
+// so the basket of a customer can be viewed without changing it
+func (s *Service) ViewBasket(ctx context.Context, userId string) (dto.ShoppingCartDTO, error) {
+	cart, err := s.store.GetBasketByUserId(ctx, userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.ShoppingCartDTO{}, ErrBasketNotFound
+		}
+		log.Error(err)
+		return dto.ShoppingCartDTO{}, ErrGettingUserShoppingCart
+	}
+	return fromShoppingCart(cart), nil
+}
+
+// UpdateProductStock - sets the stock quantity of the product with the given id
+func (s *Service) UpdateProductStock(ctx context.Context, productId string, quantity int32) (dto.ProductDTO, error) {
+	product, err := s.store.UpdateProductStock(ctx, productId, quantity)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return dto.ProductDTO{}, ErrProductNotFound
+		}
+		log.Error(err)
+		return dto.ProductDTO{}, ErrUpdateProductStock
+	}
+	return fromProduct(product, models.BaseCurrency), nil
+}
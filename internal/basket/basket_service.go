@@ -3,17 +3,18 @@ package basket
 import (
 	"context"
 	"errors"
-	"fmt"
 	"github.com/erdemcemal/basket-service/internal/campaign"
+	"github.com/erdemcemal/basket-service/internal/config"
+	"github.com/erdemcemal/basket-service/internal/customer"
 	"github.com/erdemcemal/basket-service/internal/dto"
 	"github.com/erdemcemal/basket-service/internal/models"
 	basketstore "github.com/erdemcemal/basket-service/internal/store/basket"
+	campaignstore "github.com/erdemcemal/basket-service/internal/store/campaign"
+	"github.com/erdemcemal/basket-service/internal/tax"
 	"github.com/shopspring/decimal"
 	log "github.com/siruspen/logrus"
 	"gorm.io/gorm"
-	"math"
-	"os"
-	"strconv"
+	"time"
 )
 
 var (
@@ -25,6 +26,15 @@ var (
 	ErrProductAlreadyInBasket  = errors.New("product already in basket")
 	ErrCheckoutBasket          = errors.New("error checking out basket")
 	ErrGettingProducts         = errors.New("error getting products")
+	ErrCouponNotFound          = errors.New("coupon not found")
+	ErrCouponNotValid          = errors.New("coupon is not valid at this time")
+	ErrCouponLimitReached      = errors.New("coupon redemption limit reached")
+	ErrCouponMinBasketAmount   = errors.New("basket total is below the coupon minimum amount")
+	ErrCouponNotInBasket       = errors.New("no coupon applied to basket")
+	ErrInvalidCampaigns        = errors.New("invalid campaign definitions")
+	ErrGettingCampaigns        = errors.New("error getting campaigns")
+	ErrCampaignNotAvailable    = errors.New("campaign budget or redemption limit is exhausted")
+	ErrCouponCurrency          = errors.New("coupon is not valid in the basket currency")
 )
 
 // BasketService - represents the basket service
@@ -35,178 +45,372 @@ type BasketService interface {
 	RemoveItemFromBasket(ctx context.Context, userId string, itemToRemoveId string) (dto.ShoppingCartDTO, error)
 	UpdateItemInBasket(ctx context.Context, userId string, productId string, quantity int32) (dto.ShoppingCartDTO, error)
 	CheckoutBasket(ctx context.Context, userId string) error
+	ApplyCoupon(ctx context.Context, userId string, code string) (dto.ShoppingCartDTO, error)
+	RemoveCoupon(ctx context.Context, userId string) (dto.ShoppingCartDTO, error)
+	SimulateCampaigns(ctx context.Context, simulation dto.SimulateCampaignsDTO) (dto.CampaignSimulationDTO, error)
+	GetDiscountTrace(ctx context.Context, userId string) (dto.DiscountTraceDTO, error)
+	GetShippingMethods(ctx context.Context, userId string, region string) ([]dto.ShippingMethodDTO, error)
+	SelectShippingMethod(ctx context.Context, userId string, selection dto.SelectShippingMethodDTO) (dto.ShoppingCartDTO, error)
+	SetBasketCurrency(ctx context.Context, userId string, currency string) (dto.ShoppingCartDTO, error)
+	ViewBasket(ctx context.Context, userId string) (dto.ShoppingCartDTO, error)
+	UpdateProductStock(ctx context.Context, productId string, quantity int32) (dto.ProductDTO, error)
+	MergeBasket(ctx context.Context, userId string, guestUserId string, policy string) (dto.ShoppingCartDTO, error)
 }
 
 // Service - represents the basket service implementation
 type Service struct {
-	store basketstore.BasketStore
+	store         basketstore.BasketStore
+	campaignStore campaignstore.CampaignStore
+	customers     customer.ProfileProvider
+	// campaigns - policy and rounding the active campaigns of the campaign store are combined with
+	campaigns   campaign.Config
+	givenAmount decimal.Decimal
+	clock       campaign.Clock
+	// taxes - resolves the vat rates of the cart items by their tax class
+	taxes tax.Engine
+	// currencies - currencies the baskets can be priced in and the currency of the new baskets
+	currencies config.CurrencyConfig
+	// mergePolicy - policy the guest baskets are merged with if the client doesn't choose one
+	mergePolicy models.MergePolicy
 }
 
-// NewService - creates a new basket service with the given store
-func NewService(store basketstore.BasketStore) *Service {
+// NewService - creates a new basket service with the given stores and settings
+func NewService(store basketstore.BasketStore, campaignStore campaignstore.CampaignStore, customers customer.ProfileProvider, cfg config.CampaignConfig, taxCfg config.TaxConfig, currencyCfg config.CurrencyConfig, basketCfg config.BasketConfig) *Service {
+	givenAmount := decimal.Zero
+	if cfg.GivenAmount != nil {
+		givenAmount = *cfg.GivenAmount
+	}
 	return &Service{
-		store: store,
+		store:         store,
+		campaignStore: campaignStore,
+		customers:     customers,
+		campaigns:     cfg.Definitions,
+		givenAmount:   givenAmount,
+		clock:         campaign.SystemClock{},
+		taxes:         tax.NewEngine(taxCfg.Table(), taxCfg.PricesIncludeVat),
+		currencies:    currencyCfg,
+		mergePolicy:   basketCfg.MergePolicy,
 	}
 }
 
-// GetProducts - returns all products in the store
+// GetProducts - returns the products priced in the currency of the client with their unreserved stock
 // TODO: pagination, sorting, filtering
 func (s *Service) GetProducts(ctx context.Context) ([]dto.ProductDTO, error) {
+	currency := currencyFrom(ctx)
+	if currency == "" {
+		currency = s.currencies.Default
+	}
+	if !s.currencies.IsSupported(currency) {
+		return []dto.ProductDTO{}, ErrUnsupportedCurrency
+	}
 	products, err := s.store.GetProducts(ctx)
 	if err != nil {
-		log.Errorf("error getting products: %w", err)
+		log.Errorf("error getting products: %v", err)
+		return []dto.ProductDTO{}, ErrGettingProducts
+	}
+	reserved, err := s.store.GetReservedStock(ctx)
+	if err != nil {
+		log.Errorf("error getting reserved stock: %v", err)
 		return []dto.ProductDTO{}, ErrGettingProducts
 	}
 	var dtoProducts []dto.ProductDTO
+	now := s.clock.Now()
 	for _, product := range products {
-		dtoProducts = append(dtoProducts, fromProduct(product))
+		price, ok := product.PriceIn(currency)
+		if !ok {
+			continue
+		}
+		product.UnitPrice = price
+		product.Quantity -= reserved[product.ID.String()]
+		if product.Quantity < 0 {
+			product.Quantity = 0
+		}
+		// vat rate of the product is the rate of its tax class effective now
+		if vatRate, err := s.taxes.ProductRate(product, now); err != nil {
+			log.Error(err)
+		} else {
+			product.VatRate = vatRate
+		}
+		dtoProducts = append(dtoProducts, fromProduct(product, currency))
 	}
 	return dtoProducts, nil
 }
 
 // GetBasket - returns the shopping cart for the given user id, if not exist creates a new one
 func (s *Service) GetBasket(ctx context.Context, userId string) (dto.ShoppingCartDTO, error) {
-	cart, err := s.store.GetBasket(ctx, userId)
+	cart, err := s.getBasket(ctx, userId)
 	if err != nil {
-		log.Error(err)
-		return dto.ShoppingCartDTO{}, ErrGettingUserShoppingCart
+		return dto.ShoppingCartDTO{}, err
 	}
 	return fromShoppingCart(cart), nil
 }
 
-// AddItemToBasket - adds an item to the shopping cart with the given product id and quantity
+// AddItemToBasket - adds an item to the shopping cart and reserves its stock
 func (s *Service) AddItemToBasket(ctx context.Context, userId string, item dto.AddItemToBasketDTO) (dto.ShoppingCartDTO, error) {
-	shoppingCart, err := s.store.GetBasket(ctx, userId)
-	if err != nil {
-		log.Error(err)
-		return dto.ShoppingCartDTO{}, ErrGettingUserShoppingCart
-	}
-	if shoppingCart.ContainsItem(item.ProductID) {
-		log.Error(err)
-		return dto.ShoppingCartDTO{}, ErrProductAlreadyInBasket
-	}
-	// get product from store
-	product, err := s.store.GetProductById(ctx, item.ProductID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return dto.ShoppingCartDTO{}, ErrProductNotFound
+	var shoppingCart models.ShoppingCart
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		shoppingCart, err = s.getBasket(ctx, userId)
+		if err != nil {
+			return err
 		}
-		log.Error(err)
-		return dto.ShoppingCartDTO{}, err
-	}
-	if product.Quantity < item.Quantity {
-		return dto.ShoppingCartDTO{}, ErrProductStockNotEnough
-	}
-
-	cartItem := models.NewShoppingCartItem(product.ID, product.Name, item.Quantity, product.UnitPrice, product.VatRate, shoppingCart.ID.String())
+		if shoppingCart.ContainsItem(item.ProductID) {
+			return ErrProductAlreadyInBasket
+		}
+		// get product from store
+		product, err := s.store.GetProductById(ctx, item.ProductID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrProductNotFound
+			}
+			log.Error(err)
+			return ErrGettingProducts
+		}
+		price, err := productPrice(product, shoppingCart.Currency)
+		if err != nil {
+			return err
+		}
+		cartItem := models.NewShoppingCartItemFromProduct(product, item.Quantity, shoppingCart.ID.String())
+		cartItem.Price = price
 
-	shoppingCart.AddItem(cartItem)
-	shoppingCart.ApplyDiscount(decimal.NewFromFloat(tryApplyDiscount(s.store, shoppingCart)))
+		shoppingCart.AddItem(cartItem)
+		s.priceBasket(ctx, &shoppingCart)
 
-	err = s.store.UpdateBasket(ctx, userId, shoppingCart)
+		return s.updateBasketWithReservation(ctx, &shoppingCart, item.ProductID, item.Quantity, ErrUpdateBasket)
+	})
 	if err != nil {
-		log.Error(err)
 		return dto.ShoppingCartDTO{}, err
 	}
 	return fromShoppingCart(shoppingCart), nil
 }
 
-// RemoveItemFromBasket - removes an item from the shopping cart with the given product id
+// RemoveItemFromBasket - removes an item from the shopping cart and releases its stock
 func (s *Service) RemoveItemFromBasket(ctx context.Context, userId string, itemToRemoveId string) (dto.ShoppingCartDTO, error) {
-	shoppingCart, err := s.store.GetBasket(ctx, userId)
-	if err != nil {
-		log.Error(err)
-		return dto.ShoppingCartDTO{}, ErrGettingUserShoppingCart
-	}
-	cartItemToRemove, exists := shoppingCart.GetCartItemByProductId(itemToRemoveId)
-	if !exists {
-		return dto.ShoppingCartDTO{}, ErrProductNotFound
-	}
+	var shoppingCart models.ShoppingCart
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		shoppingCart, err = s.getBasket(ctx, userId)
+		if err != nil {
+			return err
+		}
+		cartItemToRemove, exists := shoppingCart.GetCartItemByProductId(itemToRemoveId)
+		if !exists {
+			return ErrProductNotFound
+		}
 
-	shoppingCart.RemoveItem(itemToRemoveId)
-	shoppingCart.ApplyDiscount(decimal.NewFromFloat(tryApplyDiscount(s.store, shoppingCart)))
+		shoppingCart.RemoveItem(itemToRemoveId)
+		s.priceBasket(ctx, &shoppingCart)
 
-	err = s.store.RemoveItemFromBasket(ctx, cartItemToRemove, shoppingCart)
+		err = s.store.RemoveItemFromBasket(ctx, cartItemToRemove, &shoppingCart)
+		if errors.Is(err, basketstore.ErrBasketVersionConflict) {
+			return ErrBasketConflict
+		}
+		if err != nil {
+			log.Error(err)
+			return ErrUpdateBasket
+		}
+		s.releaseStock(ctx, shoppingCart.ID.String(), itemToRemoveId)
+		return nil
+	})
 	if err != nil {
-		log.Error(err)
 		return dto.ShoppingCartDTO{}, err
 	}
 	return fromShoppingCart(shoppingCart), nil
 }
 
-// UpdateItemInBasket - updates the quantity of an item in the shopping cart with the given product id and new quantity
+// UpdateItemInBasket - updates the quantity of an item in the shopping cart and its stock reservation
 func (s *Service) UpdateItemInBasket(ctx context.Context, userId string, productId string, newQuantity int32) (dto.ShoppingCartDTO, error) {
-	shoppingCart, err := s.store.GetBasket(ctx, userId)
-	if err != nil {
-		log.Error(err)
-		return dto.ShoppingCartDTO{}, ErrGettingUserShoppingCart
-	}
-	if !shoppingCart.ContainsItem(productId) {
-		return dto.ShoppingCartDTO{}, ErrProductNotInBasket
-	}
-	product, err := s.store.GetProductById(ctx, productId)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return dto.ShoppingCartDTO{}, ErrProductNotFound
+	var shoppingCart models.ShoppingCart
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		shoppingCart, err = s.getBasket(ctx, userId)
+		if err != nil {
+			return err
 		}
-		log.Error(err)
-		return dto.ShoppingCartDTO{}, ErrGettingProducts
-	}
-	if product.Quantity < newQuantity {
-		return dto.ShoppingCartDTO{}, ErrProductStockNotEnough
-	}
-
-	shoppingCart.UpdateItemQuantity(productId, newQuantity)
-	shoppingCart.ApplyDiscount(decimal.NewFromFloat(tryApplyDiscount(s.store, shoppingCart)))
+		if !shoppingCart.ContainsItem(productId) {
+			return ErrProductNotInBasket
+		}
+		shoppingCart.UpdateItemQuantity(productId, newQuantity)
+		s.priceBasket(ctx, &shoppingCart)
 
-	err = s.store.UpdateBasket(ctx, userId, shoppingCart)
+		return s.updateBasketWithReservation(ctx, &shoppingCart, productId, newQuantity, ErrUpdateProductQuantity)
+	})
 	if err != nil {
-		log.Error(err)
-		return dto.ShoppingCartDTO{}, ErrUpdateProductQuantity
+		return dto.ShoppingCartDTO{}, err
 	}
 	return fromShoppingCart(shoppingCart), nil
 }
 
-// CheckoutBasket - checks out the shopping cart and returns the total price of the basket
+// CheckoutBasket - checks out the shopping cart of the user, checkout is not retried
+// if the basket is updated in the meantime, so the shopper confirms the order of the updated basket
 func (s *Service) CheckoutBasket(ctx context.Context, userId string) error {
-	shoppingCart, err := s.store.GetBasket(ctx, userId)
+	shoppingCart, err := s.getBasket(ctx, userId)
 	if err != nil {
-		log.Error(err)
-		return ErrGettingUserShoppingCart
+		return err
 	}
 
-	shoppingCart.ApplyDiscount(decimal.NewFromFloat(tryApplyDiscount(s.store, shoppingCart)))
+	s.priceBasket(ctx, &shoppingCart)
+	if err := s.requireShipping(ctx, shoppingCart); err != nil {
+		return err
+	}
 
 	err = s.store.CheckoutBasket(ctx, shoppingCart)
+	if isCampaignNotAvailable(err) {
+		// a concurrent checkout has used up the campaign, so the basket is repriced with the remaining budgets once
+		s.priceBasket(ctx, &shoppingCart)
+		err = s.store.CheckoutBasket(ctx, shoppingCart)
+	}
 	if err != nil {
 		log.Error(err)
+		if errors.Is(err, basketstore.ErrBasketVersionConflict) {
+			return ErrBasketConflict
+		}
+		if errors.Is(err, basketstore.ErrCouponRedemptionLimitReached) {
+			return ErrCouponLimitReached
+		}
+		if errors.Is(err, basketstore.ErrStockNotAvailable) {
+			return ErrProductStockNotEnough
+		}
+		if isCampaignNotAvailable(err) {
+			return ErrCampaignNotAvailable
+		}
 		return ErrCheckoutBasket
 	}
 	return nil
 }
 
-// fromProduct - converts a product model to a product dto
-func fromProduct(product models.Product) dto.ProductDTO {
+// isCampaignNotAvailable - checks if the checkout failed because a campaign can't be redeemed anymore
+func isCampaignNotAvailable(err error) bool {
+	return errors.Is(err, basketstore.ErrCampaignBudgetExhausted) || errors.Is(err, basketstore.ErrCampaignRedemptionLimitReached) ||
+		errors.Is(err, basketstore.ErrCampaignBudgetCurrency)
+}
+
+// ApplyCoupon - applies the coupon with the given code to the shopping cart if it can be redeemed by the user
+func (s *Service) ApplyCoupon(ctx context.Context, userId string, code string) (dto.ShoppingCartDTO, error) {
+	var shoppingCart models.ShoppingCart
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		shoppingCart, err = s.getBasket(ctx, userId)
+		if err != nil {
+			return err
+		}
+		coupon, err := s.store.GetCouponByCode(ctx, models.NormalizeCouponCode(code))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrCouponNotFound
+			}
+			log.Error(err)
+			return err
+		}
+		if !coupon.IsValidAt(s.clock.Now()) {
+			return ErrCouponNotValid
+		}
+		if !coupon.AppliesIn(shoppingCart.Currency) {
+			return ErrCouponCurrency
+		}
+		userRedemptionCount, err := s.store.CountUserCouponRedemptions(ctx, coupon.ID.String(), userId)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+		if !coupon.HasRedemptionsLeft(userRedemptionCount) {
+			return ErrCouponLimitReached
+		}
+		if shoppingCart.TotalPrice.LessThan(coupon.MinBasketAmount) {
+			return ErrCouponMinBasketAmount
+		}
+
+		shoppingCart.ApplyCoupon(coupon.Code)
+		s.priceBasket(ctx, &shoppingCart)
+
+		return s.updateBasket(ctx, &shoppingCart, ErrUpdateBasket)
+	})
+	if err != nil {
+		return dto.ShoppingCartDTO{}, err
+	}
+	return fromShoppingCart(shoppingCart), nil
+}
+
+// RemoveCoupon - removes the coupon from the shopping cart
+func (s *Service) RemoveCoupon(ctx context.Context, userId string) (dto.ShoppingCartDTO, error) {
+	var shoppingCart models.ShoppingCart
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		shoppingCart, err = s.getBasket(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if shoppingCart.CouponCode == "" {
+			return ErrCouponNotInBasket
+		}
+
+		shoppingCart.RemoveCoupon()
+		s.priceBasket(ctx, &shoppingCart)
+
+		return s.updateBasket(ctx, &shoppingCart, ErrUpdateBasket)
+	})
+	if err != nil {
+		return dto.ShoppingCartDTO{}, err
+	}
+	return fromShoppingCart(shoppingCart), nil
+}
+
+// fromProduct - converts a product model priced in the given currency to a product dto
+func fromProduct(product models.Product, currency string) dto.ProductDTO {
 	return dto.ProductDTO{
-		ID:        product.ID.String(),
-		Name:      product.Name,
-		UnitPrice: product.UnitPrice,
-		Quantity:  product.Quantity,
-		VatRate:   product.VatRate,
+		ID:         product.ID.String(),
+		Name:       product.Name,
+		UnitPrice:  product.UnitPrice,
+		Currency:   currency,
+		Quantity:   product.Quantity,
+		VatRate:    product.VatRate,
+		Brand:      product.Brand,
+		Categories: product.Categories,
+		Tags:       product.Tags,
+		Weight:     product.Weight,
+		TaxClass:   product.TaxClass,
 	}
 }
 
 // fromShoppingCart - converts a shopping cart model to a shopping cart dto
 func fromShoppingCart(cart models.ShoppingCart) dto.ShoppingCartDTO {
 	return dto.ShoppingCartDTO{
-		ID:            cart.ID.String(),
-		UserID:        cart.UserID,
-		Items:         fromShoppingCartItems(cart.Items),
-		TotalPrice:    cart.TotalPrice,
-		TotalVat:      cart.TotalVat,
-		TotalDiscount: cart.TotalDiscount,
-		SubTotal:      cart.SubTotal,
+		ID:               cart.ID.String(),
+		Version:          cart.Version,
+		UserID:           cart.UserID,
+		Currency:         models.CurrencyOrBase(cart.Currency),
+		Items:            fromShoppingCartItems(cart.Items),
+		TotalPrice:       cart.TotalPrice,
+		TotalVat:         cart.TotalVat,
+		TotalDiscount:    cart.TotalDiscount,
+		SubTotal:         cart.SubTotal,
+		AppliedCampaigns: fromAppliedCampaigns(cart.AppliedCampaigns),
+		CouponCode:       cart.CouponCode,
+		PricesIncludeVat: cart.PricesIncludeVat,
+		ShippingMethod:   cart.ShippingMethod,
+		ShippingRegion:   cart.ShippingRegion,
+		ShippingCost:     cart.ShippingCost,
+		ShippingDiscount: cart.ShippingDiscount,
+		TotalWeight:      cart.TotalWeight(),
+	}
+}
+
+// fromAppliedCampaigns - converts the applied campaigns of a shopping cart to a list of applied campaigns dto
+func fromAppliedCampaigns(campaigns models.AppliedCampaigns) []dto.AppliedCampaignDTO {
+	dtoCampaigns := []dto.AppliedCampaignDTO{}
+	for _, appliedCampaign := range campaigns {
+		dtoItems := []dto.AppliedCampaignItemDTO{}
+		for _, item := range appliedCampaign.Items {
+			dtoItems = append(dtoItems, dto.AppliedCampaignItemDTO{ProductID: item.ProductID, Amount: item.Amount})
+		}
+		dtoCampaigns = append(dtoCampaigns, dto.AppliedCampaignDTO{
+			CampaignID: appliedCampaign.CampaignID,
+			Label:      appliedCampaign.Label,
+			Amount:     appliedCampaign.Amount,
+			Items:      dtoItems,
+		})
 	}
+	return dtoCampaigns
 }
 
 // fromShoppingCartItems - converts a list of shopping cart items to a list of shopping cart items dto
@@ -220,32 +424,125 @@ func fromShoppingCartItems(items []models.ShoppingCartItem) []dto.ShoppingCartIt
 			Price:     item.Price,
 			VatRate:   item.VatRate,
 			Quantity:  item.Quantity,
+			Discount:  item.Discount,
+			TaxClass:  item.TaxClass,
+			VatAmount: item.VatAmount,
 		})
 	}
 	return dtoItems
 }
 
-func tryApplyDiscount(store basketstore.BasketStore, cart models.ShoppingCart) float64 {
-	givenAmountStr := os.Getenv("GIVEN_AMOUNT")
-	fmt.Println("given amount: ", givenAmountStr)
-	if givenAmountStr == "" {
-		fmt.Println("no given amount")
-		panic("no given amount")
+// tryApplyDiscount - returns the active campaigns applied on the cart
+func (s *Service) tryApplyDiscount(ctx context.Context, cart models.ShoppingCart) models.AppliedCampaigns {
+	campaigns, err := s.activeCampaigns(ctx)
+	if err != nil {
+		log.Error(err)
+		return models.AppliedCampaigns{}
+	}
+	calculation, err := s.calculateDiscount(ctx, campaigns, cart, s.customerFacts(ctx, campaigns, cart.UserID, cart.Currency, s.clock.Now()), s.clock)
+	if err != nil {
+		log.Error(err)
+		return models.AppliedCampaigns{}
+	}
+	return toAppliedCampaigns(calculation)
+}
+
+// activeCampaigns - returns the active campaigns of the campaign store with the configured policy
+func (s *Service) activeCampaigns(ctx context.Context) (campaign.Config, error) {
+	storedCampaigns, err := s.campaignStore.GetCampaigns(ctx, models.CampaignStatusActive)
+	if err != nil {
+		return campaign.Config{}, err
+	}
+	return s.campaigns.WithStoredCampaigns(storedCampaigns)
+}
+
+// customerFacts - returns the facts of the user the rules are built with
+func (s *Service) customerFacts(ctx context.Context, campaigns campaign.Config, userId string, currency string, now time.Time) campaign.Facts {
+	userMonthlyAmount, _ := s.store.GetUserMonthlyOrderAmount(ctx, userId, currency)
+	recentOrders, err := s.store.GetUserRecentOrders(ctx, userId, now.AddDate(0, 0, -int(campaigns.MaxLookbackDays())))
+	if err != nil {
+		log.Error(err)
+	}
+	var userOrders []campaign.Order
+	for _, order := range recentOrders {
+		userOrders = append(userOrders, campaign.Order{Sequence: order.OrderSequence, Amount: order.SubTotal, Currency: order.Currency, CreatedAt: order.CreatedAt})
 	}
-	givenAmount, err := strconv.ParseFloat(givenAmountStr, 64)
+	var segments []string
+	if profile, err := s.customers.GetProfile(ctx, userId); err != nil {
+		log.Error(err)
+	} else {
+		segments = profile.Segments()
+	}
+	campaignRedemptions, err := s.campaignStore.CountUserCampaignRedemptions(ctx, userId)
 	if err != nil {
-		panic(fmt.Errorf("error parsing given amount: %w", err))
+		log.Error(err)
+	}
+	return campaign.Facts{
+		GivenAmount:         s.givenAmount,
+		UserMonthlyAmount:   userMonthlyAmount,
+		UserOrders:          userOrders,
+		Now:                 now,
+		Segments:            segments,
+		CampaignRedemptions: campaignRedemptions,
 	}
-	log.Info("Given amount:", givenAmount)
-	var discountRules []campaign.Rule
-	userMonthlyAmount, _ := store.GetUserMonthlyOrderAmount(context.Background(), cart.UserID)
-	discountRules = append(discountRules, campaign.NewPurchaseAmountRule(givenAmount, userMonthlyAmount))
+}
 
-	userLastFourthOrderAmount, _ := store.GetEveryFourthOrderAmount(context.Background())
-	discountRules = append(discountRules, campaign.NewEveryFourthOrderRule(givenAmount, userLastFourthOrderAmount))
-	discountRules = append(discountRules, campaign.SameProductRule{})
+// calculateDiscount - calculates the discount of the cart with the rules of the given campaigns and the cart coupon
+func (s *Service) calculateDiscount(ctx context.Context, campaigns campaign.Config, cart models.ShoppingCart, facts campaign.Facts, clock campaign.Clock) (campaign.Calculation, error) {
+	discountCalculator, err := s.discountCalculator(ctx, campaigns, cart, facts, clock)
+	if err != nil {
+		return campaign.Calculation{}, err
+	}
+	return discountCalculator.Calculate(cart), nil
+}
 
-	discountCalculator := campaign.NewDiscountCalculator(discountRules)
-	discountAmount := discountCalculator.CalculateDiscount(cart)
-	return math.Round(discountAmount*100) / 100
+// discountCalculator - creates the discount calculator with the rules of the given campaigns and the cart coupon
+func (s *Service) discountCalculator(ctx context.Context, campaigns campaign.Config, cart models.ShoppingCart, facts campaign.Facts, clock campaign.Clock) (*campaign.DiscountCalculator, error) {
+	discountRules, err := campaigns.Rules(campaign.RuleContext{Context: ctx, UserID: cart.UserID, Currency: cart.Currency, Facts: facts, Lookup: s.store})
+	if err != nil {
+		return nil, err
+	}
+	if couponRule, ok := s.couponRule(ctx, cart); ok {
+		discountRules = append(discountRules, couponRule)
+	}
+	campaigns.Rounding = campaigns.Rounding.ForCurrency(cart.Currency)
+	return campaigns.NewDiscountCalculator(discountRules, clock), nil
+}
+
+// couponRule - returns the rule of the coupon applied on the cart if it applies in the cart currency
+func (s *Service) couponRule(ctx context.Context, cart models.ShoppingCart) (campaign.Rule, bool) {
+	if cart.CouponCode == "" {
+		return nil, false
+	}
+	coupon, err := s.store.GetCouponByCode(ctx, cart.CouponCode)
+	if err != nil {
+		log.Error(err)
+		return nil, false
+	}
+	if !coupon.AppliesIn(cart.Currency) {
+		return nil, false
+	}
+	return campaign.PrioritizedRule{
+		Rule:             campaign.NewCouponRule(coupon),
+		ExclusivityGroup: campaign.CouponExclusivityGroup,
+		Schedule:         campaign.Schedule{ValidFrom: coupon.ValidFrom, ValidUntil: coupon.ValidUntil},
+	}, true
+}
+
+// toAppliedCampaigns - converts the discount calculation to applied campaigns
+func toAppliedCampaigns(calculation campaign.Calculation) models.AppliedCampaigns {
+	appliedCampaigns := models.AppliedCampaigns{}
+	for _, result := range calculation.Applied {
+		appliedCampaign := models.AppliedCampaign{
+			CampaignID: result.RuleID,
+			Label:      result.Label,
+			Amount:     result.Amount,
+			Items:      []models.AppliedCampaignItem{},
+		}
+		for _, line := range result.Lines {
+			appliedCampaign.Items = append(appliedCampaign.Items, models.AppliedCampaignItem{ProductID: line.ProductID, Amount: line.Amount})
+		}
+		appliedCampaigns = append(appliedCampaigns, appliedCampaign)
+	}
+	return appliedCampaigns
 }
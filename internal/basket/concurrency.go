@@ -0,0 +1,81 @@
+package basket
+
+import (
+	"context"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/models"
+	basketstore "github.com/erdemcemal/basket-service/internal/store/basket"
+	log "github.com/siruspen/logrus"
+)
+
+// maxBasketUpdateAttempts - how many times an operation is run when the basket is updated by another request in the meantime
+const maxBasketUpdateAttempts = 3
+
+var (
+	ErrBasketConflict        = errors.New("basket is updated by another request, try again")
+	ErrBasketVersionMismatch = errors.New("basket is updated since the expected version")
+	ErrUpdateBasket          = errors.New("error updating basket")
+)
+
+// BasketVersion - identifies the state of a basket the client has read, the id of the basket changes after checkout
+type BasketVersion struct {
+	CartID  string
+	Version int64
+}
+
+// expectedVersionKey - is the context key of the basket version expected by the client
+type expectedVersionKey struct{}
+
+// WithExpectedVersion - returns a copy of the context with the basket version the client expects, such as from an If-Match header,
+// the basket is only changed if it still has the version and operations are not retried on a conflict
+func WithExpectedVersion(ctx context.Context, version BasketVersion) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, version)
+}
+
+// expectedVersionFrom - returns the basket version expected by the client of the context, false if there is none
+func expectedVersionFrom(ctx context.Context) (BasketVersion, bool) {
+	version, ok := ctx.Value(expectedVersionKey{}).(BasketVersion)
+	return version, ok
+}
+
+// checkExpectedVersion - checks that the shopping cart has the version the client expects
+func checkExpectedVersion(ctx context.Context, cart models.ShoppingCart) error {
+	expected, ok := expectedVersionFrom(ctx)
+	if !ok {
+		return nil
+	}
+	if expected.CartID != cart.ID.String() || expected.Version != cart.Version {
+		return ErrBasketVersionMismatch
+	}
+	return nil
+}
+
+// retryOnConflict - runs the read-modify-write operation on the basket again if another request has updated the basket after it is read,
+// the operation reads the basket again on every attempt, so it is applied on the latest basket. Operations of clients which expect
+// a basket version are not retried, as the basket doesn't have that version anymore
+func retryOnConflict(ctx context.Context, operation func() error) error {
+	var err error
+	for attempt := 1; attempt <= maxBasketUpdateAttempts; attempt++ {
+		err = operation()
+		if !errors.Is(err, ErrBasketConflict) {
+			return err
+		}
+		if _, ok := expectedVersionFrom(ctx); ok {
+			return ErrBasketVersionMismatch
+		}
+		log.Warnf("basket is updated concurrently, attempt %d of %d", attempt, maxBasketUpdateAttempts)
+	}
+	return err
+}
+
+// updateBasket - updates the shopping cart in the store, a version conflict is returned as ErrBasketConflict and other errors as the given failure
+func (s *Service) updateBasket(ctx context.Context, cart *models.ShoppingCart, failure error) error {
+	if err := s.store.UpdateBasket(ctx, cart); err != nil {
+		if errors.Is(err, basketstore.ErrBasketVersionConflict) {
+			return ErrBasketConflict
+		}
+		log.Error(err)
+		return failure
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package basket
+
+import (
+	"context"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	log "github.com/siruspen/logrus"
+)
+
+var (
+	ErrUnsupportedCurrency  = errors.New("currency is not supported")
+	ErrProductPriceNotFound = errors.New("product has no price in the basket currency")
+	ErrUpdateBasketCurrency = errors.New("error updating basket currency")
+)
+
+// currencyKey - is the context key of the currency selected by the client
+type currencyKey struct{}
+
+// WithCurrency - returns a copy of the context with the currency selected by the client
+func WithCurrency(ctx context.Context, currency string) context.Context {
+	return context.WithValue(ctx, currencyKey{}, models.NormalizeCurrency(currency))
+}
+
+// currencyFrom - returns the currency selected by the client of the context, empty if it is not selected
+func currencyFrom(ctx context.Context) string {
+	currency, _ := ctx.Value(currencyKey{}).(string)
+	return currency
+}
+
+// SetBasketCurrency - changes the currency of the shopping cart and reprices it
+func (s *Service) SetBasketCurrency(ctx context.Context, userId string, currency string) (dto.ShoppingCartDTO, error) {
+	var shoppingCart models.ShoppingCart
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		shoppingCart, err = s.store.GetBasket(ctx, userId)
+		if err != nil {
+			log.Error(err)
+			return ErrGettingUserShoppingCart
+		}
+		if err := checkExpectedVersion(ctx, shoppingCart); err != nil {
+			return err
+		}
+		if err := s.changeCurrency(ctx, &shoppingCart, models.NormalizeCurrency(currency)); err != nil {
+			return err
+		}
+		return s.updateBasket(ctx, &shoppingCart, ErrUpdateBasketCurrency)
+	})
+	if err != nil {
+		return dto.ShoppingCartDTO{}, err
+	}
+	return fromShoppingCart(shoppingCart), nil
+}
+
+// getBasket - returns the shopping cart of the user repriced in the currency of the client, the cart is not saved,
+// so reading the basket doesn't change it and the requests which change the basket save it in the new currency
+func (s *Service) getBasket(ctx context.Context, userId string) (models.ShoppingCart, error) {
+	shoppingCart, err := s.store.GetBasket(ctx, userId)
+	if err != nil {
+		log.Error(err)
+		return models.ShoppingCart{}, ErrGettingUserShoppingCart
+	}
+	if err := checkExpectedVersion(ctx, shoppingCart); err != nil {
+		return models.ShoppingCart{}, err
+	}
+	currency := currencyFrom(ctx)
+	if currency == "" {
+		if shoppingCart.Currency != "" {
+			return shoppingCart, nil
+		}
+		currency = s.currencies.Default
+	}
+	if currency == shoppingCart.Currency {
+		return shoppingCart, nil
+	}
+	if err := s.changeCurrency(ctx, &shoppingCart, currency); err != nil {
+		return models.ShoppingCart{}, err
+	}
+	return shoppingCart, nil
+}
+
+// changeCurrency - sets the currency of the cart and reprices it
+func (s *Service) changeCurrency(ctx context.Context, cart *models.ShoppingCart, currency string) error {
+	if !s.currencies.IsSupported(currency) {
+		return ErrUnsupportedCurrency
+	}
+	for i, item := range cart.Items {
+		product, err := s.store.GetProductById(ctx, item.ProductID.String())
+		if err != nil {
+			log.Error(err)
+			return ErrGettingProducts
+		}
+		price, ok := product.PriceIn(currency)
+		if !ok {
+			return ErrProductPriceNotFound
+		}
+		cart.Items[i].Price = price
+	}
+	cart.Currency = currency
+	s.priceBasket(ctx, cart)
+	return nil
+}
+
+// productPrice - returns the unit price of the product in the given currency
+func productPrice(product models.Product, currency string) (decimal.Decimal, error) {
+	price, ok := product.PriceIn(currency)
+	if !ok {
+		return decimal.Zero, ErrProductPriceNotFound
+	}
+	return price, nil
+}
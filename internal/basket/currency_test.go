@@ -0,0 +1,35 @@
+package basket
+
+import (
+	"context"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestService_GetBasket_RepricesWithoutSaving(t *testing.T) {
+	service, store, first, _ := newMergeTest()
+	first.Prices = []models.ProductPrice{models.NewProductPrice(first.ID, "EUR", decimal.New(3, 0))}
+	store.products[first.ID.String()] = first
+	service.currencies.Supported = append(service.currencies.Supported, "EUR")
+	stored := store.carts["user"]
+
+	cart, err := service.GetBasket(WithCurrency(context.Background(), "eur"), "user")
+	if err != nil {
+		t.Fatalf("Expected basket to be returned, got %v", err)
+	}
+	if cart.Currency != "EUR" || len(cart.Items) != 1 || !cart.Items[0].Price.Equal(decimal.New(3, 0)) {
+		t.Errorf("Expected basket to be repriced in EUR, got %s and %+v", cart.Currency, cart.Items)
+	}
+	if store.updates != 0 || store.carts["user"].Currency != stored.Currency || cart.Version != stored.Version {
+		t.Errorf("Expected basket not to be saved, got %d updates and currency %s", store.updates, store.carts["user"].Currency)
+	}
+
+	cart, err = service.UpdateItemInBasket(WithCurrency(context.Background(), "EUR"), "user", first.ID.String(), 1)
+	if err != nil {
+		t.Fatalf("Expected quantity to be updated, got %v", err)
+	}
+	if store.updates != 1 || store.carts["user"].Currency != "EUR" || cart.Version != stored.Version+1 {
+		t.Errorf("Expected basket to be saved in EUR, got %d updates and currency %s", store.updates, store.carts["user"].Currency)
+	}
+}
@@ -0,0 +1,70 @@
+package basket
+
+import (
+	"context"
+	"github.com/erdemcemal/basket-service/internal/campaign"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/shopspring/decimal"
+	log "github.com/siruspen/logrus"
+)
+
+// GetDiscountTrace - evaluates the campaigns on the shopping cart of the user and returns the inputs of every campaign
+// and why its discount is applied or not, the shopping cart is not changed
+func (s *Service) GetDiscountTrace(ctx context.Context, userId string) (dto.DiscountTraceDTO, error) {
+	shoppingCart, err := s.getBasket(ctx, userId)
+	if err != nil {
+		return dto.DiscountTraceDTO{}, err
+	}
+	campaigns, err := s.activeCampaigns(ctx)
+	if err != nil {
+		log.Error(err)
+		return dto.DiscountTraceDTO{}, ErrGettingCampaigns
+	}
+	now := s.clock.Now()
+	facts := s.customerFacts(ctx, campaigns, userId, shoppingCart.Currency, now)
+	discountCalculator, err := s.discountCalculator(ctx, campaigns, shoppingCart, facts, campaign.FixedClock(now))
+	if err != nil {
+		log.Error(err)
+		return dto.DiscountTraceDTO{}, ErrInvalidCampaigns
+	}
+	return fromTrace(userId, shoppingCart.TotalPrice, facts, discountCalculator.Explain(shoppingCart)), nil
+}
+
+// fromTrace - converts a discount calculator trace and the customer facts it is calculated with to a discount trace dto
+func fromTrace(userId string, cartAmount decimal.Decimal, facts campaign.Facts, trace campaign.Trace) dto.DiscountTraceDTO {
+	campaignTraces := []dto.CampaignTraceDTO{}
+	for _, ruleTrace := range trace.Rules {
+		inputs := ruleTrace.Inputs
+		if inputs == nil {
+			inputs = map[string]string{}
+		}
+		campaignTraces = append(campaignTraces, dto.CampaignTraceDTO{
+			CampaignID:         ruleTrace.RuleID,
+			Label:              ruleTrace.Label,
+			Priority:           ruleTrace.Priority,
+			ExclusivityGroup:   ruleTrace.ExclusivityGroup,
+			Active:             ruleTrace.Active,
+			Inputs:             inputs,
+			PreconditionPassed: ruleTrace.PreconditionPassed,
+			Amount:             ruleTrace.Amount,
+			AppliedAmount:      ruleTrace.AppliedAmount,
+			Applied:            ruleTrace.Applied,
+			Outcome:            ruleTrace.Outcome,
+		})
+	}
+	return dto.DiscountTraceDTO{
+		UserID:      userId,
+		Policy:      string(trace.Policy),
+		EvaluatedAt: trace.EvaluatedAt,
+		CartAmount:  cartAmount,
+		Facts: dto.CustomerFactsDTO{
+			GivenAmount:         facts.GivenAmount,
+			MonthlyAmount:       facts.UserMonthlyAmount,
+			RecentOrderCount:    len(facts.UserOrders),
+			Segments:            facts.Segments,
+			CampaignRedemptions: facts.CampaignRedemptions,
+		},
+		TotalDiscount: trace.Amount,
+		Campaigns:     campaignTraces,
+	}
+}
@@ -0,0 +1,150 @@
+package basket
+
+import (
+	"context"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/erdemcemal/basket-service/internal/models"
+	basketstore "github.com/erdemcemal/basket-service/internal/store/basket"
+	"github.com/shopspring/decimal"
+	log "github.com/siruspen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidMergePolicy = errors.New("basket merge policy is not valid")
+	ErrMergeBasket        = errors.New("error merging basket")
+)
+
+// MergeBasket - merges the guest basket into the basket of the user when the guest logs in and deletes the guest basket,
+// quantities of the products in both baskets are combined with the given policy or the configured policy if it is empty.
+// Items which can't be sold in the basket currency anymore are left out, the coupon and the shipping method of the guest
+// basket are kept if the basket of the user has none. The stock reserved by the guest basket is reserved for the merged basket
+// with the basket update, products whose stock is not available anymore keep the quantity of the basket of the user
+func (s *Service) MergeBasket(ctx context.Context, userId string, guestUserId string, policy string) (dto.ShoppingCartDTO, error) {
+	mergePolicy := models.MergePolicy(policy)
+	if mergePolicy == "" {
+		mergePolicy = s.mergePolicy
+	}
+	if !mergePolicy.IsValid() {
+		return dto.ShoppingCartDTO{}, ErrInvalidMergePolicy
+	}
+	var shoppingCart models.ShoppingCart
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		shoppingCart, err = s.getBasket(ctx, userId)
+		if err != nil {
+			return err
+		}
+		guestCart, err := s.store.GetBasketByUserId(ctx, guestUserId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// the guest basket is merged already or never created, so the basket of the user is returned as it is
+				return nil
+			}
+			log.Error(err)
+			return ErrGettingUserShoppingCart
+		}
+		// quantities of the products changed by the merge, they are reserved for the basket of the user when it is saved
+		reservations := make(map[string]int32)
+		for _, guestItem := range guestCart.Items {
+			if err := s.mergeItem(ctx, &shoppingCart, guestItem, mergePolicy, reservations); err != nil {
+				return err
+			}
+		}
+		if shoppingCart.CouponCode == "" && guestCart.CouponCode != "" && s.canRedeemCoupon(ctx, shoppingCart, guestCart.CouponCode) {
+			shoppingCart.ApplyCoupon(guestCart.CouponCode)
+		}
+		if shoppingCart.ShippingMethod == "" && guestCart.ShippingMethod != "" {
+			// the shipping cost is quoted for the merged basket, the method is removed if it doesn't deliver it
+			shoppingCart.SelectShipping(guestCart.ShippingMethod, guestCart.ShippingRegion, decimal.Zero)
+		}
+		s.priceBasket(ctx, &shoppingCart)
+
+		err = s.store.MergeBasket(ctx, &shoppingCart, guestCart, reservations)
+		if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, basketstore.ErrBasketVersionConflict) || errors.Is(err, basketstore.ErrStockNotAvailable) {
+			// a concurrent request has merged the guest basket, updated the basket of the user or reserved the stock,
+			// so the merge is calculated again with the latest baskets and stock
+			return ErrBasketConflict
+		}
+		if err != nil {
+			log.Error(err)
+			return ErrMergeBasket
+		}
+		return nil
+	})
+	if err != nil {
+		return dto.ShoppingCartDTO{}, err
+	}
+	return fromShoppingCart(shoppingCart), nil
+}
+
+// mergeItem - adds the item of the guest basket to the shopping cart, the quantity of a product which is already in the cart is combined with the merge policy,
+// the stock reserved by the guest basket is available for the merged quantity, which is added to the reservations
+func (s *Service) mergeItem(ctx context.Context, cart *models.ShoppingCart, guestItem models.ShoppingCartItem, policy models.MergePolicy, reservations map[string]int32) error {
+	productId := guestItem.ProductID.String()
+	product, err := s.store.GetProductById(ctx, productId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Warnf("product %s of the guest basket does not exist anymore, it is not merged", productId)
+			return nil
+		}
+		log.Error(err)
+		return ErrGettingProducts
+	}
+	available, err := s.store.AvailableStock(ctx, productId, cart.ID.String(), guestItem.ShoppingCartID)
+	if err != nil {
+		log.Error(err)
+		return ErrGettingProducts
+	}
+	userItem, exists := cart.GetCartItemByProductId(productId)
+	if !exists {
+		quantity := policy.MergedQuantity(0, guestItem.Quantity, false, available)
+		if quantity <= 0 {
+			return nil
+		}
+		price, err := productPrice(product, cart.Currency)
+		if err != nil {
+			log.Warnf("product %s of the guest basket has no price in %s, it is not merged", productId, cart.Currency)
+			return nil
+		}
+		if quantity > available {
+			log.Warnf("stock of product %s of the guest basket is not available anymore, it is not merged", productId)
+			return nil
+		}
+		reservations[productId] = quantity
+		cartItem := models.NewShoppingCartItemFromProduct(product, quantity, cart.ID.String())
+		cartItem.Price = price
+		cart.AddItem(cartItem)
+		return nil
+	}
+	quantity := policy.MergedQuantity(userItem.Quantity, guestItem.Quantity, userItem.ChangedAt().After(guestItem.ChangedAt()), available)
+	if quantity <= 0 || quantity == userItem.Quantity {
+		return nil
+	}
+	if quantity > available {
+		log.Warnf("stock of product %s is not available for the merged quantity, the quantity of the user basket is kept", productId)
+		return nil
+	}
+	reservations[productId] = quantity
+	cart.UpdateItemQuantity(productId, quantity)
+	return nil
+}
+
+// canRedeemCoupon - checks if the coupon with the given code can be applied on the shopping cart of its user
+func (s *Service) canRedeemCoupon(ctx context.Context, cart models.ShoppingCart, code string) bool {
+	coupon, err := s.store.GetCouponByCode(ctx, code)
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	if !coupon.IsValidAt(s.clock.Now()) || !coupon.AppliesIn(cart.Currency) || cart.TotalPrice.LessThan(coupon.MinBasketAmount) {
+		return false
+	}
+	userRedemptionCount, err := s.store.CountUserCouponRedemptions(ctx, coupon.ID.String(), cart.UserID)
+	if err != nil {
+		log.Error(err)
+		return false
+	}
+	return coupon.HasRedemptionsLeft(userRedemptionCount)
+}
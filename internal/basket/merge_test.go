@@ -0,0 +1,246 @@
+package basket
+
+import (
+	"context"
+	"github.com/erdemcemal/basket-service/internal/campaign"
+	"github.com/erdemcemal/basket-service/internal/config"
+	"github.com/erdemcemal/basket-service/internal/customer"
+	"github.com/erdemcemal/basket-service/internal/models"
+	basketstore "github.com/erdemcemal/basket-service/internal/store/basket"
+	campaignstore "github.com/erdemcemal/basket-service/internal/store/campaign"
+	"github.com/erdemcemal/basket-service/internal/tax"
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+// fakeStore - keeps the baskets and products in memory, the store methods the tests don't use are not implemented
+type fakeStore struct {
+	basketstore.BasketStore
+	carts     map[string]models.ShoppingCart
+	products  map[string]models.Product
+	available map[string]int32
+	// mergeErrors - errors the merges return in order, before the merges succeed
+	mergeErrors []error
+	merges      int
+	guestCartId uuid.UUID
+	// updateErrors - errors the updates return in order, before the updates succeed
+	updateErrors []error
+	reservations map[string]int32
+	updates      int
+}
+
+func (f *fakeStore) GetBasket(ctx context.Context, userId string) (models.ShoppingCart, error) {
+	cart, _ := f.GetBasketByUserId(ctx, userId)
+	return cart, nil
+}
+
+// GetBasketByUserId - returns a copy of the basket, so the changes of the service are kept only if the basket is updated
+func (f *fakeStore) GetBasketByUserId(_ context.Context, userId string) (models.ShoppingCart, error) {
+	cart, ok := f.carts[userId]
+	if !ok {
+		return models.ShoppingCart{}, gorm.ErrRecordNotFound
+	}
+	cart.Items = append([]models.ShoppingCartItem(nil), cart.Items...)
+	return cart, nil
+}
+
+func (f *fakeStore) GetProductById(_ context.Context, id string) (models.Product, error) {
+	product, ok := f.products[id]
+	if !ok {
+		return models.Product{}, gorm.ErrRecordNotFound
+	}
+	return product, nil
+}
+
+func (f *fakeStore) AvailableStock(_ context.Context, productId string, _ ...string) (int32, error) {
+	return f.available[productId], nil
+}
+
+func (f *fakeStore) MergeBasket(_ context.Context, cart *models.ShoppingCart, guestCart models.ShoppingCart, reservations map[string]int32) error {
+	f.merges++
+	if len(f.mergeErrors) > 0 {
+		err := f.mergeErrors[0]
+		f.mergeErrors = f.mergeErrors[1:]
+		return err
+	}
+	f.guestCartId = guestCart.ID
+	f.reservations = reservations
+	delete(f.carts, guestCart.UserID)
+	cart.Version++
+	f.carts[cart.UserID] = *cart
+	return nil
+}
+
+func (f *fakeStore) UpdateBasket(_ context.Context, cart *models.ShoppingCart) error {
+	f.updates++
+	if len(f.updateErrors) > 0 {
+		err := f.updateErrors[0]
+		f.updateErrors = f.updateErrors[1:]
+		return err
+	}
+	cart.Version++
+	f.carts[cart.UserID] = *cart
+	return nil
+}
+
+func (f *fakeStore) UpdateBasketWithReservation(ctx context.Context, cart *models.ShoppingCart, productId string, quantity int32) error {
+	if quantity > f.available[productId] {
+		return basketstore.ErrStockNotAvailable
+	}
+	if err := f.UpdateBasket(ctx, cart); err != nil {
+		return err
+	}
+	if f.reservations == nil {
+		f.reservations = make(map[string]int32)
+	}
+	f.reservations[productId] = quantity
+	return nil
+}
+
+func (f *fakeStore) GetUserMonthlyOrderAmount(context.Context, string, string) (decimal.Decimal, error) {
+	return decimal.Zero, nil
+}
+
+func (f *fakeStore) GetUserRecentOrders(context.Context, string, time.Time) ([]models.SalesHistory, error) {
+	return nil, nil
+}
+
+// fakeCampaignStore - has no campaigns
+type fakeCampaignStore struct {
+	campaignstore.CampaignStore
+}
+
+func (f fakeCampaignStore) GetCampaigns(context.Context, ...models.CampaignStatus) ([]models.Campaign, error) {
+	return nil, nil
+}
+
+func (f fakeCampaignStore) CountUserCampaignRedemptions(context.Context, string) (map[string]int64, error) {
+	return nil, nil
+}
+
+// fakeProfiles - returns an empty profile for every user
+type fakeProfiles struct{}
+
+func (f fakeProfiles) GetProfile(context.Context, string) (customer.Profile, error) {
+	return customer.Profile{}, nil
+}
+
+// newMergeTest - returns a service with the basket of the user and the guest basket, both have the first product,
+// the user changed it before the guest and only the guest basket has the second product
+func newMergeTest() (*Service, *fakeStore, models.Product, models.Product) {
+	first := models.Product{Base: models.Base{ID: uuid.Must(uuid.NewV4())}, Name: "first", UnitPrice: decimal.New(10, 0)}
+	second := models.Product{Base: models.Base{ID: uuid.Must(uuid.NewV4())}, Name: "second", UnitPrice: decimal.New(20, 0)}
+	changedAt := time.Date(2022, 5, 20, 12, 0, 0, 0, time.UTC)
+
+	userCart := models.NewShoppingCart("user")
+	userCart.Currency = models.BaseCurrency
+	userItem := models.NewShoppingCartItemFromProduct(first, 2, userCart.ID.String())
+	userItem.CreatedAt = changedAt
+	userCart.AddItem(userItem)
+
+	guestCart := models.NewShoppingCart("guest:token")
+	guestCart.Currency = models.BaseCurrency
+	guestItem := models.NewShoppingCartItemFromProduct(first, 3, guestCart.ID.String())
+	guestItem.CreatedAt = changedAt.Add(time.Hour)
+	guestCart.AddItem(guestItem)
+	guestCart.AddItem(models.NewShoppingCartItemFromProduct(second, 1, guestCart.ID.String()))
+
+	store := &fakeStore{
+		carts:     map[string]models.ShoppingCart{userCart.UserID: userCart, guestCart.UserID: guestCart},
+		products:  map[string]models.Product{first.ID.String(): first, second.ID.String(): second},
+		available: map[string]int32{first.ID.String(): 4, second.ID.String(): 5},
+	}
+	service := &Service{
+		store:         store,
+		campaignStore: fakeCampaignStore{},
+		customers:     fakeProfiles{},
+		clock:         campaign.SystemClock{},
+		taxes:         tax.NewEngine(tax.DefaultTable(), false),
+		currencies:    config.CurrencyConfig{Default: models.BaseCurrency, Supported: []string{models.BaseCurrency}},
+		mergePolicy:   models.MergeSumQuantities,
+	}
+	return service, store, first, second
+}
+
+func TestService_MergeBasket_Policies(t *testing.T) {
+	tests := []struct {
+		policy models.MergePolicy
+		// expectedQuantity - quantity of the product in both baskets, 4 of it is available
+		expectedQuantity int32
+		expectedReserved bool
+	}{
+		// 5 is more than the available stock, so the quantity of the user is kept
+		{policy: models.MergeSumQuantities, expectedQuantity: 2},
+		{policy: models.MergeKeepNewest, expectedQuantity: 3, expectedReserved: true},
+		{policy: models.MergeCapAtStock, expectedQuantity: 4, expectedReserved: true},
+	}
+	for _, test := range tests {
+		service, store, first, second := newMergeTest()
+		guestCartId := store.carts["guest:token"].ID
+		cart, err := service.MergeBasket(context.Background(), "user", "guest:token", string(test.policy))
+		if err != nil {
+			t.Fatalf("Expected %s merge to succeed, got %v", test.policy, err)
+		}
+		quantities := make(map[string]int32)
+		for _, item := range cart.Items {
+			quantities[item.ProductID] = item.Quantity
+		}
+		if quantities[first.ID.String()] != test.expectedQuantity || quantities[second.ID.String()] != 1 {
+			t.Errorf("Expected %s merge to have quantities %d and 1, got %v", test.policy, test.expectedQuantity, quantities)
+		}
+		// the merged quantities are reserved for the basket of the user, the unchanged quantities keep their reservation
+		reserved, ok := store.reservations[first.ID.String()]
+		if ok != test.expectedReserved || (ok && reserved != test.expectedQuantity) || store.reservations[second.ID.String()] != 1 {
+			t.Errorf("Expected %s merge to reserve %v, got %v", test.policy, test.expectedReserved, store.reservations)
+		}
+		if _, ok := store.carts["guest:token"]; ok || store.guestCartId != guestCartId {
+			t.Errorf("Expected %s merge to delete the guest basket %s, got %s", test.policy, guestCartId, store.guestCartId)
+		}
+	}
+}
+
+func TestService_MergeBasket_WithoutGuestBasket(t *testing.T) {
+	service, store, first, _ := newMergeTest()
+	delete(store.carts, "guest:token")
+	cart, err := service.MergeBasket(context.Background(), "user", "guest:token", "")
+	if err != nil {
+		t.Fatalf("Expected merge without guest basket to succeed, got %v", err)
+	}
+	if store.merges != 0 || len(cart.Items) != 1 || cart.Items[0].ProductID != first.ID.String() || cart.Items[0].Quantity != 2 {
+		t.Errorf("Expected basket of the user to be returned as it is, got %d merges and %+v", store.merges, cart.Items)
+	}
+}
+
+func TestService_MergeBasket_RetriesConflicts(t *testing.T) {
+	tests := []struct {
+		name        string
+		mergeErrors []error
+		expectedErr error
+	}{
+		{name: "stock reserved concurrently", mergeErrors: []error{basketstore.ErrStockNotAvailable}},
+		{name: "basket updated concurrently", mergeErrors: []error{basketstore.ErrBasketVersionConflict}},
+		{
+			name:        "conflict on every attempt",
+			mergeErrors: []error{basketstore.ErrBasketVersionConflict, basketstore.ErrBasketVersionConflict, basketstore.ErrBasketVersionConflict},
+			expectedErr: ErrBasketConflict,
+		},
+	}
+	for _, test := range tests {
+		service, store, _, _ := newMergeTest()
+		store.mergeErrors = test.mergeErrors
+		_, err := service.MergeBasket(context.Background(), "user", "guest:token", "")
+		if err != test.expectedErr {
+			t.Errorf("Expected %s merge to return %v, got %v", test.name, test.expectedErr, err)
+		}
+		expectedMerges := len(test.mergeErrors) + 1
+		if test.expectedErr != nil {
+			expectedMerges = len(test.mergeErrors)
+		}
+		if store.merges != expectedMerges {
+			t.Errorf("Expected %s merge to be tried %d times, got %d", test.name, expectedMerges, store.merges)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package basket
+
+import (
+	"context"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/models"
+	basketstore "github.com/erdemcemal/basket-service/internal/store/basket"
+	log "github.com/siruspen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+// updateBasketWithReservation - updates the shopping cart in the store and reserves the quantity of the product for it, the reservation
+// replaces the previous one of the product and is not made if the cart is not updated. A version conflict is returned as ErrBasketConflict
+// and other errors as the given failure
+func (s *Service) updateBasketWithReservation(ctx context.Context, cart *models.ShoppingCart, productId string, quantity int32, failure error) error {
+	err := s.store.UpdateBasketWithReservation(ctx, cart, productId, quantity)
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, basketstore.ErrBasketVersionConflict) {
+		return ErrBasketConflict
+	}
+	if errors.Is(err, basketstore.ErrStockNotAvailable) {
+		return ErrProductStockNotEnough
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrProductNotFound
+	}
+	log.Error(err)
+	return failure
+}
+
+// releaseStock - releases the reservation of the product for the shopping cart, the reservation expires anyway if it fails
+func (s *Service) releaseStock(ctx context.Context, cartId string, productId string) {
+	if err := s.store.ReleaseStock(ctx, cartId, productId); err != nil {
+		log.Errorf("error releasing stock of product %s for cart %s: %v", productId, cartId, err)
+	}
+}
+
+// SweepReservations - releases the expired stock reservations every interval until the context is done,
+// so the stock held by the abandoned baskets is available again
+func (s *Service) SweepReservations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			released, err := s.store.ReleaseExpiredReservations(ctx)
+			if err != nil {
+				log.Errorf("error releasing expired stock reservations: %v", err)
+				continue
+			}
+			if released > 0 {
+				log.Infof("released %d expired stock reservations", released)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package basket
+
+import (
+	"context"
+	basketstore "github.com/erdemcemal/basket-service/internal/store/basket"
+	"testing"
+)
+
+func TestService_UpdateItemInBasket_ReservesWithUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		quantity     int32
+		updateErrors []error
+		expectedErr  error
+		// expectedReserved - quantity reserved for the basket, 0 if nothing is reserved
+		expectedReserved int32
+	}{
+		{name: "available quantity", quantity: 4, expectedReserved: 4},
+		{name: "quantity over the stock", quantity: 5, expectedErr: ErrProductStockNotEnough},
+		{name: "basket updated concurrently", quantity: 3, updateErrors: []error{basketstore.ErrBasketVersionConflict}, expectedReserved: 3},
+		{
+			name:         "conflict on every attempt",
+			quantity:     3,
+			updateErrors: []error{basketstore.ErrBasketVersionConflict, basketstore.ErrBasketVersionConflict, basketstore.ErrBasketVersionConflict},
+			expectedErr:  ErrBasketConflict,
+		},
+	}
+	for _, test := range tests {
+		service, store, first, _ := newMergeTest()
+		store.updateErrors = test.updateErrors
+		_, err := service.UpdateItemInBasket(context.Background(), "user", first.ID.String(), test.quantity)
+		if err != test.expectedErr {
+			t.Errorf("Expected %s to return %v, got %v", test.name, test.expectedErr, err)
+		}
+		if store.reservations[first.ID.String()] != test.expectedReserved {
+			t.Errorf("Expected %s to reserve %d, got %v", test.name, test.expectedReserved, store.reservations)
+		}
+		expectedQuantity := test.expectedReserved
+		if expectedQuantity == 0 {
+			expectedQuantity = 2
+		}
+		cart := store.carts["user"]
+		if item, _ := cart.GetCartItemByProductId(first.ID.String()); item.Quantity != expectedQuantity {
+			t.Errorf("Expected %s to keep quantity %d, got %d", test.name, expectedQuantity, item.Quantity)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package basket
+
+import (
+	"context"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/erdemcemal/basket-service/internal/models"
+	log "github.com/siruspen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrShippingMethodNotFound = errors.New("shipping method not found")
+	ErrShippingNotAvailable   = errors.New("shipping method does not deliver the basket to the region")
+	ErrShippingMethodRequired = errors.New("shipping method must be selected before checkout")
+	ErrGettingShippingMethods = errors.New("error getting shipping methods")
+	ErrUpdateShippingOfBasket = errors.New("error updating shipping of basket")
+)
+
+// GetShippingMethods - returns the shipping methods which deliver the shopping cart of the user to the given region with their costs
+func (s *Service) GetShippingMethods(ctx context.Context, userId string, region string) ([]dto.ShippingMethodDTO, error) {
+	shoppingCart, err := s.getBasket(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	methods, err := s.store.GetShippingMethods(ctx)
+	if err != nil {
+		log.Error(err)
+		return nil, ErrGettingShippingMethods
+	}
+	dtoMethods := []dto.ShippingMethodDTO{}
+	for _, method := range methods {
+		cost, ok := method.Quote(region, shoppingCart.TotalWeight(), shoppingCart.TotalPrice, shoppingCart.Currency)
+		if !ok {
+			continue
+		}
+		dtoMethods = append(dtoMethods, dto.ShippingMethodDTO{Code: method.Code, Name: method.Name, Cost: cost, Currency: models.CurrencyOrBase(shoppingCart.Currency)})
+	}
+	return dtoMethods, nil
+}
+
+// SelectShippingMethod - selects the shipping method and region of the shopping cart if the method delivers the cart to the region
+func (s *Service) SelectShippingMethod(ctx context.Context, userId string, selection dto.SelectShippingMethodDTO) (dto.ShoppingCartDTO, error) {
+	var shoppingCart models.ShoppingCart
+	err := retryOnConflict(ctx, func() error {
+		var err error
+		shoppingCart, err = s.getBasket(ctx, userId)
+		if err != nil {
+			return err
+		}
+		method, err := s.store.GetShippingMethodByCode(ctx, selection.Method)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrShippingMethodNotFound
+			}
+			log.Error(err)
+			return ErrGettingShippingMethods
+		}
+		cost, ok := method.Quote(selection.Region, shoppingCart.TotalWeight(), shoppingCart.TotalPrice, shoppingCart.Currency)
+		if !ok {
+			return ErrShippingNotAvailable
+		}
+
+		// the shipping cost is quoted just now, so only the vat and the campaigns are calculated again
+		shoppingCart.SelectShipping(method.Code, selection.Region, cost)
+		s.applyRatesAndCampaigns(ctx, &shoppingCart)
+
+		return s.updateBasket(ctx, &shoppingCart, ErrUpdateShippingOfBasket)
+	})
+	if err != nil {
+		return dto.ShoppingCartDTO{}, err
+	}
+	return fromShoppingCart(shoppingCart), nil
+}
+
+// priceBasket - recalculates the shipping cost and the vat rates of the shopping cart and applies the campaigns on it,
+// the shipping method is removed if it doesn't deliver the changed cart anymore and vat is calculated after the discounts
+func (s *Service) priceBasket(ctx context.Context, cart *models.ShoppingCart) {
+	s.priceShipping(ctx, cart)
+	s.applyRatesAndCampaigns(ctx, cart)
+}
+
+// applyRatesAndCampaigns - applies the vat rates effective now and the campaigns on the shopping cart with its current shipping cost
+func (s *Service) applyRatesAndCampaigns(ctx context.Context, cart *models.ShoppingCart) {
+	if err := s.taxes.ApplyRates(cart, s.clock.Now()); err != nil {
+		log.Error(err)
+	}
+	cart.ApplyCampaigns(s.tryApplyDiscount(ctx, *cart))
+}
+
+// priceShipping - recalculates the shipping cost of the selected shipping method for the shopping cart
+func (s *Service) priceShipping(ctx context.Context, cart *models.ShoppingCart) {
+	if cart.ShippingMethod == "" {
+		return
+	}
+	method, err := s.store.GetShippingMethodByCode(ctx, cart.ShippingMethod)
+	if err != nil {
+		log.Error(err)
+		cart.ClearShipping()
+		return
+	}
+	cost, ok := method.Quote(cart.ShippingRegion, cart.TotalWeight(), cart.TotalPrice, cart.Currency)
+	if !ok {
+		log.Warnf("shipping method %s does not deliver the basket of user %s anymore", method.Code, cart.UserID)
+		cart.ClearShipping()
+		return
+	}
+	cart.SelectShipping(method.Code, cart.ShippingRegion, cost)
+}
+
+// requireShipping - checks that a shipping method is selected for the shopping cart if there are shipping methods
+func (s *Service) requireShipping(ctx context.Context, cart models.ShoppingCart) error {
+	if cart.ShippingMethod != "" {
+		return nil
+	}
+	methods, err := s.store.GetShippingMethods(ctx)
+	if err != nil {
+		log.Error(err)
+		return ErrGettingShippingMethods
+	}
+	if len(methods) > 0 {
+		return ErrShippingMethodRequired
+	}
+	return nil
+}
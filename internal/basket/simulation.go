@@ -0,0 +1,137 @@
+package basket
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/campaign"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/erdemcemal/basket-service/internal/models"
+	log "github.com/siruspen/logrus"
+	"gorm.io/gorm"
+)
+
+// SimulateCampaigns - calculates what every campaign would give for a hypothetical cart and which ones are applied
+// with the campaign policy, no basket is read or changed
+func (s *Service) SimulateCampaigns(ctx context.Context, simulation dto.SimulateCampaignsDTO) (dto.CampaignSimulationDTO, error) {
+	campaigns, err := s.activeCampaigns(ctx)
+	if err != nil {
+		log.Error(err)
+		return dto.CampaignSimulationDTO{}, ErrGettingCampaigns
+	}
+	if len(simulation.Campaigns) > 0 {
+		campaigns.Campaigns = append(campaigns.Campaigns, simulation.Campaigns...)
+		if err := campaigns.Validate(); err != nil {
+			return dto.CampaignSimulationDTO{}, fmt.Errorf("%w: %v", ErrInvalidCampaigns, err)
+		}
+	}
+
+	cart := models.NewShoppingCart(simulation.UserID)
+	cart.CouponCode = models.NormalizeCouponCode(simulation.CouponCode)
+	cart.Currency = models.NormalizeCurrency(simulation.Currency)
+	if cart.Currency == "" {
+		cart.Currency = currencyFrom(ctx)
+	}
+	if cart.Currency == "" {
+		cart.Currency = s.currencies.Default
+	}
+	if !s.currencies.IsSupported(cart.Currency) {
+		return dto.CampaignSimulationDTO{}, ErrUnsupportedCurrency
+	}
+	for _, item := range simulation.Items {
+		product, err := s.store.GetProductById(ctx, item.ProductID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return dto.CampaignSimulationDTO{}, ErrProductNotFound
+			}
+			log.Error(err)
+			return dto.CampaignSimulationDTO{}, ErrGettingProducts
+		}
+		price, err := productPrice(product, cart.Currency)
+		if err != nil {
+			return dto.CampaignSimulationDTO{}, err
+		}
+		cartItem := models.NewShoppingCartItemFromProduct(product, item.Quantity, cart.ID.String())
+		cartItem.Price = price
+		cart.AddItem(cartItem)
+	}
+	if simulation.ShippingMethod != "" {
+		method, err := s.store.GetShippingMethodByCode(ctx, simulation.ShippingMethod)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return dto.CampaignSimulationDTO{}, ErrShippingMethodNotFound
+			}
+			log.Error(err)
+			return dto.CampaignSimulationDTO{}, ErrGettingShippingMethods
+		}
+		cost, ok := method.Quote(simulation.ShippingRegion, cart.TotalWeight(), cart.TotalPrice, cart.Currency)
+		if !ok {
+			return dto.CampaignSimulationDTO{}, ErrShippingNotAvailable
+		}
+		cart.SelectShipping(method.Code, simulation.ShippingRegion, cost)
+	}
+
+	clock := s.clock
+	if simulation.At != nil {
+		clock = campaign.FixedClock(*simulation.At)
+	}
+	now := clock.Now()
+	if err := s.taxes.ApplyRates(&cart, now); err != nil {
+		log.Error(err)
+	}
+	facts := campaign.Facts{GivenAmount: s.givenAmount, Now: now}
+	if simulation.UserID != "" {
+		facts = s.customerFacts(ctx, campaigns, simulation.UserID, cart.Currency, now)
+	}
+	if simulation.MonthlyPurchaseAmount != nil {
+		facts.UserMonthlyAmount = *simulation.MonthlyPurchaseAmount
+	}
+	if simulation.Segments != nil {
+		facts.Segments = simulation.Segments
+	}
+	if simulation.OrderCount != nil {
+		// every lookback window counts the overridden orders
+		facts.RecentOrderCount = simulation.OrderCount
+	}
+	if simulation.LastOrdersAmount != nil {
+		facts.LastOrdersAmount = simulation.LastOrdersAmount
+	}
+
+	calculation, err := s.calculateDiscount(ctx, campaigns, cart, facts, clock)
+	if err != nil {
+		return dto.CampaignSimulationDTO{}, fmt.Errorf("%w: %v", ErrInvalidCampaigns, err)
+	}
+	appliedCampaigns := toAppliedCampaigns(calculation)
+	cart.ApplyCampaigns(appliedCampaigns)
+	return fromCalculation(campaigns.Policy, cart, calculation, appliedCampaigns), nil
+}
+
+// fromCalculation - converts a discount calculation of a simulated cart to a campaign simulation dto
+func fromCalculation(policy campaign.Policy, cart models.ShoppingCart, calculation campaign.Calculation, appliedCampaigns models.AppliedCampaigns) dto.CampaignSimulationDTO {
+	if policy == "" {
+		policy = campaign.PolicyBestOf
+	}
+	simulatedCampaigns := []dto.SimulatedCampaignDTO{}
+	for _, result := range calculation.Evaluated {
+		_, applied := appliedCampaigns.Find(result.RuleID)
+		items := []dto.AppliedCampaignItemDTO{}
+		for _, line := range result.Lines {
+			items = append(items, dto.AppliedCampaignItemDTO{ProductID: line.ProductID, Amount: line.Amount})
+		}
+		simulatedCampaigns = append(simulatedCampaigns, dto.SimulatedCampaignDTO{
+			CampaignID: result.RuleID,
+			Label:      result.Label,
+			Amount:     result.Amount,
+			Applied:    applied,
+			Items:      items,
+		})
+	}
+	return dto.CampaignSimulationDTO{
+		Policy:           string(policy),
+		TotalPrice:       cart.TotalPrice,
+		TotalDiscount:    cart.TotalDiscount,
+		ShippingCost:     cart.ShippingCost,
+		Campaigns:        simulatedCampaigns,
+		AppliedCampaigns: fromAppliedCampaigns(appliedCampaigns),
+	}
+}
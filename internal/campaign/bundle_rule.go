@@ -0,0 +1,64 @@
+package campaign
+
+import (
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+const bundleRuleLabel = "Bundle discount"
+
+// BundleRule - is a rule that sells a set of products for a fixed price
+type BundleRule struct {
+	// Items - units of every product in one bundle
+	Items map[string]int32
+	Price decimal.Decimal
+}
+
+// NewBundleRule - creates a new bundle rule with the given products and bundle price
+func NewBundleRule(items map[string]int32, price decimal.Decimal) *BundleRule {
+	return &BundleRule{Items: items, Price: price}
+}
+
+// CalculateDiscount - calculates the difference between the regular price and the bundle price for every complete bundle in the cart
+func (b BundleRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	if len(b.Items) == 0 {
+		return newResult(RuleTypeBundle, bundleRuleLabel, nil)
+	}
+	bundles := int32(-1)
+	regularPrice := decimal.Zero
+	var bundleItems []models.ShoppingCartItem
+	for productId, units := range b.Items {
+		item, ok := cart.GetCartItemByProductId(productId)
+		if !ok || units <= 0 {
+			return newResult(RuleTypeBundle, bundleRuleLabel, nil)
+		}
+		if count := item.Quantity / units; bundles < 0 || count < bundles {
+			bundles = count
+		}
+		regularPrice = regularPrice.Add(item.Price.Mul(decimal.NewFromInt32(units)))
+		bundleItems = append(bundleItems, item)
+	}
+	saving := regularPrice.Sub(b.Price)
+	if bundles <= 0 || !saving.IsPositive() {
+		return newResult(RuleTypeBundle, bundleRuleLabel, nil)
+	}
+	// the saving is allocated to the products by their share of the regular bundle price
+	sort.SliceStable(bundleItems, func(i, j int) bool {
+		return bundleItems[i].ProductID.String() < bundleItems[j].ProductID.String()
+	})
+	var lines []LineDiscount
+	for _, item := range bundleItems {
+		itemPrice := item.Price.Mul(decimal.NewFromInt32(b.Items[item.ProductID.String()]))
+		lines = append(lines, LineDiscount{
+			ProductID: item.ProductID.String(),
+			Amount:    saving.Mul(itemPrice).Div(regularPrice).Mul(decimal.NewFromInt32(bundles)),
+		})
+	}
+	return newResult(RuleTypeBundle, bundleRuleLabel, lines)
+}
+
+// IsFixedAmount - checks if the discount is a fixed amount, the saving of a bundle doesn't depend on the rest of the cart
+func (b BundleRule) IsFixedAmount() bool {
+	return true
+}
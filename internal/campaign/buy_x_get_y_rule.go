@@ -0,0 +1,109 @@
+package campaign
+
+import (
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+const (
+	buyXGetYRuleLabel  = "Buy X get Y discount"
+	freeItemPercentage = 100
+)
+
+// BuyXGetYRule - is a rule that gives Y units for every X units bought, of the same product or of other products
+type BuyXGetYRule struct {
+	// BuyProductIDs - products which have to be bought, empty means every product
+	BuyProductIDs []string
+	// GetProductIDs - products which are given, empty means the bought product itself
+	GetProductIDs []string
+	BuyQuantity   int32
+	GetQuantity   int32
+	// DiscountPercentage - discount on the given units, they are free if it is zero
+	DiscountPercentage decimal.Decimal
+}
+
+// NewBuyXGetYRule - creates a new buy x get y rule with the given products and quantities
+func NewBuyXGetYRule(buyProductIDs, getProductIDs []string, buyQuantity, getQuantity int32, discountPercentage decimal.Decimal) *BuyXGetYRule {
+	return &BuyXGetYRule{
+		BuyProductIDs:      buyProductIDs,
+		GetProductIDs:      getProductIDs,
+		BuyQuantity:        buyQuantity,
+		GetQuantity:        getQuantity,
+		DiscountPercentage: discountPercentage,
+	}
+}
+
+// CalculateDiscount - calculates the discount of the given units in the cart
+func (b BuyXGetYRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	if b.BuyQuantity <= 0 || b.GetQuantity <= 0 {
+		return newResult(RuleTypeBuyXGetY, buyXGetYRuleLabel, nil)
+	}
+	percentage := b.DiscountPercentage
+	if percentage.IsZero() {
+		percentage = decimal.New(freeItemPercentage, 0)
+	}
+	var lines []LineDiscount
+	if len(b.GetProductIDs) == 0 {
+		// same product: every X + Y units of an item, Y units are given
+		for _, item := range cart.Items {
+			if !matchesProduct(b.BuyProductIDs, item.ProductID.String()) {
+				continue
+			}
+			groupSize := b.BuyQuantity + b.GetQuantity
+			freeUnits := item.Quantity / groupSize * b.GetQuantity
+			if rest := item.Quantity%groupSize - b.BuyQuantity; rest > 0 {
+				freeUnits += rest
+			}
+			lines = append(lines, LineDiscount{
+				ProductID: item.ProductID.String(),
+				Amount:    percentageOf(item.Price.Mul(decimal.NewFromInt32(freeUnits)), percentage),
+			})
+		}
+		return newResult(RuleTypeBuyXGetY, buyXGetYRuleLabel, lines)
+	}
+
+	// other products: every X bought units give Y units of the cheapest given products in the cart
+	var boughtUnits int32
+	var getItems []models.ShoppingCartItem
+	for _, item := range cart.Items {
+		if matchesProduct(b.BuyProductIDs, item.ProductID.String()) {
+			boughtUnits += item.Quantity
+		}
+		if matchesProduct(b.GetProductIDs, item.ProductID.String()) {
+			getItems = append(getItems, item)
+		}
+	}
+	freeUnits := boughtUnits / b.BuyQuantity * b.GetQuantity
+	sort.SliceStable(getItems, func(i, j int) bool {
+		return getItems[i].Price.LessThan(getItems[j].Price)
+	})
+	for _, item := range getItems {
+		if freeUnits <= 0 {
+			break
+		}
+		units := item.Quantity
+		if units > freeUnits {
+			units = freeUnits
+		}
+		freeUnits -= units
+		lines = append(lines, LineDiscount{
+			ProductID: item.ProductID.String(),
+			Amount:    percentageOf(item.Price.Mul(decimal.NewFromInt32(units)), percentage),
+		})
+	}
+	return newResult(RuleTypeBuyXGetY, buyXGetYRuleLabel, lines)
+}
+
+// matchesProduct - checks if the product is in the given product ids, empty product ids match every product
+func matchesProduct(productIDs []string, productId string) bool {
+	if len(productIDs) == 0 {
+		return true
+	}
+	for _, id := range productIDs {
+		if id == productId {
+			return true
+		}
+	}
+	return false
+}
@@ -2,34 +2,257 @@ package campaign
 
 import (
 	"github.com/erdemcemal/basket-service/internal/models"
-	"math"
+	"github.com/shopspring/decimal"
+	"sort"
 )
 
 const (
+	sameProductRuleMinQuantity           = 3
 	sameProductRuleDiscountPercentage    = 8
 	purchaseAmountRuleDiscountPercentage = 10
+	sameProductRuleLabel                 = "Same product discount"
+	purchaseAmountRuleLabel              = "Monthly purchase amount discount"
+)
+
+// Policy - represents how the discounts of the rules are combined
+type Policy string
+
+const (
+	// PolicyBestOf - only the highest discount is applied
+	PolicyBestOf Policy = "best_of"
+	// PolicySum - discounts of all rules are added up
+	PolicySum Policy = "sum"
+	// PolicyPriority - rules are applied by priority and only the first rule of an exclusivity group is applied
+	PolicyPriority Policy = "priority"
+	// PolicySequential - rules are applied by priority, each one on the amount remaining after the previous ones
+	PolicySequential Policy = "sequential"
 )
 
 // DiscountCalculator - calculates the discount for the cart with available rules
 type DiscountCalculator struct {
-	rules []Rule
+	rules    []Rule
+	policy   Policy
+	rounding Rounding
+	clock    Clock
 }
 
 // Rule - represents a rule for discount calculation
 type Rule interface {
-	CalculateDiscount(cart models.ShoppingCart) float64
+	CalculateDiscount(cart models.ShoppingCart) Result
+}
+
+// FixedAmountRule - is a rule whose discount is a fixed amount instead of a share of the cart, such as a fixed coupon
+type FixedAmountRule interface {
+	IsFixedAmount() bool
+}
+
+// PrioritizedRule - represents a rule defined by a campaign with its priority and exclusivity group
+type PrioritizedRule struct {
+	Rule
+	// ID and Label - replace the rule defaults in the result when they are set
+	ID    string
+	Label string
+	// Priority - rules with higher priority are applied first
+	Priority int
+	// ExclusivityGroup - only one rule of the same group is applied, rules without a group are always stackable
+	ExclusivityGroup string
+	// Schedule - when the rule is active, empty schedule means always
+	Schedule Schedule
+	// MaxAmount - discount of the rule is limited to it, such as the remaining budget of the campaign, unlimited if it is empty
+	MaxAmount *decimal.Decimal
+}
+
+// CalculateDiscount - calculates the discount with the wrapped rule and labels the result with the campaign
+func (r PrioritizedRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	result := r.Rule.CalculateDiscount(cart)
+	if r.ID != "" {
+		result.RuleID = r.ID
+	}
+	if r.Label != "" {
+		result.Label = r.Label
+	}
+	return result
 }
 
-// NewDiscountCalculator - creates a new discount calculator with the given rules
+// NewDiscountCalculator - creates a new discount calculator with the given rules which applies the highest discount
 func NewDiscountCalculator(discountRules []Rule) *DiscountCalculator {
-	return &DiscountCalculator{rules: discountRules}
+	return NewDiscountCalculatorWithPolicy(discountRules, PolicyBestOf)
+}
+
+// NewDiscountCalculatorWithPolicy - creates a new discount calculator with the given rules and combination policy
+func NewDiscountCalculatorWithPolicy(discountRules []Rule, policy Policy) *DiscountCalculator {
+	if policy == "" {
+		policy = PolicyBestOf
+	}
+	return &DiscountCalculator{rules: discountRules, policy: policy, rounding: DefaultRounding(), clock: SystemClock{}}
+}
+
+// WithClock - sets the clock the rule schedules are evaluated with and returns the calculator
+func (dc *DiscountCalculator) WithClock(clock Clock) *DiscountCalculator {
+	if clock != nil {
+		dc.clock = clock
+	}
+	return dc
+}
+
+// WithRounding - sets the rounding of the discount amounts and returns the calculator
+func (dc *DiscountCalculator) WithRounding(rounding Rounding) *DiscountCalculator {
+	dc.rounding = rounding
+	return dc
+}
+
+// IsValid - checks if the policy is one of the known policies, empty policy means best of
+func (p Policy) IsValid() bool {
+	switch p {
+	case "", PolicyBestOf, PolicySum, PolicyPriority, PolicySequential:
+		return true
+	}
+	return false
 }
 
-// CalculateDiscount - calculates the discount for the given cart and return the highest discount amount
-func (dc *DiscountCalculator) CalculateDiscount(cart models.ShoppingCart) float64 {
-	var discount float64
+// CalculateDiscount - calculates the discount for the given cart and combines the rule discounts with the calculator policy
+func (dc *DiscountCalculator) CalculateDiscount(cart models.ShoppingCart) decimal.Decimal {
+	return dc.Calculate(cart).Amount
+}
+
+// Calculate - calculates the discount for the given cart with the rules active at the clock time and returns the results of the applied rules
+func (dc *DiscountCalculator) Calculate(cart models.ShoppingCart) Calculation {
+	calculation := Calculation{Amount: decimal.Zero}
+	evaluations := dc.evaluate(cart)
+	for _, evaluation := range evaluations {
+		calculation.Evaluated = append(calculation.Evaluated, evaluation.result)
+	}
+	switch dc.policy {
+	case PolicySum:
+		for _, evaluation := range evaluations {
+			if evaluation.result.Amount.IsPositive() {
+				calculation.add(evaluation.result)
+			}
+		}
+		return dc.capCalculation(calculation, cart)
+	case PolicyPriority:
+		appliedGroups := make(map[string]bool)
+		for _, evaluation := range byPriority(evaluations) {
+			if !evaluation.result.Amount.IsPositive() {
+				continue
+			}
+			if group := evaluation.rule.ExclusivityGroup; group != "" {
+				if appliedGroups[group] {
+					continue
+				}
+				appliedGroups[group] = true
+			}
+			calculation.add(evaluation.result)
+		}
+		return dc.capCalculation(calculation, cart)
+	case PolicySequential:
+		total := cart.AmountWithShipping()
+		if !total.IsPositive() {
+			return calculation
+		}
+		remaining := total
+		for _, evaluation := range byPriority(evaluations) {
+			result := evaluation.result
+			if !result.Amount.IsPositive() || !remaining.IsPositive() {
+				continue
+			}
+			if isFixedAmount(evaluation.rule) {
+				// fixed amounts are given in full up to the remaining amount
+				result = result.capAt(remaining, dc.rounding)
+			} else {
+				// percentage discounts are calculated on the full cart, so they are scaled down to the remaining amount
+				result = result.scale(remaining.Div(total)).round(dc.rounding).capAt(remaining, dc.rounding)
+			}
+			remaining = remaining.Sub(result.Amount)
+			calculation.add(result)
+		}
+		return dc.capCalculation(calculation, cart)
+	default:
+		best := Result{Amount: decimal.Zero}
+		for _, evaluation := range evaluations {
+			if evaluation.result.Amount.GreaterThan(best.Amount) {
+				best = evaluation.result
+			}
+		}
+		if best.Amount.IsPositive() {
+			calculation.add(best)
+		}
+		return calculation
+	}
+}
+
+// evaluation - represents a rule with the result it calculated for a cart
+type evaluation struct {
+	rule   PrioritizedRule
+	result Result
+}
+
+// evaluate - calculates the discount of every rule active at the clock time, rules without a schedule are always active
+func (dc *DiscountCalculator) evaluate(cart models.ShoppingCart) []evaluation {
+	now := dc.clock.Now()
+	var evaluations []evaluation
 	for _, rule := range dc.rules {
-		discount = math.Max(rule.CalculateDiscount(cart), discount)
+		prioritized := asPrioritized(rule)
+		if !prioritized.Schedule.IsActiveAt(now) {
+			continue
+		}
+		evaluations = append(evaluations, evaluation{rule: prioritized, result: dc.evaluateRule(prioritized, cart)})
+	}
+	return evaluations
+}
+
+// evaluateRule - calculates the discount of the rule, rounds it with the calculator rounding and limits it to the max amount of the rule
+func (dc *DiscountCalculator) evaluateRule(rule PrioritizedRule, cart models.ShoppingCart) Result {
+	result := rule.CalculateDiscount(cart).round(dc.rounding)
+	if rule.MaxAmount != nil {
+		result = result.capAt(*rule.MaxAmount, dc.rounding)
+	}
+	return result
+}
+
+// asPrioritized - returns the rule as a prioritized rule, rules which are not prioritized have no priority and schedule
+func asPrioritized(rule Rule) PrioritizedRule {
+	if prioritized, ok := rule.(PrioritizedRule); ok {
+		return prioritized
+	}
+	return PrioritizedRule{Rule: rule}
+}
+
+// isFixedAmount - checks if the discount of the rule is a fixed amount
+func isFixedAmount(rule PrioritizedRule) bool {
+	fixed, ok := rule.Rule.(FixedAmountRule)
+	return ok && fixed.IsFixedAmount()
+}
+
+// byPriority - returns the evaluations ordered by rule priority, evaluations keep their order when priorities are equal
+func byPriority(evaluations []evaluation) []evaluation {
+	ordered := make([]evaluation, len(evaluations))
+	copy(ordered, evaluations)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].rule.Priority > ordered[j].rule.Priority
+	})
+	return ordered
+}
+
+// capCalculation - limits the discount with the total price and the shipping cost of the cart by scaling down every applied result
+func (dc *DiscountCalculator) capCalculation(calculation Calculation, cart models.ShoppingCart) Calculation {
+	total := cart.AmountWithShipping()
+	if calculation.Amount.LessThanOrEqual(total) {
+		return calculation
+	}
+	factor := total.Div(calculation.Amount)
+	capped := Calculation{Amount: decimal.Zero, Evaluated: calculation.Evaluated}
+	for _, result := range calculation.Applied {
+		capped.add(result.scale(factor).round(dc.rounding))
+	}
+	// rounding the scaled lines may exceed the total by the smallest unit, it is taken from the last line
+	if excess := capped.Amount.Sub(total); excess.IsPositive() && len(capped.Applied) > 0 {
+		last := &capped.Applied[len(capped.Applied)-1]
+		last.Amount = last.Amount.Sub(excess)
+		if len(last.Lines) > 0 {
+			last.Lines[len(last.Lines)-1].Amount = last.Lines[len(last.Lines)-1].Amount.Sub(excess)
+		}
+		capped.Amount = total
 	}
-	return discount
+	return capped
 }
@@ -2,9 +2,10 @@ package campaign
 
 import (
 	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/gofrs/uuid"
 	"github.com/shopspring/decimal"
-	"math"
 	"testing"
+	"time"
 )
 
 func TestDiscountCalculator_SameProductRule_CalculateDiscount(t *testing.T) {
@@ -37,10 +38,10 @@ func TestDiscountCalculator_SameProductRule_CalculateDiscount(t *testing.T) {
 	// 1. discount for first item is 0.8 * 10 - (item.Quantity-3) = 10 * 1 * 8 / 100 = 0.8
 	// 2. discount for second item is 0.8 * 10 - (item.Quantity-3) = 10 * 2 * 8 / 100 = 1.6
 	// expected result is 1.6 + 0.8 = 2.4
-	expectedDiscount := 2.4
-	discount := math.Round(dc.CalculateDiscount(cart)*100) / 100
-	if discount != expectedDiscount {
-		t.Errorf("Expected discount to be 2.4, got %f", discount)
+	expectedDiscount := decimal.NewFromFloat(2.4)
+	discount := dc.CalculateDiscount(cart)
+	if !discount.Equal(expectedDiscount) {
+		t.Errorf("Expected discount to be 2.4, got %s", discount)
 	}
 }
 
@@ -70,20 +71,23 @@ func TestPurchaseAmountRule_CalculateDiscount(t *testing.T) {
 		},
 	}
 	cart.CalculateTotalPrice()
-	purchaseAmountRule := NewPurchaseAmountRule(100, 150)
+	purchaseAmountRule := NewPurchaseAmountRule(decimal.New(100, 0), decimal.New(150, 0))
 	dc := NewDiscountCalculator([]Rule{purchaseAmountRule})
 
 	// cart total price is cart.TotalPrice = (10 * 1) + (10 * 2) + (10 * 3) + (50 * 4) + (50 * 5) = 510
 	// expected discount is 510 * 10 / 100 = 51
-	expectedDiscount := 51.0
-	discount := math.Round(dc.CalculateDiscount(cart)*100) / 100
-	if discount != expectedDiscount {
-		t.Errorf("Expected discount to be 51, got %f", discount)
+	expectedDiscount := decimal.New(51, 0)
+	discount := dc.CalculateDiscount(cart)
+	if !discount.Equal(expectedDiscount) {
+		t.Errorf("Expected discount to be 51, got %s", discount)
 	}
 }
 
-func TestEveryFourthOrderRule_CalculateDiscount(t *testing.T) {
-	rule := EveryFourthOrderRule{MinPurchaseAmountInMonth: 250, LastFourthOrderAmount: 350}
+func TestEveryNthOrderRule_CalculateDiscount(t *testing.T) {
+	now := time.Date(2022, 5, 20, 12, 0, 0, 0, time.UTC)
+	// three previous orders of the user within the last month, so the next one is the fourth
+	orders := userOrders(100, now.AddDate(0, 0, -1), now.AddDate(0, 0, -10), now.AddDate(0, 0, -20))
+	rule := NewEveryNthOrderRule(decimal.New(250, 0), 4, 30, orders, now)
 	dc := NewDiscountCalculator([]Rule{rule})
 
 	cart := models.ShoppingCart{
@@ -91,27 +95,27 @@ func TestEveryFourthOrderRule_CalculateDiscount(t *testing.T) {
 			{
 				Quantity: 1,
 				Price:    decimal.New(10, 0),
-				VatRate:  1,
+				VatRate:  decimal.New(1, 0),
 			},
 			{
 				Quantity: 2,
 				Price:    decimal.New(10, 0),
-				VatRate:  1,
+				VatRate:  decimal.New(1, 0),
 			},
 			{
 				Quantity: 3,
 				Price:    decimal.New(10, 0),
-				VatRate:  8,
+				VatRate:  decimal.New(8, 0),
 			},
 			{
 				Quantity: 4,
 				Price:    decimal.New(50, 0),
-				VatRate:  8,
+				VatRate:  decimal.New(8, 0),
 			},
 			{
 				Quantity: 5,
 				Price:    decimal.New(50, 0),
-				VatRate:  18,
+				VatRate:  decimal.New(18, 0),
 			},
 		},
 	}
@@ -121,9 +125,315 @@ func TestEveryFourthOrderRule_CalculateDiscount(t *testing.T) {
 	// 50 * 4 * 0.1 = 20
 	// 50 * 5 * 0.15 = 37.5
 	// expected discount is 37.5 + 3 + 20 = 60.5
-	expectedDiscount := 60.5
-	discount := math.Round(dc.CalculateDiscount(cart)*100) / 100
-	if discount != expectedDiscount {
-		t.Errorf("Expected discount to be 60.5, got %f", discount)
+	expectedDiscount := decimal.NewFromFloat(60.5)
+	discount := dc.CalculateDiscount(cart)
+	if !discount.Equal(expectedDiscount) {
+		t.Errorf("Expected discount to be 60.5, got %s", discount)
+	}
+}
+
+func TestEveryNthOrderRule_IsPerUserOrderSequence(t *testing.T) {
+	now := time.Date(2022, 5, 20, 12, 0, 0, 0, time.UTC)
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{Quantity: 1, Price: decimal.New(300, 0), VatRate: decimal.New(18, 0)},
+		},
+	}
+	cart.CalculateTotalPrice()
+
+	tests := []struct {
+		name           string
+		orderInterval  int32
+		lookbackDays   int32
+		orders         []Order
+		minOrderAmount decimal.Decimal
+		expected       decimal.Decimal
+	}{
+		{"first order of the user", 4, 30, nil, decimal.New(250, 0), decimal.Zero},
+		{"third order of the user", 4, 30, userOrders(300, now.AddDate(0, 0, -1), now.AddDate(0, 0, -2)), decimal.New(250, 0), decimal.Zero},
+		{"fourth order of the user", 0, 0, userOrders(100, now.AddDate(0, 0, -1), now.AddDate(0, 0, -2), now.AddDate(0, 0, -3)), decimal.New(250, 0), decimal.New(45, 0)},
+		{"orders before the lookback window", 4, 7, userOrders(300, now.AddDate(0, 0, -1), now.AddDate(0, 0, -2), now.AddDate(0, 0, -10)), decimal.New(250, 0), decimal.Zero},
+		{"second order with interval of two", 2, 30, userOrders(300, now.AddDate(0, 0, -1)), decimal.New(250, 0), decimal.New(45, 0)},
+		{"last orders total under min amount", 2, 30, userOrders(300, now.AddDate(0, 0, -1)), decimal.New(500, 0), decimal.Zero},
+		{
+			name:          "only the last orders by sequence are added up",
+			orderInterval: 2,
+			lookbackDays:  30,
+			orders: []Order{
+				{Sequence: 1, Amount: decimal.New(400, 0), CreatedAt: now.AddDate(0, 0, -3)},
+				{Sequence: 3, Amount: decimal.New(100, 0), CreatedAt: now.AddDate(0, 0, -1)},
+				{Sequence: 2, Amount: decimal.New(100, 0), CreatedAt: now.AddDate(0, 0, -2)},
+			},
+			minOrderAmount: decimal.New(250, 0),
+			expected:       decimal.Zero,
+		},
+		{
+			name:          "orders in another currency are not added up",
+			orderInterval: 2,
+			lookbackDays:  30,
+			orders: []Order{
+				{Sequence: 1, Amount: decimal.New(300, 0), Currency: "EUR", CreatedAt: now.AddDate(0, 0, -1)},
+			},
+			minOrderAmount: decimal.New(250, 0),
+			expected:       decimal.Zero,
+		},
+	}
+	for _, test := range tests {
+		rule := NewEveryNthOrderRule(test.minOrderAmount, test.orderInterval, test.lookbackDays, test.orders, now)
+		discount := rule.CalculateDiscount(cart).Amount
+		if !discount.Equal(test.expected) {
+			t.Errorf("Expected discount of %s to be %s, got %s", test.name, test.expected, discount)
+		}
+	}
+}
+
+func TestEveryNthOrderRule_RecentOrderCount(t *testing.T) {
+	now := time.Date(2022, 5, 20, 12, 0, 0, 0, time.UTC)
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{Quantity: 1, Price: decimal.New(300, 0), VatRate: decimal.New(18, 0)},
+		},
+	}
+	cart.CalculateTotalPrice()
+
+	lastOrdersAmount := decimal.New(300, 0)
+	tests := []struct {
+		name             string
+		orderCount       int32
+		lastOrdersAmount *decimal.Decimal
+		orders           []Order
+		expected         decimal.Decimal
+	}{
+		{"count and total of three previous orders", 3, &lastOrdersAmount, nil, decimal.New(45, 0)},
+		{"count of three previous orders without their total", 3, nil, nil, decimal.Zero},
+		{"count overrides the orders", 1, nil, userOrders(100, now.AddDate(0, 0, -1), now.AddDate(0, 0, -2), now.AddDate(0, 0, -3)), decimal.Zero},
+		{"count with the total of the orders", 3, nil, userOrders(100, now.AddDate(0, 0, -1), now.AddDate(0, 0, -2), now.AddDate(0, 0, -3)), decimal.New(45, 0)},
+	}
+	for _, test := range tests {
+		rule := NewEveryNthOrderRule(decimal.New(250, 0), 4, 30, test.orders, now)
+		rule.RecentOrderCount = &test.orderCount
+		rule.LastOrdersAmount = test.lastOrdersAmount
+		discount := rule.CalculateDiscount(cart).Amount
+		if !discount.Equal(test.expected) {
+			t.Errorf("Expected discount of %s to be %s, got %s", test.name, test.expected, discount)
+		}
+	}
+}
+
+func TestEveryNthOrderRule_VatRateDiscountPercentages(t *testing.T) {
+	now := time.Date(2022, 5, 20, 12, 0, 0, 0, time.UTC)
+	rule := NewEveryNthOrderRule(decimal.New(250, 0), 1, 30, userOrders(300, now.AddDate(0, 0, -1)), now)
+	rule.VatRateDiscountPercentages = map[string]decimal.Decimal{"5.5": decimal.New(10, 0), "20.0": decimal.New(20, 0)}
+	tests := []struct {
+		name     string
+		vatRate  decimal.Decimal
+		expected decimal.Decimal
+	}{
+		{"fractional vat rate", decimal.RequireFromString("5.5"), decimal.New(30, 0)},
+		{"vat rate written with decimals", decimal.New(20, 0), decimal.New(60, 0)},
+		{"vat rate without percentage", decimal.New(8, 0), decimal.Zero},
+	}
+	for _, test := range tests {
+		cart := models.ShoppingCart{Items: []models.ShoppingCartItem{{Quantity: 1, Price: decimal.New(300, 0), VatRate: test.vatRate}}}
+		cart.CalculateTotalPrice()
+		discount := rule.CalculateDiscount(cart).Amount
+		if !discount.Equal(test.expected) {
+			t.Errorf("Expected discount of %s to be %s, got %s", test.name, test.expected, discount)
+		}
+	}
+}
+
+// userOrders - returns the previous orders of the user with the given amount at the given dates, the latest order comes first
+func userOrders(amount int64, dates ...time.Time) []Order {
+	var orders []Order
+	for i, date := range dates {
+		orders = append(orders, Order{Sequence: int64(len(dates) - i), Amount: decimal.New(amount, 0), CreatedAt: date})
+	}
+	return orders
+}
+
+// fixedRule - is a rule which always returns the same discount
+type fixedRule int64
+
+func (f fixedRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	return Result{Amount: decimal.New(int64(f), 0)}
+}
+
+func TestDiscountCalculator_Policies(t *testing.T) {
+	cart := models.ShoppingCart{TotalPrice: decimal.New(100, 0)}
+	rules := []Rule{
+		PrioritizedRule{Rule: fixedRule(10), Priority: 1, ExclusivityGroup: "loyalty"},
+		PrioritizedRule{Rule: fixedRule(20), Priority: 2, ExclusivityGroup: "loyalty"},
+		PrioritizedRule{Rule: fixedRule(5), Priority: 0},
+	}
+	tests := []struct {
+		policy   Policy
+		expected string
+	}{
+		{policy: PolicyBestOf, expected: "20"},
+		{policy: PolicySum, expected: "35"},
+		// only the loyalty rule with the highest priority is stacked with the rule without a group
+		{policy: PolicyPriority, expected: "25"},
+		// 20 off 100, then 10% of the remaining 80 and 5% of the remaining 72
+		{policy: PolicySequential, expected: "31.6"},
+	}
+	for _, test := range tests {
+		dc := NewDiscountCalculatorWithPolicy(rules, test.policy)
+		discount := dc.CalculateDiscount(cart)
+		if !discount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected discount to be %s with %s policy, got %s", test.expected, test.policy, discount)
+		}
+	}
+}
+
+func TestDiscountCalculator_SequentialPolicy_FixedAmountAfterPercentage(t *testing.T) {
+	productId := uuid.Must(uuid.NewV4())
+	cart := models.ShoppingCart{Items: []models.ShoppingCartItem{{ProductID: productId, Quantity: 1, Price: decimal.New(100, 0)}}}
+	cart.CalculateTotalPrice()
+	tests := []struct {
+		name        string
+		couponValue decimal.Decimal
+		expected    string
+	}{
+		// 10% of 100, then the fixed 10 in full on the remaining 90
+		{"fixed amount less than the remaining amount", decimal.New(10, 0), "20"},
+		// 10% of 100, then the fixed amount up to the remaining 90
+		{"fixed amount more than the remaining amount", decimal.New(95, 0), "100"},
+	}
+	for _, test := range tests {
+		rules := []Rule{
+			PrioritizedRule{Rule: NewPercentageRule(decimal.Zero, decimal.New(10, 0)), Priority: 2},
+			PrioritizedRule{Rule: NewCouponRule(models.Coupon{Code: "FIXED", Type: models.CouponTypeFixed, Value: test.couponValue}), Priority: 1},
+		}
+		discount := NewDiscountCalculatorWithPolicy(rules, PolicySequential).CalculateDiscount(cart)
+		if !discount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected discount of %s to be %s, got %s", test.name, test.expected, discount)
+		}
+	}
+}
+
+func TestDiscountCalculator_SumPolicy_CapsDiscountAtCartTotal(t *testing.T) {
+	cart := models.ShoppingCart{TotalPrice: decimal.New(30, 0)}
+	dc := NewDiscountCalculatorWithPolicy([]Rule{fixedRule(20), fixedRule(20)}, PolicySum)
+	if discount := dc.CalculateDiscount(cart); !discount.Equal(decimal.New(30, 0)) {
+		t.Errorf("Expected discount to be 30, got %s", discount)
+	}
+}
+
+func TestDiscountCalculator_Calculate_ReturnsItemisedResult(t *testing.T) {
+	first := uuid.Must(uuid.NewV4())
+	second := uuid.Must(uuid.NewV4())
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{ProductID: first, Quantity: 5, Price: decimal.New(10, 0)},
+			{ProductID: second, Quantity: 1, Price: decimal.New(10, 0)},
+		},
+	}
+	cart.CalculateTotalPrice()
+	rule := PrioritizedRule{Rule: SameProductRule{}, ID: "bulk", Label: "Bulk discount"}
+	calculation := NewDiscountCalculator([]Rule{rule}).Calculate(cart)
+
+	if len(calculation.Applied) != 1 {
+		t.Fatalf("Expected 1 applied result, got %d", len(calculation.Applied))
+	}
+	result := calculation.Applied[0]
+	if result.RuleID != "bulk" || result.Label != "Bulk discount" {
+		t.Errorf("Expected result to be labeled with the campaign, got %s %s", result.RuleID, result.Label)
+	}
+	// only the first item has more than 3 units: 10 * 2 * 8 / 100 = 1.6
+	if len(result.Lines) != 1 || result.Lines[0].ProductID != first.String() {
+		t.Fatalf("Expected discount to be allocated to the first item only, got %v", result.Lines)
+	}
+	if !result.Lines[0].Amount.Equal(decimal.NewFromFloat(1.6)) {
+		t.Errorf("Expected line discount to be 1.6, got %s", result.Lines[0].Amount)
+	}
+}
+
+func TestDiscountCalculator_Rounding(t *testing.T) {
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{Quantity: 4, Price: decimal.RequireFromString("1.5625")},
+		},
+	}
+	// 1.5625 * 1 * 8 / 100 = 0.125
+	tests := []struct {
+		mode     RoundingMode
+		expected string
+	}{
+		{mode: RoundingHalfUp, expected: "0.13"},
+		{mode: RoundingHalfEven, expected: "0.12"},
+	}
+	for _, test := range tests {
+		dc := NewDiscountCalculator([]Rule{SameProductRule{}}).WithRounding(Rounding{Mode: test.mode})
+		discount := dc.CalculateDiscount(cart)
+		if !discount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected discount to be %s with %s rounding, got %s", test.expected, test.mode, discount)
+		}
+	}
+}
+
+func TestDiscountCalculator_CurrencyRounding(t *testing.T) {
+	// 1.5625 * 1 * 8 / 100 = 0.125
+	tests := []struct {
+		currency string
+		expected string
+	}{
+		{currency: "EUR", expected: "0.13"},
+		{currency: "JPY", expected: "0"},
+		{currency: "KWD", expected: "0.125"},
+	}
+	for _, test := range tests {
+		cart := models.ShoppingCart{
+			Currency: test.currency,
+			Items: []models.ShoppingCartItem{
+				{Quantity: 4, Price: decimal.RequireFromString("1.5625")},
+			},
+		}
+		dc := NewDiscountCalculator([]Rule{SameProductRule{}}).WithRounding(DefaultRounding().ForCurrency(test.currency))
+		discount := dc.CalculateDiscount(cart)
+		if !discount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected discount in %s to be %s, got %s", test.currency, test.expected, discount)
+		}
+	}
+}
+
+func TestCouponRule_CalculateDiscount(t *testing.T) {
+	phone := uuid.Must(uuid.NewV4())
+	keyHolder := uuid.Must(uuid.NewV4())
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{ProductID: phone, Quantity: 1, Price: decimal.New(300, 0)},
+			{ProductID: keyHolder, Quantity: 2, Price: decimal.New(50, 0)},
+		},
+	}
+	cart.CalculateTotalPrice()
+	tests := []struct {
+		name     string
+		coupon   models.Coupon
+		expected string
+	}{
+		{name: "percent", coupon: models.Coupon{Code: "P", Type: models.CouponTypePercent, Value: decimal.New(10, 0)}, expected: "40"},
+		{name: "fixed", coupon: models.Coupon{Code: "F", Type: models.CouponTypeFixed, Value: decimal.New(30, 0)}, expected: "30"},
+		{name: "fixed on eligible products", coupon: models.Coupon{Code: "E", Type: models.CouponTypeFixed, Value: decimal.New(500, 0), EligibleProductIDs: models.StringList{keyHolder.String()}}, expected: "100"},
+		{name: "free item", coupon: models.Coupon{Code: "I", Type: models.CouponTypeFreeItem}, expected: "50"},
+		{name: "below min basket amount", coupon: models.Coupon{Code: "M", Type: models.CouponTypePercent, Value: decimal.New(10, 0), MinBasketAmount: decimal.New(500, 0)}, expected: "0"},
+	}
+	for _, test := range tests {
+		dc := NewDiscountCalculator([]Rule{NewCouponRule(test.coupon)})
+		discount := dc.CalculateDiscount(cart)
+		if !discount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected %s coupon discount to be %s, got %s", test.name, test.expected, discount)
+		}
+	}
+}
+
+func TestDiscountCalculator_Calculate_ReturnsEveryEvaluatedRule(t *testing.T) {
+	cart := models.ShoppingCart{TotalPrice: decimal.New(100, 0)}
+	calculation := NewDiscountCalculator([]Rule{fixedRule(10), fixedRule(20), fixedRule(0)}).Calculate(cart)
+	if len(calculation.Evaluated) != 3 {
+		t.Errorf("Expected 3 evaluated rules, got %d", len(calculation.Evaluated))
+	}
+	if len(calculation.Applied) != 1 || !calculation.Applied[0].Amount.Equal(decimal.New(20, 0)) {
+		t.Errorf("Expected only the highest discount to be applied, got %v", calculation.Applied)
 	}
 }
@@ -0,0 +1,92 @@
+package campaign
+
+import (
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// CouponExclusivityGroup - exclusivity group of the coupon rules, so only one coupon is applied with the priority policy
+const CouponExclusivityGroup = "coupon"
+
+// CouponRule - is a rule that applies the discount of a coupon entered by the shopper
+type CouponRule struct {
+	Coupon models.Coupon
+}
+
+// NewCouponRule - creates a new coupon rule with the given coupon
+func NewCouponRule(coupon models.Coupon) *CouponRule {
+	return &CouponRule{Coupon: coupon}
+}
+
+// CalculateDiscount - calculates the coupon discount on the eligible items if the cart total reaches the coupon minimum amount
+func (c CouponRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	id := c.Coupon.CampaignID()
+	label := "Coupon " + c.Coupon.Code
+	if cart.TotalPrice.LessThan(c.Coupon.MinBasketAmount) {
+		return newResult(id, label, nil)
+	}
+	var eligibleItems []models.ShoppingCartItem
+	eligibleAmount := decimal.Zero
+	for _, item := range cart.Items {
+		if c.Coupon.IsEligible(item.ProductID.String()) {
+			eligibleItems = append(eligibleItems, item)
+			eligibleAmount = eligibleAmount.Add(item.Price.Mul(decimal.NewFromInt32(item.Quantity)))
+		}
+	}
+	if !eligibleAmount.IsPositive() {
+		return newResult(id, label, nil)
+	}
+
+	var lines []LineDiscount
+	switch c.Coupon.Type {
+	case models.CouponTypePercent:
+		for _, item := range eligibleItems {
+			lines = append(lines, LineDiscount{
+				ProductID: item.ProductID.String(),
+				Amount:    percentageOf(item.Price.Mul(decimal.NewFromInt32(item.Quantity)), c.Coupon.Value),
+			})
+		}
+	case models.CouponTypeFixed:
+		// fixed amount is allocated to the eligible items by their share of the eligible amount
+		amount := decimal.Min(c.Coupon.Value, eligibleAmount)
+		for _, item := range eligibleItems {
+			lines = append(lines, LineDiscount{
+				ProductID: item.ProductID.String(),
+				Amount:    amount.Mul(item.Price.Mul(decimal.NewFromInt32(item.Quantity))).Div(eligibleAmount),
+			})
+		}
+	case models.CouponTypeFreeItem:
+		cheapest := eligibleItems[0]
+		for _, item := range eligibleItems[1:] {
+			if item.Price.LessThan(cheapest.Price) {
+				cheapest = item
+			}
+		}
+		lines = append(lines, LineDiscount{ProductID: cheapest.ProductID.String(), Amount: cheapest.Price})
+	}
+	return newResult(id, label, lines)
+}
+
+// IsFixedAmount - checks if the coupon takes a fixed amount or a free item off the cart
+func (c CouponRule) IsFixedAmount() bool {
+	return c.Coupon.Type == models.CouponTypeFixed || c.Coupon.Type == models.CouponTypeFreeItem
+}
+
+// Explain - returns the coupon the rule is calculated with and if the cart total reaches the coupon minimum amount
+func (c CouponRule) Explain(cart models.ShoppingCart) Explanation {
+	explanation := Explanation{
+		Inputs: map[string]string{
+			"code":              c.Coupon.Code,
+			"type":              string(c.Coupon.Type),
+			"value":             c.Coupon.Value.String(),
+			"min_basket_amount": c.Coupon.MinBasketAmount.String(),
+			"cart_amount":       cart.TotalPrice.String(),
+		},
+		PreconditionPassed: !cart.TotalPrice.LessThan(c.Coupon.MinBasketAmount),
+	}
+	if !explanation.PreconditionPassed {
+		explanation.Reason = fmt.Sprintf("cart amount %s is less than the coupon minimum amount %s", cart.TotalPrice, c.Coupon.MinBasketAmount)
+	}
+	return explanation
+}
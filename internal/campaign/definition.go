@@ -0,0 +1,378 @@
+package campaign
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	RuleTypePurchaseAmount = "purchase_amount"
+	RuleTypeEveryNthOrder  = "every_nth_order"
+	// RuleTypeEveryFourthOrder - is an every nth order campaign whose interval is four unless it is set
+	RuleTypeEveryFourthOrder = "every_fourth_order"
+	RuleTypeSameProduct      = "same_product"
+	RuleTypeBuyXGetY         = "buy_x_get_y"
+	RuleTypeBundle           = "bundle"
+	RuleTypeTieredQuantity   = "tiered_quantity"
+	RuleTypePercentage       = "percentage"
+	// RuleTypeShipping - discounts the shipping cost of the cart, a hundred percent is free shipping
+	RuleTypeShipping = "shipping"
+)
+
+var (
+	ErrUnknownRuleType      = errors.New("unknown campaign rule type")
+	ErrMissingCampaignID    = errors.New("campaign id is required")
+	ErrDuplicateCampaignID  = errors.New("duplicate campaign id")
+	ErrInvalidPercentage    = errors.New("discount percentage must be between 0 and 100")
+	ErrInvalidVatRate       = errors.New("vat rate of the discount percentages is not a number")
+	ErrInvalidValidityRange = errors.New("campaign valid_from must be before valid_until")
+	ErrUnknownPolicy        = errors.New("unknown campaign combination policy")
+	ErrUnknownRoundingMode  = errors.New("unknown discount rounding mode")
+	ErrInvalidPrecision     = errors.New("discount precision must be between 0 and 8")
+	ErrInvalidQuantityRule  = errors.New("invalid quantity campaign")
+	ErrInvalidSchedule      = errors.New("invalid campaign schedule")
+	ErrInvalidBudget        = errors.New("campaign budget and redemption limit must not be negative")
+)
+
+// Config - represents the campaign definitions loaded at startup
+type Config struct {
+	// Policy - how the discounts of the campaigns are combined, best of is used if it is empty
+	Policy Policy `json:"policy,omitempty"`
+	// Rounding - how discount amounts are rounded, half up with the precision of the basket currency is used if it is empty
+	Rounding  Rounding     `json:"rounding"`
+	Campaigns []Definition `json:"campaigns"`
+	// Registry - rule types the campaigns are built with, the default registry is used if it is empty
+	Registry *Registry `json:"-"`
+}
+
+// Definition - represents a declarative campaign definition which is turned into a rule
+type Definition struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Label - human readable name of the campaign shown with the applied discount
+	Label string `json:"label,omitempty"`
+	// MinPurchaseAmount - threshold the customer history must exceed, given amount is used if it is not set
+	MinPurchaseAmount *decimal.Decimal `json:"min_purchase_amount,omitempty"`
+	// MinQuantity - quantity of the same product after which the discount is applied
+	MinQuantity int32           `json:"min_quantity,omitempty"`
+	Percentage  decimal.Decimal `json:"percentage,omitempty"`
+	// OrderInterval and LookbackDays - every nth order of the user in the lookback window gets the discount
+	OrderInterval int32 `json:"order_interval,omitempty"`
+	LookbackDays  int32 `json:"lookback_days,omitempty"`
+	// VatRatePercentages - discount percentages per vat rate bucket, the vat rates are numbers such as "8" or "5.5"
+	VatRatePercentages map[string]decimal.Decimal `json:"vat_rate_percentages,omitempty"`
+	ValidFrom          *time.Time                 `json:"valid_from,omitempty"`
+	ValidUntil         *time.Time                 `json:"valid_until,omitempty"`
+	// DaysOfWeek, Hours and Timezone - limit the campaign to days of week and hours of day, such as a happy hour
+	DaysOfWeek []string    `json:"days_of_week,omitempty"`
+	Hours      []HourRange `json:"hours,omitempty"`
+	Timezone   string      `json:"timezone,omitempty"`
+	// Priority - campaigns with higher priority are applied first by the priority and sequential policies
+	Priority int `json:"priority,omitempty"`
+	// ExclusivityGroup - only one campaign of the same group is applied by the priority policy
+	ExclusivityGroup string `json:"exclusivity_group,omitempty"`
+	// ProductIDs - products bought for buy x get y and priced with the tiers, empty means every product
+	ProductIDs []string `json:"product_ids,omitempty"`
+	// GetProductIDs - products given by buy x get y, empty means the bought product itself
+	GetProductIDs []string `json:"get_product_ids,omitempty"`
+	BuyQuantity   int32    `json:"buy_quantity,omitempty"`
+	GetQuantity   int32    `json:"get_quantity,omitempty"`
+	// BundleItems - units of every product in a bundle sold for the bundle price
+	BundleItems map[string]int32 `json:"bundle_items,omitempty"`
+	BundlePrice decimal.Decimal  `json:"bundle_price,omitempty"`
+	Tiers       []Tier           `json:"tiers,omitempty"`
+	// ShippingMethods - shipping methods a shipping campaign discounts, every method if it is empty
+	ShippingMethods []string `json:"shipping_methods,omitempty"`
+	// Include and Exclude - select the cart items the campaign is calculated on, every item if they are empty
+	Include *ProductFilter `json:"include,omitempty"`
+	Exclude *ProductFilter `json:"exclude,omitempty"`
+	// Segments - customer segments the campaign is applied for, such as "gold", "vip" or "first_time_buyer", every customer if it is empty
+	Segments []string `json:"segments,omitempty"`
+	// Currencies - basket currencies the campaign is applied in, such as the currency of its amounts, every currency if it is empty
+	Currencies []string `json:"currencies,omitempty"`
+	// Budget - total discount the campaign can give, unlimited if it is not set
+	Budget *decimal.Decimal `json:"budget,omitempty"`
+	// BudgetCurrency - currency of the budget, the base currency if it is empty, a campaign with a budget is applied only in it
+	BudgetCurrency string `json:"budget_currency,omitempty"`
+	// MaxRedemptionsPerUser - how many orders of a user the campaign can be applied on, unlimited if it is zero
+	MaxRedemptionsPerUser int64 `json:"max_redemptions_per_user,omitempty"`
+	// Params - fields of the rule types registered by other packages
+	Params json.RawMessage `json:"params,omitempty"`
+	// RemainingBudget - discount the stored campaign can still give, the discount of the campaign is limited to it
+	RemainingBudget *decimal.Decimal `json:"-"`
+}
+
+// Facts - represents the customer facts the rules are built with
+type Facts struct {
+	GivenAmount decimal.Decimal
+	// UserMonthlyAmount - total of the orders of the user in the last month in the basket currency
+	UserMonthlyAmount decimal.Decimal
+	// UserOrders - previous orders of the user, at least within the longest lookback window
+	UserOrders []Order
+	// RecentOrderCount - number of the previous orders of the user in every lookback window, overrides the orders if it is set, such as in simulations
+	RecentOrderCount *int32
+	// LastOrdersAmount - total of the last orders of the user, overrides the order amounts if it is set, such as in simulations
+	LastOrdersAmount *decimal.Decimal
+	// Now - time the order history is evaluated at
+	Now time.Time
+	// Segments - segments of the customer, such as the loyalty tier
+	Segments []string
+	// CampaignRedemptions - how many orders of the customer every campaign is applied on by campaign id
+	CampaignRedemptions map[string]int64
+}
+
+// Order - represents a previous order of the user with its number among all orders of the user
+type Order struct {
+	Sequence int64
+	Amount   decimal.Decimal
+	// Currency - currency of the amount, the base currency if it is empty
+	Currency  string
+	CreatedAt time.Time
+}
+
+// DefaultConfig - returns the campaign definitions used when no campaign file is configured
+func DefaultConfig() Config {
+	return Config{
+		Policy:   PolicyBestOf,
+		Rounding: DefaultRounding(),
+		Campaigns: []Definition{
+			{ID: "purchase-amount", Type: RuleTypePurchaseAmount, Percentage: decimal.New(purchaseAmountRuleDiscountPercentage, 0)},
+			{
+				ID:            "every-fourth-order",
+				Type:          RuleTypeEveryNthOrder,
+				OrderInterval: defaultOrderInterval,
+				LookbackDays:  defaultLookbackDays,
+				VatRatePercentages: map[string]decimal.Decimal{
+					strconv.Itoa(lowVatRate):  decimal.New(lowVatRateDiscountPercentage, 0),
+					strconv.Itoa(highVatRate): decimal.New(highVatRateDiscountPercentage, 0),
+				},
+			},
+			{ID: "same-product", Type: RuleTypeSameProduct, MinQuantity: sameProductRuleMinQuantity, Percentage: decimal.New(sameProductRuleDiscountPercentage, 0)},
+		},
+	}
+}
+
+// LoadConfig - reads and validates the campaign definitions from the given json file
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read campaign file %s: %w", path, err)
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return Config{}, fmt.Errorf("failed to parse campaign file %s: %w", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// Validate - checks that every campaign definition can be turned into a rule
+func (c Config) Validate() error {
+	if !c.Policy.IsValid() {
+		return fmt.Errorf("%w: %q", ErrUnknownPolicy, c.Policy)
+	}
+	if err := c.Rounding.Validate(); err != nil {
+		return err
+	}
+	ids := make(map[string]bool)
+	for _, definition := range c.Campaigns {
+		if err := definition.validate(c.registry()); err != nil {
+			return err
+		}
+		if ids[definition.ID] {
+			return fmt.Errorf("%w: %s", ErrDuplicateCampaignID, definition.ID)
+		}
+		ids[definition.ID] = true
+	}
+	return nil
+}
+
+// Rules - builds the rules of the campaigns with their schedules, the calculator skips the rules which are not active at its clock time,
+// campaigns the customer has redeemed as many times as they allow and campaigns of other currencies are skipped
+func (c Config) Rules(ruleContext RuleContext) ([]Rule, error) {
+	var rules []Rule
+	for _, definition := range c.Campaigns {
+		if definition.MaxRedemptionsPerUser > 0 && ruleContext.CampaignRedemptions[definition.ID] >= definition.MaxRedemptionsPerUser {
+			continue
+		}
+		if !definition.AppliesIn(ruleContext.Currency) {
+			continue
+		}
+		rule, err := c.registry().NewRule(definition, ruleContext)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, PrioritizedRule{
+			Rule:             rule,
+			ID:               definition.ID,
+			Label:            definition.Label,
+			Priority:         definition.Priority,
+			ExclusivityGroup: definition.ExclusivityGroup,
+			Schedule:         definition.Schedule(),
+			MaxAmount:        definition.RemainingBudget,
+		})
+	}
+	return rules, nil
+}
+
+// WithStoredCampaigns - returns the config with the definitions of the given stored campaigns in place of its campaigns,
+// policy and rounding of the config are kept
+func (c Config) WithStoredCampaigns(stored []models.Campaign) (Config, error) {
+	c.Campaigns = make([]Definition, 0, len(stored))
+	for _, storedCampaign := range stored {
+		definition, err := DefinitionOf(storedCampaign)
+		if err != nil {
+			return Config{}, err
+		}
+		c.Campaigns = append(c.Campaigns, definition)
+	}
+	return c, nil
+}
+
+// AppliesIn - checks if the campaign is applied on baskets in the given currency, the budget of the campaign is spent only in its currency
+func (d Definition) AppliesIn(currency string) bool {
+	if d.Budget != nil && models.CurrencyOrBase(models.NormalizeCurrency(d.BudgetCurrency)) != models.CurrencyOrBase(currency) {
+		return false
+	}
+	if len(d.Currencies) == 0 {
+		return true
+	}
+	for _, definitionCurrency := range d.Currencies {
+		if models.CurrencyOrBase(models.NormalizeCurrency(definitionCurrency)) == models.CurrencyOrBase(currency) {
+			return true
+		}
+	}
+	return false
+}
+
+// registry - returns the registry of the config, the default registry if it is not set
+func (c Config) registry() *Registry {
+	if c.Registry == nil {
+		return DefaultRegistry()
+	}
+	return c.Registry
+}
+
+// MaxLookbackDays - returns the longest order history lookback window of the campaigns
+func (c Config) MaxLookbackDays() int32 {
+	maxLookbackDays := int32(defaultLookbackDays)
+	for _, definition := range c.Campaigns {
+		if definition.LookbackDays > maxLookbackDays {
+			maxLookbackDays = definition.LookbackDays
+		}
+	}
+	return maxLookbackDays
+}
+
+// NewDiscountCalculator - creates a discount calculator with the given rules, the combination policy of the config and the given clock
+func (c Config) NewDiscountCalculator(rules []Rule, clock Clock) *DiscountCalculator {
+	return NewDiscountCalculatorWithPolicy(rules, c.Policy).WithRounding(c.Rounding).WithClock(clock)
+}
+
+// DefinitionOf - decodes the definition of the given stored campaign, the campaign key is used as its id
+// and the budget of the campaign is taken from its columns
+func DefinitionOf(stored models.Campaign) (Definition, error) {
+	var definition Definition
+	if err := json.Unmarshal(stored.Definition, &definition); err != nil {
+		return Definition{}, fmt.Errorf("failed to parse definition of campaign %s: %w", stored.Key, err)
+	}
+	definition.ID = stored.Key
+	definition.Budget = stored.Budget
+	definition.BudgetCurrency = stored.BudgetCurrency
+	definition.MaxRedemptionsPerUser = stored.MaxRedemptionsPerUser
+	definition.RemainingBudget = stored.RemainingBudget()
+	return definition, nil
+}
+
+// ToCampaign - encodes the definition into a stored campaign with the given status
+func (d Definition) ToCampaign(status models.CampaignStatus) (models.Campaign, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return models.Campaign{}, err
+	}
+	storedCampaign := models.NewCampaign(d.ID, status, data)
+	storedCampaign.Budget = d.Budget
+	if d.Budget != nil {
+		storedCampaign.BudgetCurrency = models.CurrencyOrBase(models.NormalizeCurrency(d.BudgetCurrency))
+	}
+	storedCampaign.MaxRedemptionsPerUser = d.MaxRedemptionsPerUser
+	return storedCampaign, nil
+}
+
+// Validate - checks the definition fields, fields of the rule type are checked by its rule factory in the default registry
+func (d Definition) Validate() error {
+	return d.validate(DefaultRegistry())
+}
+
+// validate - checks the definition fields with the rule factory of its type in the given registry
+func (d Definition) validate(registry *Registry) error {
+	if d.ID == "" {
+		return ErrMissingCampaignID
+	}
+	if err := registry.Validate(d); err != nil {
+		return err
+	}
+	if !isValidPercentage(d.Percentage) {
+		return fmt.Errorf("%w: campaign %s", ErrInvalidPercentage, d.ID)
+	}
+	for vatRate, percentage := range d.VatRatePercentages {
+		if _, err := decimal.NewFromString(vatRate); err != nil {
+			return fmt.Errorf("%w: %q of campaign %s", ErrInvalidVatRate, vatRate, d.ID)
+		}
+		if !isValidPercentage(percentage) {
+			return fmt.Errorf("%w: campaign %s", ErrInvalidPercentage, d.ID)
+		}
+	}
+	if (d.Budget != nil && d.Budget.IsNegative()) || d.MaxRedemptionsPerUser < 0 {
+		return fmt.Errorf("%w: campaign %s", ErrInvalidBudget, d.ID)
+	}
+	if err := d.Schedule().Validate(); err != nil {
+		return fmt.Errorf("%w: campaign %s", err, d.ID)
+	}
+	return nil
+}
+
+// Schedule - returns when the campaign is active
+func (d Definition) Schedule() Schedule {
+	return Schedule{
+		ValidFrom:  d.ValidFrom,
+		ValidUntil: d.ValidUntil,
+		DaysOfWeek: d.DaysOfWeek,
+		Hours:      d.Hours,
+		Timezone:   d.Timezone,
+	}
+}
+
+// IsActiveAt - checks if the campaign is active at the given time
+func (d Definition) IsActiveAt(now time.Time) bool {
+	return d.Schedule().IsActiveAt(now)
+}
+
+// NewRule - creates the rule described by the definition with the rule factory of its type in the default registry
+func (d Definition) NewRule(ruleContext RuleContext) (Rule, error) {
+	return DefaultRegistry().NewRule(d, ruleContext)
+}
+
+// DecodeParams - decodes the params of the definition into the given value, rules of other packages keep their fields in the params
+func (d Definition) DecodeParams(value interface{}) error {
+	if len(d.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(d.Params, value); err != nil {
+		return fmt.Errorf("failed to parse params of campaign %s: %w", d.ID, err)
+	}
+	return nil
+}
+
+// isValidPercentage - checks if the given percentage is in the range of 0 and 100
+func isValidPercentage(percentage decimal.Decimal) bool {
+	return !percentage.IsNegative() && percentage.LessThanOrEqual(decimal.New(100, 0))
+}
@@ -0,0 +1,170 @@
+package campaign
+
+import (
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig_CampaignFile(t *testing.T) {
+	config, err := LoadConfig("../../config/campaigns.json")
+	if err != nil {
+		t.Fatalf("Expected campaign file to be loaded, got %v", err)
+	}
+	if len(config.Campaigns) != 3 {
+		t.Errorf("Expected 3 campaigns, got %d", len(config.Campaigns))
+	}
+	if !config.Campaigns[1].VatRatePercentages["18"].Equal(decimal.New(15, 0)) {
+		t.Errorf("Expected vat rate 18 percentage to be 15, got %s", config.Campaigns[1].VatRatePercentages["18"])
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	config := Config{Campaigns: []Definition{{ID: "unknown", Type: "unknown"}}}
+	if err := config.Validate(); !errors.Is(err, ErrUnknownRuleType) {
+		t.Errorf("Expected unknown rule type error, got %v", err)
+	}
+	config = Config{Campaigns: []Definition{{ID: "same", Type: RuleTypeSameProduct}, {ID: "same", Type: RuleTypeSameProduct}}}
+	if err := config.Validate(); !errors.Is(err, ErrDuplicateCampaignID) {
+		t.Errorf("Expected duplicate campaign id error, got %v", err)
+	}
+	config = Config{Campaigns: []Definition{{ID: "same", Type: RuleTypeSameProduct, Percentage: decimal.New(120, 0)}}}
+	if err := config.Validate(); !errors.Is(err, ErrInvalidPercentage) {
+		t.Errorf("Expected invalid percentage error, got %v", err)
+	}
+	config = Config{Campaigns: []Definition{{ID: "nth", Type: RuleTypeEveryNthOrder, VatRatePercentages: map[string]decimal.Decimal{"high": decimal.New(15, 0)}}}}
+	if err := config.Validate(); !errors.Is(err, ErrInvalidVatRate) {
+		t.Errorf("Expected invalid vat rate error, got %v", err)
+	}
+}
+
+func TestConfig_Rules_SkipsCampaignsNotActiveAtClockTime(t *testing.T) {
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+	config := Config{Policy: PolicySum, Campaigns: []Definition{
+		{ID: "same-product", Type: RuleTypeSameProduct},
+		{ID: "expired", Type: RuleTypeSameProduct, ValidUntil: &expired},
+	}}
+	rules, err := config.Rules(RuleContext{})
+	if err != nil {
+		t.Fatalf("Expected rules to be built, got %v", err)
+	}
+	cart := models.ShoppingCart{Items: []models.ShoppingCartItem{{Quantity: 4, Price: decimal.New(10, 0)}}}
+	cart.CalculateTotalPrice()
+
+	calculation := config.NewDiscountCalculator(rules, FixedClock(now)).Calculate(cart)
+	if len(calculation.Applied) != 1 || calculation.Applied[0].RuleID != "same-product" {
+		t.Errorf("Expected only the same product campaign to be applied, got %v", calculation.Applied)
+	}
+	calculation = config.NewDiscountCalculator(rules, FixedClock(expired.Add(-time.Minute))).Calculate(cart)
+	if len(calculation.Applied) != 2 {
+		t.Errorf("Expected both campaigns to be applied before expiry, got %v", calculation.Applied)
+	}
+}
+
+func TestSchedule_IsActiveAt(t *testing.T) {
+	happyHour := Schedule{DaysOfWeek: []string{"friday"}, Hours: []HourRange{{From: 17, To: 19}}, Timezone: "Europe/Istanbul"}
+	nightOwl := Schedule{Hours: []HourRange{{From: 22, To: 2}}}
+	tests := []struct {
+		name     string
+		schedule Schedule
+		now      time.Time
+		expected bool
+	}{
+		// 2022-07-01 is a friday, 14:30 UTC is 17:30 in Istanbul
+		{name: "happy hour", schedule: happyHour, now: time.Date(2022, 7, 1, 14, 30, 0, 0, time.UTC), expected: true},
+		{name: "after happy hour", schedule: happyHour, now: time.Date(2022, 7, 1, 16, 0, 0, 0, time.UTC), expected: false},
+		{name: "happy hour on another day", schedule: happyHour, now: time.Date(2022, 7, 2, 14, 30, 0, 0, time.UTC), expected: false},
+		{name: "after midnight", schedule: nightOwl, now: time.Date(2022, 7, 1, 1, 0, 0, 0, time.UTC), expected: true},
+		{name: "afternoon", schedule: nightOwl, now: time.Date(2022, 7, 1, 15, 0, 0, 0, time.UTC), expected: false},
+	}
+	for _, test := range tests {
+		if active := test.schedule.IsActiveAt(test.now); active != test.expected {
+			t.Errorf("Expected %s to be %t, got %t", test.name, test.expected, active)
+		}
+	}
+}
+
+func TestDefinition_Validate_Schedule(t *testing.T) {
+	definition := Definition{ID: "happy-hour", Type: RuleTypeSameProduct, DaysOfWeek: []string{"funday"}}
+	if err := definition.Validate(); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("Expected invalid schedule error, got %v", err)
+	}
+	definition = Definition{ID: "happy-hour", Type: RuleTypeSameProduct, Timezone: "Mars/Olympus"}
+	if err := definition.Validate(); !errors.Is(err, ErrInvalidSchedule) {
+		t.Errorf("Expected invalid schedule error, got %v", err)
+	}
+}
+
+func TestConfig_WithStoredCampaigns(t *testing.T) {
+	config := DefaultConfig()
+	storedCampaign, err := config.Campaigns[2].ToCampaign(models.CampaignStatusActive)
+	if err != nil {
+		t.Fatalf("Expected definition to be encoded, got %v", err)
+	}
+	storedConfig, err := config.WithStoredCampaigns([]models.Campaign{storedCampaign})
+	if err != nil {
+		t.Fatalf("Expected stored campaigns to be decoded, got %v", err)
+	}
+	if storedConfig.Policy != config.Policy || len(storedConfig.Campaigns) != 1 {
+		t.Fatalf("Expected config with the policy and one stored campaign, got %+v", storedConfig)
+	}
+	definition := storedConfig.Campaigns[0]
+	if definition.ID != "same-product" || definition.MinQuantity != sameProductRuleMinQuantity || !definition.Percentage.Equal(config.Campaigns[2].Percentage) {
+		t.Errorf("Expected stored definition to match the same product campaign, got %+v", definition)
+	}
+}
+
+func TestConfig_Rules_BudgetAndRedemptionLimit(t *testing.T) {
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{Quantity: 1, Price: decimal.New(300, 0)},
+			{Quantity: 1, Price: decimal.New(100, 0)},
+		},
+	}
+	cart.CalculateTotalPrice()
+	budget := decimal.New(100, 0)
+	storedCampaign, err := Definition{ID: "spring", Type: RuleTypePercentage, Percentage: decimal.New(50, 0), Budget: &budget, MaxRedemptionsPerUser: 2}.ToCampaign(models.CampaignStatusActive)
+	if err != nil {
+		t.Fatalf("Expected definition to be encoded, got %v", err)
+	}
+	storedCampaign.SpentAmount = decimal.New(70, 0)
+	config, err := Config{}.WithStoredCampaigns([]models.Campaign{storedCampaign})
+	if err != nil {
+		t.Fatalf("Expected stored campaigns to be decoded, got %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		currency    string
+		redemptions map[string]int64
+		expected    string
+	}{
+		// 200 discount is limited to the remaining budget of 30
+		{name: "remaining budget", redemptions: map[string]int64{"spring": 1}, expected: "30"},
+		{name: "redemption limit reached", redemptions: map[string]int64{"spring": 2}, expected: "0"},
+		// the budget is in the base currency, so it is not spent on baskets in another currency
+		{name: "basket in another currency", currency: "EUR", redemptions: map[string]int64{"spring": 1}, expected: "0"},
+	}
+	for _, test := range tests {
+		rules, err := config.Rules(RuleContext{Currency: test.currency, Facts: Facts{CampaignRedemptions: test.redemptions}})
+		if err != nil {
+			t.Fatalf("Expected rules of %s to be created, got %v", test.name, err)
+		}
+		calculation := config.NewDiscountCalculator(rules, nil).Calculate(cart)
+		if !calculation.Amount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected %s discount to be %s, got %s", test.name, test.expected, calculation.Amount)
+		}
+		lines := decimal.Zero
+		for _, result := range calculation.Applied {
+			for _, line := range result.Lines {
+				lines = lines.Add(line.Amount)
+			}
+		}
+		if !lines.Equal(calculation.Amount) {
+			t.Errorf("Expected %s line discounts to add up to %s, got %s", test.name, calculation.Amount, lines)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package campaign
+
+import (
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	lowVatRate                    = 8
+	highVatRate                   = 18
+	lowVatRateDiscountPercentage  = 10
+	highVatRateDiscountPercentage = 15
+	defaultOrderInterval          = 4
+	defaultLookbackDays           = 30
+)
+
+// EveryNthOrderRule - is a rule that applies discount on every nth order of a user within the lookback window
+// if the total of the last n orders of the user is more than given amount
+type EveryNthOrderRule struct {
+	MinOrderAmount decimal.Decimal
+	// OrderInterval - n, default interval is used if it is zero
+	OrderInterval int32
+	// LookbackDays - only the orders of the user in this window are counted, default window is used if it is zero
+	LookbackDays int32
+	// UserOrders - previous orders of the user
+	UserOrders []Order
+	// RecentOrderCount - number of the previous orders of the user in the lookback window, the orders are counted if it is nil
+	RecentOrderCount *int32
+	// LastOrdersAmount - total of the last n orders of the user, the order amounts are added up if it is nil
+	LastOrdersAmount *decimal.Decimal
+	// Now - time the lookback window ends at
+	Now time.Time
+	// VatRateDiscountPercentages - discount percentages per vat rate, the vat rates are numbers such as "8" or "5.5"
+	VatRateDiscountPercentages map[string]decimal.Decimal
+	// DiscountPercentage - discount percentage of every item if there are no vat rate percentages,
+	// default vat rate percentages are used if both are empty
+	DiscountPercentage decimal.Decimal
+}
+
+// NewEveryNthOrderRule - creates a new every nth order rule with the given min order amount, interval, lookback window and orders of the user
+func NewEveryNthOrderRule(minOrderAmount decimal.Decimal, orderInterval, lookbackDays int32, userOrders []Order, now time.Time) *EveryNthOrderRule {
+	return &EveryNthOrderRule{
+		MinOrderAmount: minOrderAmount,
+		OrderInterval:  orderInterval,
+		LookbackDays:   lookbackDays,
+		UserOrders:     userOrders,
+		Now:            now,
+	}
+}
+
+// CalculateDiscount - calculates the discount for the given cart if it is the nth order of the user and the total of the last n orders is more than given amount
+func (e EveryNthOrderRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	label := fmt.Sprintf("Every %d orders discount", e.interval())
+	if !e.Explain(cart).PreconditionPassed {
+		return newResult(RuleTypeEveryNthOrder, label, nil)
+	}
+	var lines []LineDiscount
+	for _, item := range cart.Items {
+		if percentage, ok := e.itemPercentage(item); ok {
+			lines = append(lines, LineDiscount{
+				ProductID: item.ProductID.String(),
+				Amount:    percentageOf(item.Price.Mul(decimal.NewFromInt32(item.Quantity)), percentage),
+			})
+		}
+	}
+	return newResult(RuleTypeEveryNthOrder, label, lines)
+}
+
+// Explain - returns the order history the rule is calculated with and if the next order of the user is the nth one
+func (e EveryNthOrderRule) Explain(cart models.ShoppingCart) Explanation {
+	recentOrders := e.recentOrders()
+	previousOrders := int32(len(recentOrders))
+	if e.RecentOrderCount != nil {
+		previousOrders = *e.RecentOrderCount
+	}
+	lastOrdersAmount := e.lastOrdersAmount(recentOrders, cart.Currency)
+	orderNumber := previousOrders + 1
+	explanation := Explanation{
+		Inputs: map[string]string{
+			"min_order_amount":   e.MinOrderAmount.String(),
+			"last_orders_amount": lastOrdersAmount.String(),
+			"order_interval":     strconv.Itoa(int(e.interval())),
+			"lookback_days":      strconv.Itoa(int(e.lookbackDays())),
+			"previous_orders":    strconv.Itoa(int(previousOrders)),
+		},
+		PreconditionPassed: true,
+	}
+	switch {
+	case orderNumber%e.interval() != 0:
+		explanation.PreconditionPassed = false
+		explanation.Reason = fmt.Sprintf("order %d of the user in the last %d days is not a multiple of %d", orderNumber, e.lookbackDays(), e.interval())
+	case e.MinOrderAmount.GreaterThanOrEqual(lastOrdersAmount):
+		explanation.PreconditionPassed = false
+		explanation.Reason = fmt.Sprintf("total of the last %d orders %s is not more than %s", e.interval(), lastOrdersAmount, e.MinOrderAmount)
+	}
+	return explanation
+}
+
+// interval - returns n, the default interval if it is not set
+func (e EveryNthOrderRule) interval() int32 {
+	if e.OrderInterval == 0 {
+		return defaultOrderInterval
+	}
+	return e.OrderInterval
+}
+
+// lookbackDays - returns the lookback window, the default window if it is not set
+func (e EveryNthOrderRule) lookbackDays() int32 {
+	if e.LookbackDays == 0 {
+		return defaultLookbackDays
+	}
+	return e.LookbackDays
+}
+
+// recentOrders - returns the previous orders of the user in the lookback window, the latest order by sequence comes first
+func (e EveryNthOrderRule) recentOrders() []Order {
+	windowStart := e.Now.AddDate(0, 0, -int(e.lookbackDays()))
+	var recentOrders []Order
+	for _, order := range e.UserOrders {
+		if order.CreatedAt.After(windowStart) && !order.CreatedAt.After(e.Now) {
+			recentOrders = append(recentOrders, order)
+		}
+	}
+	sort.SliceStable(recentOrders, func(i, j int) bool {
+		return recentOrders[i].Sequence > recentOrders[j].Sequence
+	})
+	return recentOrders
+}
+
+// lastOrdersAmount - returns the total of the last n orders of the given recent orders in the given currency, the overridden total if it is set
+func (e EveryNthOrderRule) lastOrdersAmount(recentOrders []Order, currency string) decimal.Decimal {
+	if e.LastOrdersAmount != nil {
+		return *e.LastOrdersAmount
+	}
+	total := decimal.Zero
+	for i, order := range recentOrders {
+		if i == int(e.interval()) {
+			break
+		}
+		if models.CurrencyOrBase(order.Currency) == models.CurrencyOrBase(currency) {
+			total = total.Add(order.Amount)
+		}
+	}
+	return total
+}
+
+// itemPercentage - returns the discount percentage of the item by its vat rate, or the discount percentage of every item
+func (e EveryNthOrderRule) itemPercentage(item models.ShoppingCartItem) (decimal.Decimal, bool) {
+	if len(e.VatRateDiscountPercentages) > 0 {
+		// the vat rates are compared as numbers, so "8" and "8.0" are the same bucket
+		for vatRate, percentage := range e.VatRateDiscountPercentages {
+			if rate, err := decimal.NewFromString(vatRate); err == nil && rate.Equal(item.VatRate) {
+				return percentage, true
+			}
+		}
+		return decimal.Zero, false
+	}
+	if e.DiscountPercentage.IsPositive() {
+		return e.DiscountPercentage, true
+	}
+	switch {
+	case item.VatRate.Equal(decimal.New(lowVatRate, 0)):
+		return decimal.New(lowVatRateDiscountPercentage, 0), true
+	case item.VatRate.Equal(decimal.New(highVatRate, 0)):
+		return decimal.New(highVatRateDiscountPercentage, 0), true
+	}
+	return decimal.Zero, false
+}
@@ -0,0 +1,54 @@
+package campaign
+
+import (
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+const percentageRuleLabel = "Percentage discount"
+
+// PercentageRule - is a rule applies the discount percentage on every item if the cart total is more than the min amount
+type PercentageRule struct {
+	// MinCartAmount - cart total must be more than it, every cart gets the discount if it is zero
+	MinCartAmount      decimal.Decimal
+	DiscountPercentage decimal.Decimal
+}
+
+// NewPercentageRule - creates a new percentage rule with the given min cart amount and discount percentage
+func NewPercentageRule(minCartAmount, discountPercentage decimal.Decimal) *PercentageRule {
+	return &PercentageRule{
+		MinCartAmount:      minCartAmount,
+		DiscountPercentage: discountPercentage,
+	}
+}
+
+// CalculateDiscount - calculates the discount percentage of every item if the cart total is more than the min amount
+func (p PercentageRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	if p.MinCartAmount.IsPositive() && p.MinCartAmount.GreaterThanOrEqual(cart.TotalPrice) {
+		return newResult(RuleTypePercentage, percentageRuleLabel, nil)
+	}
+	var lines []LineDiscount
+	for _, item := range cart.Items {
+		lines = append(lines, LineDiscount{
+			ProductID: item.ProductID.String(),
+			Amount:    percentageOf(item.Price.Mul(decimal.NewFromInt32(item.Quantity)), p.DiscountPercentage),
+		})
+	}
+	return newResult(RuleTypePercentage, percentageRuleLabel, lines)
+}
+
+// Explain - returns the min cart amount the rule is calculated with and if the cart total is more than it
+func (p PercentageRule) Explain(cart models.ShoppingCart) Explanation {
+	explanation := Explanation{
+		Inputs: map[string]string{
+			"min_cart_amount": p.MinCartAmount.String(),
+			"cart_amount":     cart.TotalPrice.String(),
+		},
+		PreconditionPassed: !p.MinCartAmount.IsPositive() || cart.TotalPrice.GreaterThan(p.MinCartAmount),
+	}
+	if !explanation.PreconditionPassed {
+		explanation.Reason = fmt.Sprintf("cart amount %s is not more than %s", cart.TotalPrice, p.MinCartAmount)
+	}
+	return explanation
+}
@@ -0,0 +1,109 @@
+package campaign
+
+import (
+	"github.com/erdemcemal/basket-service/internal/models"
+	"strconv"
+)
+
+// ProductFilter - selects cart items by product id, category, tag or brand
+type ProductFilter struct {
+	ProductIDs []string `json:"product_ids,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	Brands     []string `json:"brands,omitempty"`
+}
+
+// IsEmpty - checks if the filter has no criteria
+func (f ProductFilter) IsEmpty() bool {
+	return len(f.ProductIDs) == 0 && len(f.Categories) == 0 && len(f.Tags) == 0 && len(f.Brands) == 0
+}
+
+// matchesAll - checks if the item matches every criteria of the filter, an empty criteria matches every item
+func (f ProductFilter) matchesAll(item models.ShoppingCartItem) bool {
+	return (len(f.ProductIDs) == 0 || containsAny(f.ProductIDs, item.ProductID.String())) &&
+		(len(f.Categories) == 0 || containsAny(f.Categories, item.Categories...)) &&
+		(len(f.Tags) == 0 || containsAny(f.Tags, item.Tags...)) &&
+		(len(f.Brands) == 0 || containsAny(f.Brands, item.Brand))
+}
+
+// matchesAny - checks if the item matches at least one criteria of the filter
+func (f ProductFilter) matchesAny(item models.ShoppingCartItem) bool {
+	return containsAny(f.ProductIDs, item.ProductID.String()) ||
+		containsAny(f.Categories, item.Categories...) ||
+		containsAny(f.Tags, item.Tags...) ||
+		containsAny(f.Brands, item.Brand)
+}
+
+// containsAny - checks if the list contains at least one of the given values
+func containsAny(list []string, values ...string) bool {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		for _, v := range list {
+			if v == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FilteredRule - is a rule calculated only on the cart items selected by the include and exclude filters
+type FilteredRule struct {
+	Rule
+	// Include - item must match every criteria of it, every item is included if it is empty
+	Include ProductFilter
+	// Exclude - item matching any criteria of it is excluded
+	Exclude ProductFilter
+}
+
+// NewFilteredRule - creates a new filtered rule which calculates the given rule on the eligible items of the cart
+func NewFilteredRule(rule Rule, include, exclude ProductFilter) *FilteredRule {
+	return &FilteredRule{Rule: rule, Include: include, Exclude: exclude}
+}
+
+// IsEligible - checks if the item is selected by the filters of the rule
+func (r FilteredRule) IsEligible(item models.ShoppingCartItem) bool {
+	return r.Include.matchesAll(item) && !r.Exclude.matchesAny(item)
+}
+
+// CalculateDiscount - calculates the discount of the wrapped rule on a cart with the eligible items only,
+// so the amount thresholds of the rule are compared with the total of the eligible items
+func (r FilteredRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	return r.Rule.CalculateDiscount(r.eligibleCart(cart))
+}
+
+// eligibleCart - returns a copy of the cart with the eligible items only
+func (r FilteredRule) eligibleCart(cart models.ShoppingCart) models.ShoppingCart {
+	eligibleCart := cart
+	eligibleCart.Items = nil
+	for _, item := range cart.Items {
+		if r.IsEligible(item) {
+			eligibleCart.Items = append(eligibleCart.Items, item)
+		}
+	}
+	eligibleCart.CalculateTotalPrice()
+	return eligibleCart
+}
+
+// Explain - returns the explanation of the wrapped rule on the eligible items with the number of eligible items
+func (r FilteredRule) Explain(cart models.ShoppingCart) Explanation {
+	eligibleCart := r.eligibleCart(cart)
+	explanation := explain(r.Rule, eligibleCart)
+	explanation.Inputs = withInput(explanation.Inputs, "eligible_items", strconv.Itoa(len(eligibleCart.Items)))
+	if len(eligibleCart.Items) == 0 {
+		explanation.PreconditionPassed = false
+		explanation.Reason = "no item of the cart is selected by the product filters"
+	}
+	return explanation
+}
+
+// withInput - returns the inputs with the given input added
+func withInput(inputs map[string]string, key, value string) map[string]string {
+	if inputs == nil {
+		inputs = make(map[string]string)
+	}
+	inputs[key] = value
+	return inputs
+}
@@ -0,0 +1,48 @@
+package campaign
+
+import (
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestFilteredRule_CalculateDiscount(t *testing.T) {
+	phone := uuid.Must(uuid.NewV4())
+	keyHolder := uuid.Must(uuid.NewV4())
+	phoneCase := uuid.Must(uuid.NewV4())
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{ProductID: phone, Quantity: 1, Price: decimal.New(500, 0), Brand: "Apple", Categories: models.StringList{"phones"}},
+			{ProductID: keyHolder, Quantity: 2, Price: decimal.New(30, 0), Categories: models.StringList{"accessories"}},
+			{ProductID: phoneCase, Quantity: 1, Price: decimal.New(40, 0), Brand: "Apple", Categories: models.StringList{"accessories"}, Tags: models.StringList{"clearance"}},
+		},
+	}
+	cart.CalculateTotalPrice()
+	tenPercent := Definition{ID: "accessories", Type: RuleTypePurchaseAmount, Percentage: decimal.New(10, 0)}
+	tests := []struct {
+		name     string
+		include  *ProductFilter
+		exclude  *ProductFilter
+		expected string
+	}{
+		{name: "no filter", expected: "60"},
+		{name: "accessories", include: &ProductFilter{Categories: []string{"accessories"}}, expected: "10"},
+		{name: "apple accessories", include: &ProductFilter{Categories: []string{"accessories"}, Brands: []string{"Apple"}}, expected: "4"},
+		{name: "accessories except clearance", include: &ProductFilter{Categories: []string{"accessories"}}, exclude: &ProductFilter{Tags: []string{"clearance"}}, expected: "6"},
+		{name: "everything except the phone", exclude: &ProductFilter{ProductIDs: []string{phone.String()}}, expected: "10"},
+	}
+	for _, test := range tests {
+		definition := tenPercent
+		definition.Include = test.include
+		definition.Exclude = test.exclude
+		rule, err := definition.NewRule(RuleContext{Facts: Facts{UserMonthlyAmount: decimal.New(1, 0)}})
+		if err != nil {
+			t.Fatalf("Expected rule of %s to be created, got %v", test.name, err)
+		}
+		discount := NewDiscountCalculator([]Rule{rule}).CalculateDiscount(cart)
+		if !discount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected %s discount to be %s, got %s", test.name, test.expected, discount)
+		}
+	}
+}
@@ -1,15 +1,21 @@
 package campaign
 
-import "github.com/erdemcemal/basket-service/internal/models"
+import (
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+)
 
 // PurchaseAmountRule - is a rule applies discount if the given amount is more than customer purchase amount in a month
 type PurchaseAmountRule struct {
-	MinPurchaseAmountInMonth      float64
-	CustomerPurchaseAmountInMonth float64
+	MinPurchaseAmountInMonth      decimal.Decimal
+	CustomerPurchaseAmountInMonth decimal.Decimal
+	// DiscountPercentage - discount percentage applied on the cart, default percentage is used if it is zero
+	DiscountPercentage decimal.Decimal
 }
 
 // NewPurchaseAmountRule - creates a new purchase amount rule with the given min purchase amount and customer purchase amount
-func NewPurchaseAmountRule(minPurchaseAmountInMonth, customerPurchaseAmountInMonth float64) *PurchaseAmountRule {
+func NewPurchaseAmountRule(minPurchaseAmountInMonth, customerPurchaseAmountInMonth decimal.Decimal) *PurchaseAmountRule {
 	return &PurchaseAmountRule{
 		MinPurchaseAmountInMonth:      minPurchaseAmountInMonth,
 		CustomerPurchaseAmountInMonth: customerPurchaseAmountInMonth,
@@ -17,12 +23,36 @@ func NewPurchaseAmountRule(minPurchaseAmountInMonth, customerPurchaseAmountInMon
 }
 
 // CalculateDiscount - calculates the discount if the given amount is more than customer purchase amount in a month
-func (p PurchaseAmountRule) CalculateDiscount(cart models.ShoppingCart) float64 {
-	if p.MinPurchaseAmountInMonth >= p.CustomerPurchaseAmountInMonth {
-		return 0
+func (p PurchaseAmountRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	if p.MinPurchaseAmountInMonth.GreaterThanOrEqual(p.CustomerPurchaseAmountInMonth) {
+		return newResult(RuleTypePurchaseAmount, purchaseAmountRuleLabel, nil)
 	}
-	// apply purchase amount rule for total amount of the cart.
-	cartAmount := cart.TotalPrice
-	discountAmount := cartAmount.InexactFloat64() * purchaseAmountRuleDiscountPercentage / 100
-	return discountAmount
+	percentage := p.DiscountPercentage
+	if percentage.IsZero() {
+		percentage = decimal.New(purchaseAmountRuleDiscountPercentage, 0)
+	}
+	// apply purchase amount rule for total amount of the cart, allocated to every item by its amount.
+	var lines []LineDiscount
+	for _, item := range cart.Items {
+		lines = append(lines, LineDiscount{
+			ProductID: item.ProductID.String(),
+			Amount:    percentageOf(item.Price.Mul(decimal.NewFromInt32(item.Quantity)), percentage),
+		})
+	}
+	return newResult(RuleTypePurchaseAmount, purchaseAmountRuleLabel, lines)
+}
+
+// Explain - returns the monthly purchase amounts the rule is calculated with and if the customer amount is more than the min amount
+func (p PurchaseAmountRule) Explain(cart models.ShoppingCart) Explanation {
+	explanation := Explanation{
+		Inputs: map[string]string{
+			"min_purchase_amount_in_month":      p.MinPurchaseAmountInMonth.String(),
+			"customer_purchase_amount_in_month": p.CustomerPurchaseAmountInMonth.String(),
+		},
+		PreconditionPassed: p.CustomerPurchaseAmountInMonth.GreaterThan(p.MinPurchaseAmountInMonth),
+	}
+	if !explanation.PreconditionPassed {
+		explanation.Reason = fmt.Sprintf("customer purchase amount in month %s is not more than %s", p.CustomerPurchaseAmountInMonth, p.MinPurchaseAmountInMonth)
+	}
+	return explanation
 }
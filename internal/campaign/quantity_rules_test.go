@@ -0,0 +1,83 @@
+package campaign
+
+import (
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestBuyXGetYRule_CalculateDiscount(t *testing.T) {
+	phone := uuid.Must(uuid.NewV4())
+	keyHolder := uuid.Must(uuid.NewV4())
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{ProductID: phone, Quantity: 2, Price: decimal.New(549, 0)},
+			{ProductID: keyHolder, Quantity: 7, Price: decimal.New(30, 0)},
+		},
+	}
+	tests := []struct {
+		name     string
+		rule     Rule
+		expected string
+	}{
+		// 7 key holders are two groups of 2 + 1 and one bought unit: 2 free units
+		{name: "same product", rule: NewBuyXGetYRule([]string{keyHolder.String()}, nil, 2, 1, decimal.Zero), expected: "60"},
+		// 2 phones give 2 key holders
+		{name: "other product", rule: NewBuyXGetYRule([]string{phone.String()}, []string{keyHolder.String()}, 1, 1, decimal.Zero), expected: "60"},
+		{name: "half price", rule: NewBuyXGetYRule([]string{phone.String()}, []string{keyHolder.String()}, 1, 1, decimal.New(50, 0)), expected: "30"},
+	}
+	for _, test := range tests {
+		discount := NewDiscountCalculator([]Rule{test.rule}).CalculateDiscount(cart)
+		if !discount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected %s discount to be %s, got %s", test.name, test.expected, discount)
+		}
+	}
+}
+
+func TestBundleRule_CalculateDiscount(t *testing.T) {
+	phone := uuid.Must(uuid.NewV4())
+	keyHolder := uuid.Must(uuid.NewV4())
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{ProductID: phone, Quantity: 2, Price: decimal.New(549, 0)},
+			{ProductID: keyHolder, Quantity: 1, Price: decimal.New(30, 0)},
+		},
+	}
+	rule := NewBundleRule(map[string]int32{phone.String(): 1, keyHolder.String(): 1}, decimal.New(560, 0))
+	calculation := NewDiscountCalculator([]Rule{rule}).Calculate(cart)
+
+	// only one bundle is complete: 549 + 30 - 560 = 19
+	if !calculation.Amount.Equal(decimal.New(19, 0)) {
+		t.Errorf("Expected bundle discount to be 19, got %s", calculation.Amount)
+	}
+	if len(calculation.Applied) != 1 || len(calculation.Applied[0].Lines) != 2 {
+		t.Fatalf("Expected bundle discount to be allocated to both products, got %v", calculation.Applied)
+	}
+}
+
+func TestTieredQuantityRule_CalculateDiscount(t *testing.T) {
+	keyHolder := uuid.Must(uuid.NewV4())
+	unitPrice := decimal.New(25, 0)
+	rule := NewTieredQuantityRule([]string{keyHolder.String()}, []Tier{
+		{MinQuantity: 5, Percentage: decimal.New(10, 0)},
+		{MinQuantity: 10, UnitPrice: &unitPrice},
+	})
+	tests := []struct {
+		quantity int32
+		expected string
+	}{
+		{quantity: 4, expected: "0"},
+		{quantity: 5, expected: "15"},
+		{quantity: 10, expected: "50"},
+	}
+	for _, test := range tests {
+		cart := models.ShoppingCart{
+			Items: []models.ShoppingCartItem{{ProductID: keyHolder, Quantity: test.quantity, Price: decimal.New(30, 0)}},
+		}
+		discount := NewDiscountCalculator([]Rule{rule}).CalculateDiscount(cart)
+		if !discount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected discount for %d units to be %s, got %s", test.quantity, test.expected, discount)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package campaign
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMissingRuleType           = errors.New("rule type is required")
+	ErrRuleTypeAlreadyRegistered = errors.New("rule type is already registered")
+	ErrLookupNotAvailable        = errors.New("store lookups are not available in the rule context")
+)
+
+// defaultRegistry - contains the built-in rule types and the rule types registered by other packages
+var defaultRegistry = newBuiltinRegistry()
+
+// Lookup - exposes the store lookups the rule factories may need to build the rule of a customer
+type Lookup interface {
+	GetUserMonthlyOrderAmount(ctx context.Context, userId string, currency string) (decimal.Decimal, error)
+	GetUserOrderCount(ctx context.Context, userId string, since time.Time) (int64, error)
+	GetProductById(ctx context.Context, id string) (models.Product, error)
+}
+
+// RuleContext - represents what the rule factories build the rule of a customer with,
+// the customer facts are calculated once and the lookups are available for anything else
+type RuleContext struct {
+	Context context.Context
+	UserID  string
+	// Currency - currency of the basket the rules are calculated on, the base currency if it is empty
+	Currency string
+	Facts
+	// Lookup - store lookups, simulations without a user have none
+	Lookup Lookup
+}
+
+// RuleFactory - builds the rules of a rule type from campaign definitions
+type RuleFactory interface {
+	// Validate - checks the definition fields of the rule type
+	Validate(definition Definition) error
+	// NewRule - creates the rule described by the definition for the customer of the rule context
+	NewRule(definition Definition, ruleContext RuleContext) (Rule, error)
+}
+
+// RuleFactoryFunc - is a rule factory of a rule type without definition fields to validate
+type RuleFactoryFunc func(definition Definition, ruleContext RuleContext) (Rule, error)
+
+// Registry - contains the rule factories by rule type
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]RuleFactory
+}
+
+// NewRegistry - creates a new registry without any rule type
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]RuleFactory)}
+}
+
+// DefaultRegistry - returns the registry the campaigns are built with unless their config has its own registry
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register - registers the rule factory of the given rule type to the default registry
+func Register(ruleType string, factory RuleFactory) error {
+	return defaultRegistry.Register(ruleType, factory)
+}
+
+// MustRegister - registers the rule factory of the given rule type to the default registry and panics if it can't,
+// it is meant to be called from the init function of the package of the rule
+func MustRegister(ruleType string, factory RuleFactory) {
+	if err := Register(ruleType, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Validate - does not check anything, the rule type has no definition fields
+func (f RuleFactoryFunc) Validate(Definition) error {
+	return nil
+}
+
+// NewRule - creates the rule with the function
+func (f RuleFactoryFunc) NewRule(definition Definition, ruleContext RuleContext) (Rule, error) {
+	return f(definition, ruleContext)
+}
+
+// Register - registers the rule factory of the given rule type, a rule type can be registered once
+func (r *Registry) Register(ruleType string, factory RuleFactory) error {
+	if ruleType == "" || factory == nil {
+		return ErrMissingRuleType
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.factories[ruleType]; ok {
+		return fmt.Errorf("%w: %q", ErrRuleTypeAlreadyRegistered, ruleType)
+	}
+	r.factories[ruleType] = factory
+	return nil
+}
+
+// Factory - returns the rule factory of the given rule type
+func (r *Registry) Factory(ruleType string) (RuleFactory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	factory, ok := r.factories[ruleType]
+	return factory, ok
+}
+
+// Types - returns the registered rule types in alphabetical order
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ruleTypes := make([]string, 0, len(r.factories))
+	for ruleType := range r.factories {
+		ruleTypes = append(ruleTypes, ruleType)
+	}
+	sort.Strings(ruleTypes)
+	return ruleTypes
+}
+
+// Validate - checks the definition fields of the definition type with its rule factory
+func (r *Registry) Validate(definition Definition) error {
+	factory, ok := r.Factory(definition.Type)
+	if !ok {
+		return fmt.Errorf("%w: %q in campaign %s", ErrUnknownRuleType, definition.Type, definition.ID)
+	}
+	return factory.Validate(definition)
+}
+
+// NewRule - creates the rule described by the definition with the factory of its type for the customer of the rule context,
+// the rule is calculated only on the items selected by the product filters and only for the target segments of the definition
+func (r *Registry) NewRule(definition Definition, ruleContext RuleContext) (Rule, error) {
+	factory, ok := r.Factory(definition.Type)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q in campaign %s", ErrUnknownRuleType, definition.Type, definition.ID)
+	}
+	rule, err := factory.NewRule(definition, ruleContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the rule of campaign %s: %w", definition.ID, err)
+	}
+	var include, exclude ProductFilter
+	if definition.Include != nil {
+		include = *definition.Include
+	}
+	if definition.Exclude != nil {
+		exclude = *definition.Exclude
+	}
+	if !include.IsEmpty() || !exclude.IsEmpty() {
+		rule = NewFilteredRule(rule, include, exclude)
+	}
+	if len(definition.Segments) > 0 {
+		rule = NewSegmentRule(rule, definition.Segments, ruleContext.Segments)
+	}
+	return rule, nil
+}
+
+// context - returns the context of the lookups, background context if it is not set
+func (r RuleContext) context() context.Context {
+	if r.Context == nil {
+		return context.Background()
+	}
+	return r.Context
+}
+
+// UserOrderCount - returns the number of orders of the user since the given time
+func (r RuleContext) UserOrderCount(since time.Time) (int64, error) {
+	if r.Lookup == nil || r.UserID == "" {
+		return 0, ErrLookupNotAvailable
+	}
+	return r.Lookup.GetUserOrderCount(r.context(), r.UserID, since)
+}
+
+// Product - returns the product with the given id, such as to read its metadata
+func (r RuleContext) Product(productId string) (models.Product, error) {
+	if r.Lookup == nil {
+		return models.Product{}, ErrLookupNotAvailable
+	}
+	return r.Lookup.GetProductById(r.context(), productId)
+}
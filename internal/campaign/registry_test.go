@@ -0,0 +1,98 @@
+package campaign
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+type fakeLookup struct {
+	orderCount int64
+}
+
+func (f fakeLookup) GetUserMonthlyOrderAmount(context.Context, string, string) (decimal.Decimal, error) {
+	return decimal.Zero, nil
+}
+
+func (f fakeLookup) GetUserOrderCount(context.Context, string, time.Time) (int64, error) {
+	return f.orderCount, nil
+}
+
+func (f fakeLookup) GetProductById(context.Context, string) (models.Product, error) {
+	return models.Product{}, nil
+}
+
+// loyaltyParams - params of the loyalty rule type registered by the test
+type loyaltyParams struct {
+	MinOrders int64           `json:"min_orders"`
+	Amount    decimal.Decimal `json:"amount"`
+}
+
+func TestRegistry_NewRule_CustomRuleType(t *testing.T) {
+	registry := NewRegistry()
+	loyalty := RuleFactoryFunc(func(definition Definition, ruleContext RuleContext) (Rule, error) {
+		var params loyaltyParams
+		if err := definition.DecodeParams(&params); err != nil {
+			return nil, err
+		}
+		orderCount, err := ruleContext.UserOrderCount(time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		if orderCount < params.MinOrders {
+			return NewPercentageRule(decimal.Zero, decimal.Zero), nil
+		}
+		return NewPercentageRule(decimal.Zero, params.Amount), nil
+	})
+	if err := registry.Register("loyalty", loyalty); err != nil {
+		t.Fatalf("Expected loyalty rule type to be registered, got %v", err)
+	}
+	if err := registry.Register("loyalty", loyalty); !errors.Is(err, ErrRuleTypeAlreadyRegistered) {
+		t.Errorf("Expected already registered error, got %v", err)
+	}
+
+	config := Config{Registry: registry, Campaigns: []Definition{
+		{ID: "loyal-customers", Type: "loyalty", Params: json.RawMessage(`{"min_orders": 3, "amount": "10"}`)},
+	}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Expected config with the loyalty rule type to be valid, got %v", err)
+	}
+	cart := models.ShoppingCart{Items: []models.ShoppingCartItem{{Quantity: 1, Price: decimal.New(200, 0)}}}
+	cart.CalculateTotalPrice()
+	tests := []struct {
+		orderCount int64
+		expected   string
+	}{
+		{orderCount: 2, expected: "0"},
+		{orderCount: 3, expected: "20"},
+	}
+	for _, test := range tests {
+		rules, err := config.Rules(RuleContext{UserID: "user", Lookup: fakeLookup{orderCount: test.orderCount}})
+		if err != nil {
+			t.Fatalf("Expected rules to be built, got %v", err)
+		}
+		discount := config.NewDiscountCalculator(rules, SystemClock{}).CalculateDiscount(cart)
+		if !discount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected discount after %d orders to be %s, got %s", test.orderCount, test.expected, discount)
+		}
+	}
+
+	if _, err := config.Rules(RuleContext{}); !errors.Is(err, ErrLookupNotAvailable) {
+		t.Errorf("Expected lookup not available error, got %v", err)
+	}
+	config.Registry = nil
+	if err := config.Validate(); !errors.Is(err, ErrUnknownRuleType) {
+		t.Errorf("Expected loyalty rule type to be unknown to the default registry, got %v", err)
+	}
+}
+
+func TestDefaultRegistry_Types(t *testing.T) {
+	ruleTypes := DefaultRegistry().Types()
+	if len(ruleTypes) != 9 {
+		t.Errorf("Expected 9 built-in rule types, got %v", ruleTypes)
+	}
+}
@@ -0,0 +1,89 @@
+package campaign
+
+import "github.com/shopspring/decimal"
+
+// Result - represents the discount calculated by a rule with its allocation to the cart items
+type Result struct {
+	RuleID string
+	Label  string
+	Amount decimal.Decimal
+	Lines  []LineDiscount
+}
+
+// LineDiscount - represents the part of a discount allocated to a cart item
+type LineDiscount struct {
+	ProductID string
+	Amount    decimal.Decimal
+}
+
+// Calculation - represents the total discount of a cart and the rule results which make it up
+type Calculation struct {
+	Amount  decimal.Decimal
+	Applied []Result
+	// Evaluated - results of every active rule before they are combined with the policy
+	Evaluated []Result
+}
+
+// newResult - creates a new result from the given line discounts, lines without discount are skipped
+func newResult(ruleID, label string, lines []LineDiscount) Result {
+	result := Result{RuleID: ruleID, Label: label, Amount: decimal.Zero}
+	for _, line := range lines {
+		if !line.Amount.IsPositive() {
+			continue
+		}
+		result.Lines = append(result.Lines, line)
+		result.Amount = result.Amount.Add(line.Amount)
+	}
+	return result
+}
+
+// scale - returns a copy of the result with the amount and every line multiplied by the given factor
+func (r Result) scale(factor decimal.Decimal) Result {
+	scaled := Result{RuleID: r.RuleID, Label: r.Label, Amount: r.Amount.Mul(factor)}
+	for _, line := range r.Lines {
+		scaled.Lines = append(scaled.Lines, LineDiscount{ProductID: line.ProductID, Amount: line.Amount.Mul(factor)})
+	}
+	return scaled
+}
+
+// round - returns a copy of the result with every line rounded, the amount is the sum of the rounded lines
+func (r Result) round(rounding Rounding) Result {
+	if len(r.Lines) == 0 {
+		return Result{RuleID: r.RuleID, Label: r.Label, Amount: rounding.Round(r.Amount)}
+	}
+	var lines []LineDiscount
+	for _, line := range r.Lines {
+		lines = append(lines, LineDiscount{ProductID: line.ProductID, Amount: rounding.Round(line.Amount)})
+	}
+	return newResult(r.RuleID, r.Label, lines)
+}
+
+// capAt - returns a copy of the result scaled down to the given amount and rounded,
+// rounding may exceed the amount by the smallest unit, so it is taken from the last line
+func (r Result) capAt(amount decimal.Decimal, rounding Rounding) Result {
+	if r.Amount.LessThanOrEqual(amount) {
+		return r
+	}
+	if !amount.IsPositive() {
+		return Result{RuleID: r.RuleID, Label: r.Label, Amount: decimal.Zero}
+	}
+	capped := r.scale(amount.Div(r.Amount)).round(rounding)
+	if excess := capped.Amount.Sub(amount); excess.IsPositive() {
+		capped.Amount = amount
+		if len(capped.Lines) > 0 {
+			capped.Lines[len(capped.Lines)-1].Amount = capped.Lines[len(capped.Lines)-1].Amount.Sub(excess)
+		}
+	}
+	return capped
+}
+
+// add - adds the given result to the calculation
+func (c *Calculation) add(result Result) {
+	c.Applied = append(c.Applied, result)
+	c.Amount = c.Amount.Add(result.Amount)
+}
+
+// percentageOf - returns the given percentage of the amount
+func percentageOf(amount, percentage decimal.Decimal) decimal.Decimal {
+	return amount.Mul(percentage).Div(decimal.New(100, 0))
+}
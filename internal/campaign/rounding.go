@@ -0,0 +1,65 @@
+package campaign
+
+import (
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode - represents how discount amounts are rounded to the currency precision
+type RoundingMode string
+
+const (
+	// RoundingHalfUp - rounds half away from zero, 0.125 becomes 0.13
+	RoundingHalfUp RoundingMode = "half_up"
+	// RoundingHalfEven - rounds half to the nearest even digit (banker's rounding), 0.125 becomes 0.12
+	RoundingHalfEven RoundingMode = "half_even"
+
+	defaultCurrencyPrecision = 2
+)
+
+// Rounding - represents the rounding mode and the number of decimal places of discount amounts
+type Rounding struct {
+	Mode RoundingMode `json:"mode,omitempty"`
+	// Precision - decimal places of the discount amounts, the precision of the basket currency is used if it is not set
+	Precision *int32 `json:"precision,omitempty"`
+}
+
+// DefaultRounding - returns half up rounding with the precision of the basket currency
+func DefaultRounding() Rounding {
+	return Rounding{Mode: RoundingHalfUp}
+}
+
+// ForCurrency - returns the rounding with the precision of the given currency if no precision is set
+func (r Rounding) ForCurrency(currency string) Rounding {
+	if r.Precision == nil {
+		precision := models.CurrencyPrecision(currency)
+		r.Precision = &precision
+	}
+	return r
+}
+
+// Validate - checks the rounding mode and precision
+func (r Rounding) Validate() error {
+	switch r.Mode {
+	case "", RoundingHalfUp, RoundingHalfEven:
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownRoundingMode, r.Mode)
+	}
+	if r.Precision != nil && (*r.Precision < 0 || *r.Precision > 8) {
+		return fmt.Errorf("%w: %d", ErrInvalidPrecision, *r.Precision)
+	}
+	return nil
+}
+
+// Round - rounds the given amount with the rounding mode and precision, half up with two decimal places is used for missing values
+func (r Rounding) Round(amount decimal.Decimal) decimal.Decimal {
+	precision := int32(defaultCurrencyPrecision)
+	if r.Precision != nil {
+		precision = *r.Precision
+	}
+	if r.Mode == RoundingHalfEven {
+		return amount.RoundBank(precision)
+	}
+	return amount.Round(precision)
+}
@@ -0,0 +1,146 @@
+package campaign
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+)
+
+// ruleFactory - is the rule factory of a built-in rule type
+type ruleFactory struct {
+	validate func(definition Definition) error
+	newRule  func(definition Definition, ruleContext RuleContext) Rule
+}
+
+// Validate - checks the definition fields with the validate function of the rule type if it has one
+func (f ruleFactory) Validate(definition Definition) error {
+	if f.validate == nil {
+		return nil
+	}
+	return f.validate(definition)
+}
+
+// NewRule - creates the rule of the built-in rule type
+func (f ruleFactory) NewRule(definition Definition, ruleContext RuleContext) (Rule, error) {
+	return f.newRule(definition, ruleContext), nil
+}
+
+// newBuiltinRegistry - creates a new registry with the built-in rule types
+func newBuiltinRegistry() *Registry {
+	registry := NewRegistry()
+	everyNthOrder := ruleFactory{validate: validateEveryNthOrder, newRule: newEveryNthOrderRule}
+	builtins := map[string]RuleFactory{
+		RuleTypePurchaseAmount:   ruleFactory{newRule: newPurchaseAmountRule},
+		RuleTypeEveryNthOrder:    everyNthOrder,
+		RuleTypeEveryFourthOrder: everyNthOrder,
+		RuleTypeSameProduct: ruleFactory{newRule: func(d Definition, _ RuleContext) Rule {
+			return NewSameProductRule(d.MinQuantity, d.Percentage)
+		}},
+		RuleTypeBuyXGetY: ruleFactory{validate: validateBuyXGetY, newRule: func(d Definition, _ RuleContext) Rule {
+			return NewBuyXGetYRule(d.ProductIDs, d.GetProductIDs, d.BuyQuantity, d.GetQuantity, d.Percentage)
+		}},
+		RuleTypeBundle: ruleFactory{validate: validateBundle, newRule: func(d Definition, _ RuleContext) Rule {
+			return NewBundleRule(d.BundleItems, d.BundlePrice)
+		}},
+		RuleTypeTieredQuantity: ruleFactory{validate: validateTieredQuantity, newRule: func(d Definition, _ RuleContext) Rule {
+			return NewTieredQuantityRule(d.ProductIDs, d.Tiers)
+		}},
+		RuleTypePercentage: ruleFactory{validate: validatePercentage, newRule: newPercentageRule},
+		RuleTypeShipping: ruleFactory{validate: validatePercentage, newRule: func(d Definition, _ RuleContext) Rule {
+			return NewShippingRule(minCartAmount(d), d.Percentage, d.ShippingMethods)
+		}},
+	}
+	for ruleType, factory := range builtins {
+		if err := registry.Register(ruleType, factory); err != nil {
+			panic(err)
+		}
+	}
+	return registry
+}
+
+// minPurchaseAmount - returns the min purchase amount of the definition, the given amount of the facts if it is not set
+func minPurchaseAmount(definition Definition, facts Facts) decimal.Decimal {
+	if definition.MinPurchaseAmount != nil {
+		return *definition.MinPurchaseAmount
+	}
+	return facts.GivenAmount
+}
+
+// newPurchaseAmountRule - creates the purchase amount rule of the definition with the monthly amount of the customer
+func newPurchaseAmountRule(definition Definition, ruleContext RuleContext) Rule {
+	rule := NewPurchaseAmountRule(minPurchaseAmount(definition, ruleContext.Facts), ruleContext.UserMonthlyAmount)
+	rule.DiscountPercentage = definition.Percentage
+	return rule
+}
+
+// newEveryNthOrderRule - creates the every nth order rule of the definition with the order history of the customer
+func newEveryNthOrderRule(definition Definition, ruleContext RuleContext) Rule {
+	rule := NewEveryNthOrderRule(minPurchaseAmount(definition, ruleContext.Facts), definition.OrderInterval, definition.LookbackDays, ruleContext.UserOrders, ruleContext.Now)
+	rule.RecentOrderCount = ruleContext.RecentOrderCount
+	rule.LastOrdersAmount = ruleContext.LastOrdersAmount
+	rule.VatRateDiscountPercentages = definition.VatRatePercentages
+	rule.DiscountPercentage = definition.Percentage
+	return rule
+}
+
+// minCartAmount - returns the min purchase amount of the definition as the min cart amount, zero if it is not set
+func minCartAmount(definition Definition) decimal.Decimal {
+	if definition.MinPurchaseAmount != nil {
+		return *definition.MinPurchaseAmount
+	}
+	return decimal.Zero
+}
+
+// newPercentageRule - creates the percentage rule of the definition, it has no min cart amount unless the definition sets one
+func newPercentageRule(definition Definition, _ RuleContext) Rule {
+	return NewPercentageRule(minCartAmount(definition), definition.Percentage)
+}
+
+// validateEveryNthOrder - checks the interval and lookback window of the every nth order definition
+func validateEveryNthOrder(d Definition) error {
+	if d.OrderInterval < 0 || d.LookbackDays < 0 {
+		return fmt.Errorf("%w: campaign %s needs a positive order_interval and lookback_days", ErrInvalidQuantityRule, d.ID)
+	}
+	return nil
+}
+
+// validateBuyXGetY - checks the quantities of the buy x get y definition
+func validateBuyXGetY(d Definition) error {
+	if d.BuyQuantity <= 0 || d.GetQuantity <= 0 {
+		return fmt.Errorf("%w: campaign %s needs positive buy_quantity and get_quantity", ErrInvalidQuantityRule, d.ID)
+	}
+	return nil
+}
+
+// validateBundle - checks the items and price of the bundle definition
+func validateBundle(d Definition) error {
+	if len(d.BundleItems) == 0 || !d.BundlePrice.IsPositive() {
+		return fmt.Errorf("%w: campaign %s needs bundle_items and a positive bundle_price", ErrInvalidQuantityRule, d.ID)
+	}
+	for _, units := range d.BundleItems {
+		if units <= 0 {
+			return fmt.Errorf("%w: campaign %s needs positive bundle item units", ErrInvalidQuantityRule, d.ID)
+		}
+	}
+	return nil
+}
+
+// validateTieredQuantity - checks the tiers of the tiered quantity definition
+func validateTieredQuantity(d Definition) error {
+	if len(d.Tiers) == 0 {
+		return fmt.Errorf("%w: campaign %s needs tiers", ErrInvalidQuantityRule, d.ID)
+	}
+	for _, tier := range d.Tiers {
+		if tier.MinQuantity <= 0 || !isValidPercentage(tier.Percentage) || (tier.UnitPrice != nil && tier.UnitPrice.IsNegative()) {
+			return fmt.Errorf("%w: campaign %s has an invalid tier", ErrInvalidQuantityRule, d.ID)
+		}
+	}
+	return nil
+}
+
+// validatePercentage - checks that the percentage definition gives a discount
+func validatePercentage(d Definition) error {
+	if !d.Percentage.IsPositive() {
+		return fmt.Errorf("%w: campaign %s needs a positive percentage", ErrInvalidPercentage, d.ID)
+	}
+	return nil
+}
@@ -1,19 +1,67 @@
 package campaign
 
-import "github.com/erdemcemal/basket-service/internal/models"
+import (
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"strconv"
+)
 
-// SameProductRule - represents a rule if any item quantity is more than 3 than apply the discount
+// SameProductRule - represents a rule if any item quantity is more than min quantity than apply the discount
 type SameProductRule struct {
+	// MinQuantity - quantity after which the discount is applied, default quantity is used if it is zero
+	MinQuantity int32
+	// DiscountPercentage - discount percentage applied on the items, default percentage is used if it is zero
+	DiscountPercentage decimal.Decimal
 }
 
-// CalculateDiscount - represents a rule if any item quantity is more than 3 than apply the discount
-func (r SameProductRule) CalculateDiscount(cart models.ShoppingCart) float64 {
-	var total float64
-	// loop through the cart and calculate the discount for each item if item quantity is greater than 3 apply discount fourth and subsequent items.
+// NewSameProductRule - creates a new same product rule with the given min quantity and discount percentage
+func NewSameProductRule(minQuantity int32, discountPercentage decimal.Decimal) *SameProductRule {
+	return &SameProductRule{
+		MinQuantity:        minQuantity,
+		DiscountPercentage: discountPercentage,
+	}
+}
+
+// CalculateDiscount - represents a rule if any item quantity is more than min quantity than apply the discount
+func (r SameProductRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	minQuantity := r.MinQuantity
+	if minQuantity == 0 {
+		minQuantity = sameProductRuleMinQuantity
+	}
+	percentage := r.DiscountPercentage
+	if percentage.IsZero() {
+		percentage = decimal.New(sameProductRuleDiscountPercentage, 0)
+	}
+	var lines []LineDiscount
+	// loop through the cart and calculate the discount for each item if item quantity is greater than min quantity apply discount to subsequent items.
+	for _, item := range cart.Items {
+		if item.Quantity > minQuantity {
+			lines = append(lines, LineDiscount{
+				ProductID: item.ProductID.String(),
+				Amount:    percentageOf(item.Price.Mul(decimal.NewFromInt32(item.Quantity-minQuantity)), percentage),
+			})
+		}
+	}
+	return newResult(RuleTypeSameProduct, sameProductRuleLabel, lines)
+}
+
+// Explain - returns the min quantity the rule is calculated with and if any item quantity is more than it
+func (r SameProductRule) Explain(cart models.ShoppingCart) Explanation {
+	minQuantity := r.MinQuantity
+	if minQuantity == 0 {
+		minQuantity = sameProductRuleMinQuantity
+	}
+	explanation := Explanation{
+		Inputs:             map[string]string{"min_quantity": strconv.Itoa(int(minQuantity))},
+		PreconditionPassed: false,
+		Reason:             fmt.Sprintf("no item quantity is more than %d", minQuantity),
+	}
 	for _, item := range cart.Items {
-		if item.Quantity > 3 {
-			total += item.Price.InexactFloat64() * float64(item.Quantity-3) * sameProductRuleDiscountPercentage / 100
+		if item.Quantity > minQuantity {
+			explanation.PreconditionPassed = true
+			explanation.Reason = ""
 		}
 	}
-	return total
+	return explanation
 }
@@ -0,0 +1,131 @@
+package campaign
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// embeds the timezone database, so schedules work in images without zoneinfo
+	_ "time/tzdata"
+)
+
+// Clock - provides the time the rules are evaluated at
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock - is a clock which returns the current time
+type SystemClock struct{}
+
+// Now - returns the current time
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock - is a clock which always returns the same time, rules are evaluated "as of" that time
+type FixedClock time.Time
+
+// Now - returns the fixed time
+func (c FixedClock) Now() time.Time {
+	return time.Time(c)
+}
+
+// HourRange - represents the hours of a day a campaign is active, from is inclusive and to is exclusive
+type HourRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// Schedule - represents when a campaign is active: its validity window, days of week and hours of day
+type Schedule struct {
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+	// DaysOfWeek - lower case english day names, empty means every day
+	DaysOfWeek []string `json:"days_of_week,omitempty"`
+	// Hours - hour ranges of the day, a range may wrap around midnight, empty means all day
+	Hours []HourRange `json:"hours,omitempty"`
+	// Timezone - IANA timezone the days and hours are evaluated in, UTC is used if it is empty
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// Validate - checks the validity window, days, hours and timezone of the schedule
+func (s Schedule) Validate() error {
+	if s.ValidFrom != nil && s.ValidUntil != nil && !s.ValidFrom.Before(*s.ValidUntil) {
+		return ErrInvalidValidityRange
+	}
+	for _, day := range s.DaysOfWeek {
+		if _, ok := parseWeekday(day); !ok {
+			return fmt.Errorf("%w: unknown day %q", ErrInvalidSchedule, day)
+		}
+	}
+	for _, hours := range s.Hours {
+		if hours.From < 0 || hours.From > 23 || hours.To < 0 || hours.To > 24 || hours.From == hours.To {
+			return fmt.Errorf("%w: invalid hours %d-%d", ErrInvalidSchedule, hours.From, hours.To)
+		}
+	}
+	if _, err := s.location(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	return nil
+}
+
+// IsActiveAt - checks if the schedule contains the given time
+func (s Schedule) IsActiveAt(now time.Time) bool {
+	if s.ValidFrom != nil && now.Before(*s.ValidFrom) {
+		return false
+	}
+	if s.ValidUntil != nil && !now.Before(*s.ValidUntil) {
+		return false
+	}
+	location, err := s.location()
+	if err != nil {
+		return false
+	}
+	local := now.In(location)
+	if len(s.DaysOfWeek) > 0 {
+		active := false
+		for _, day := range s.DaysOfWeek {
+			if weekday, ok := parseWeekday(day); ok && weekday == local.Weekday() {
+				active = true
+			}
+		}
+		if !active {
+			return false
+		}
+	}
+	if len(s.Hours) > 0 {
+		hour := local.Hour()
+		for _, hours := range s.Hours {
+			if hours.contains(hour) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// location - returns the timezone of the schedule
+func (s Schedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+// contains - checks if the hour is in the range, ranges like 22-2 wrap around midnight
+func (h HourRange) contains(hour int) bool {
+	if h.From < h.To {
+		return hour >= h.From && hour < h.To
+	}
+	return hour >= h.From || hour < h.To
+}
+
+// parseWeekday - returns the weekday of the given english day name
+func parseWeekday(day string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(weekday.String(), day) {
+			return weekday, true
+		}
+	}
+	return time.Sunday, false
+}
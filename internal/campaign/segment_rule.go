@@ -0,0 +1,52 @@
+package campaign
+
+import (
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+// SegmentRule - is a rule applied only if the customer is in one of the target segments
+type SegmentRule struct {
+	Rule
+	// TargetSegments - segments the rule is applied for, such as a loyalty tier, "vip" or "first_time_buyer"
+	TargetSegments []string
+	// CustomerSegments - segments of the customer the cart belongs to
+	CustomerSegments []string
+}
+
+// NewSegmentRule - creates a new segment rule which applies the given rule only for the target segments
+func NewSegmentRule(rule Rule, targetSegments, customerSegments []string) *SegmentRule {
+	return &SegmentRule{Rule: rule, TargetSegments: targetSegments, CustomerSegments: customerSegments}
+}
+
+// IsTargeted - checks if the customer is in one of the target segments
+func (r SegmentRule) IsTargeted() bool {
+	for _, segment := range r.CustomerSegments {
+		if containsAny(r.TargetSegments, segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// CalculateDiscount - calculates the discount of the wrapped rule if the customer is targeted, no discount otherwise
+func (r SegmentRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	result := r.Rule.CalculateDiscount(cart)
+	if !r.IsTargeted() {
+		return Result{RuleID: result.RuleID, Label: result.Label, Amount: decimal.Zero}
+	}
+	return result
+}
+
+// Explain - returns the explanation of the wrapped rule with the target and the customer segments
+func (r SegmentRule) Explain(cart models.ShoppingCart) Explanation {
+	explanation := explain(r.Rule, cart)
+	explanation.Inputs = withInput(explanation.Inputs, "target_segments", strings.Join(r.TargetSegments, ","))
+	explanation.Inputs = withInput(explanation.Inputs, "customer_segments", strings.Join(r.CustomerSegments, ","))
+	if !r.IsTargeted() {
+		explanation.PreconditionPassed = false
+		explanation.Reason = "customer is not in any target segment"
+	}
+	return explanation
+}
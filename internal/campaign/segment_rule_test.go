@@ -0,0 +1,37 @@
+package campaign
+
+import (
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestSegmentRule_CalculateDiscount(t *testing.T) {
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{ProductID: uuid.Must(uuid.NewV4()), Quantity: 2, Price: decimal.New(100, 0)},
+		},
+	}
+	cart.CalculateTotalPrice()
+	firstOrder := Definition{ID: "first-order", Type: RuleTypePercentage, Percentage: decimal.New(15, 0), Segments: []string{"first_time_buyer"}}
+	tests := []struct {
+		name     string
+		segments []string
+		expected string
+	}{
+		{name: "first time buyer", segments: []string{"bronze", "first_time_buyer"}, expected: "30"},
+		{name: "returning customer", segments: []string{"gold"}, expected: "0"},
+		{name: "unknown customer", expected: "0"},
+	}
+	for _, test := range tests {
+		rule, err := firstOrder.NewRule(RuleContext{Facts: Facts{Segments: test.segments}})
+		if err != nil {
+			t.Fatalf("Expected rule of %s to be created, got %v", test.name, err)
+		}
+		discount := NewDiscountCalculator([]Rule{rule}).CalculateDiscount(cart)
+		if !discount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected %s discount to be %s, got %s", test.name, test.expected, discount)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package campaign
+
+import (
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+const (
+	shippingRuleLabel     = "Shipping discount"
+	freeShippingRuleLabel = "Free shipping"
+)
+
+// ShippingRule - is a rule applies the discount percentage on the shipping cost if the cart total is more than the min amount,
+// a hundred percent is free shipping
+type ShippingRule struct {
+	// MinCartAmount - cart total must be more than it, every cart gets the discount if it is zero
+	MinCartAmount      decimal.Decimal
+	DiscountPercentage decimal.Decimal
+	// ShippingMethods - shipping methods the discount is given on, every method if it is empty
+	ShippingMethods []string
+}
+
+// NewShippingRule - creates a new shipping rule with the given min cart amount, discount percentage and shipping methods
+func NewShippingRule(minCartAmount, discountPercentage decimal.Decimal, shippingMethods []string) *ShippingRule {
+	return &ShippingRule{
+		MinCartAmount:      minCartAmount,
+		DiscountPercentage: discountPercentage,
+		ShippingMethods:    shippingMethods,
+	}
+}
+
+// CalculateDiscount - calculates the discount percentage of the shipping cost of the cart, the discount is a line of the shipping cost
+func (s ShippingRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	if !s.Explain(cart).PreconditionPassed {
+		return newResult(RuleTypeShipping, s.label(), nil)
+	}
+	return newResult(RuleTypeShipping, s.label(), []LineDiscount{{
+		ProductID: models.ShippingLineID,
+		Amount:    percentageOf(cart.ShippingCost, s.DiscountPercentage),
+	}})
+}
+
+// Explain - returns the shipping of the cart the rule is calculated with and if it gets the discount
+func (s ShippingRule) Explain(cart models.ShoppingCart) Explanation {
+	explanation := Explanation{
+		Inputs: map[string]string{
+			"min_cart_amount": s.MinCartAmount.String(),
+			"cart_amount":     cart.TotalPrice.String(),
+			"shipping_method": cart.ShippingMethod,
+			"shipping_cost":   cart.ShippingCost.String(),
+		},
+		PreconditionPassed: true,
+	}
+	switch {
+	case cart.ShippingMethod == "" || !cart.ShippingCost.IsPositive():
+		explanation.PreconditionPassed = false
+		explanation.Reason = "cart has no shipping cost"
+	case len(s.ShippingMethods) > 0 && !containsAny(s.ShippingMethods, cart.ShippingMethod):
+		explanation.PreconditionPassed = false
+		explanation.Reason = fmt.Sprintf("shipping method %s is not one of %s", cart.ShippingMethod, strings.Join(s.ShippingMethods, ", "))
+	case s.MinCartAmount.IsPositive() && s.MinCartAmount.GreaterThanOrEqual(cart.TotalPrice):
+		explanation.PreconditionPassed = false
+		explanation.Reason = fmt.Sprintf("cart amount %s is not more than %s", cart.TotalPrice, s.MinCartAmount)
+	}
+	return explanation
+}
+
+// label - returns the label of the rule, free shipping if the whole shipping cost is discounted
+func (s ShippingRule) label() string {
+	if s.DiscountPercentage.Equal(decimal.New(100, 0)) {
+		return freeShippingRuleLabel
+	}
+	return shippingRuleLabel
+}
@@ -0,0 +1,73 @@
+package campaign
+
+import (
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestShippingRule_CalculateDiscount(t *testing.T) {
+	goldFreeShipping := Definition{ID: "gold-free-shipping", Type: RuleTypeShipping, Percentage: decimal.New(100, 0), Segments: []string{"gold"}}
+	halfExpress := Definition{ID: "half-express", Type: RuleTypeShipping, Percentage: decimal.New(50, 0), ShippingMethods: []string{"express"}, MinPurchaseAmount: decimalPtr(decimal.New(150, 0))}
+	tests := []struct {
+		name           string
+		definition     Definition
+		segments       []string
+		shippingMethod string
+		expected       string
+	}{
+		{name: "gold customer", definition: goldFreeShipping, segments: []string{"gold"}, shippingMethod: "standard", expected: "30"},
+		{name: "bronze customer", definition: goldFreeShipping, segments: []string{"bronze"}, shippingMethod: "standard", expected: "0"},
+		{name: "no shipping method", definition: goldFreeShipping, segments: []string{"gold"}, expected: "0"},
+		{name: "express shipping", definition: halfExpress, shippingMethod: "express", expected: "15"},
+		{name: "other shipping method", definition: halfExpress, shippingMethod: "standard", expected: "0"},
+	}
+	for _, test := range tests {
+		cart := models.ShoppingCart{Items: []models.ShoppingCartItem{{ProductID: uuid.Must(uuid.NewV4()), Quantity: 2, Price: decimal.New(100, 0)}}}
+		cart.CalculateTotalPrice()
+		if test.shippingMethod != "" {
+			cart.SelectShipping(test.shippingMethod, "", decimal.New(30, 0))
+		}
+		rule, err := test.definition.NewRule(RuleContext{Facts: Facts{Segments: test.segments}})
+		if err != nil {
+			t.Fatalf("Expected rule of %s to be created, got %v", test.name, err)
+		}
+		calculation := NewDiscountCalculator([]Rule{rule}).Calculate(cart)
+		if !calculation.Amount.Equal(decimal.RequireFromString(test.expected)) {
+			t.Errorf("Expected %s shipping discount to be %s, got %s", test.name, test.expected, calculation.Amount)
+		}
+		if calculation.Amount.IsPositive() {
+			var applied models.AppliedCampaigns
+			for _, result := range calculation.Applied {
+				appliedCampaign := models.AppliedCampaign{CampaignID: result.RuleID, Amount: result.Amount}
+				for _, line := range result.Lines {
+					appliedCampaign.Items = append(appliedCampaign.Items, models.AppliedCampaignItem{ProductID: line.ProductID, Amount: line.Amount})
+				}
+				applied = append(applied, appliedCampaign)
+			}
+			cart.ApplyCampaigns(applied)
+			if !cart.ShippingDiscount.Equal(calculation.Amount) || !cart.Items[0].Discount.IsZero() {
+				t.Errorf("Expected %s discount to be given on the shipping cost, got %s", test.name, cart.ShippingDiscount)
+			}
+		}
+	}
+}
+
+func TestDiscountCalculator_SumPolicy_CapsDiscountAtCartTotalWithShipping(t *testing.T) {
+	cart := models.ShoppingCart{Items: []models.ShoppingCartItem{{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, Price: decimal.New(100, 0)}}}
+	cart.CalculateTotalPrice()
+	cart.SelectShipping("standard", "", decimal.New(30, 0))
+	rules := []Rule{
+		NewPercentageRule(decimal.Zero, decimal.New(100, 0)),
+		NewShippingRule(decimal.Zero, decimal.New(100, 0), nil),
+	}
+	discount := NewDiscountCalculatorWithPolicy(rules, PolicySum).CalculateDiscount(cart)
+	if !discount.Equal(decimal.New(130, 0)) {
+		t.Errorf("Expected discount to be the cart total with shipping 130, got %s", discount)
+	}
+}
+
+func decimalPtr(value decimal.Decimal) *decimal.Decimal {
+	return &value
+}
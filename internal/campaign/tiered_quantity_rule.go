@@ -0,0 +1,61 @@
+package campaign
+
+import (
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+)
+
+const tieredQuantityRuleLabel = "Quantity tier discount"
+
+// Tier - represents the price of an item from a quantity on, either as a discount percentage or as a unit price
+type Tier struct {
+	MinQuantity int32            `json:"min_quantity"`
+	Percentage  decimal.Decimal  `json:"percentage,omitempty"`
+	UnitPrice   *decimal.Decimal `json:"unit_price,omitempty"`
+}
+
+// TieredQuantityRule - is a rule that prices every unit of an item with the highest tier its quantity reaches
+type TieredQuantityRule struct {
+	// ProductIDs - products the tiers apply to, empty means every product
+	ProductIDs []string
+	Tiers      []Tier
+}
+
+// NewTieredQuantityRule - creates a new tiered quantity rule with the given products and tiers
+func NewTieredQuantityRule(productIDs []string, tiers []Tier) *TieredQuantityRule {
+	return &TieredQuantityRule{ProductIDs: productIDs, Tiers: tiers}
+}
+
+// CalculateDiscount - calculates the discount of every item with the highest tier reached by the item quantity
+func (t TieredQuantityRule) CalculateDiscount(cart models.ShoppingCart) Result {
+	var lines []LineDiscount
+	for _, item := range cart.Items {
+		if !matchesProduct(t.ProductIDs, item.ProductID.String()) {
+			continue
+		}
+		tier, ok := t.tierFor(item.Quantity)
+		if !ok {
+			continue
+		}
+		itemAmount := item.Price.Mul(decimal.NewFromInt32(item.Quantity))
+		amount := percentageOf(itemAmount, tier.Percentage)
+		if tier.UnitPrice != nil {
+			amount = item.Price.Sub(*tier.UnitPrice).Mul(decimal.NewFromInt32(item.Quantity))
+		}
+		lines = append(lines, LineDiscount{ProductID: item.ProductID.String(), Amount: decimal.Min(amount, itemAmount)})
+	}
+	return newResult(RuleTypeTieredQuantity, tieredQuantityRuleLabel, lines)
+}
+
+// tierFor - returns the tier with the highest min quantity reached by the given quantity
+func (t TieredQuantityRule) tierFor(quantity int32) (Tier, bool) {
+	var best Tier
+	found := false
+	for _, tier := range t.Tiers {
+		if quantity >= tier.MinQuantity && (!found || tier.MinQuantity > best.MinQuantity) {
+			best = tier
+			found = true
+		}
+	}
+	return best, found
+}
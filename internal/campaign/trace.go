@@ -0,0 +1,130 @@
+package campaign
+
+import (
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Explainer - is implemented by the rules which can tell the inputs they are calculated with and if their precondition passed
+type Explainer interface {
+	Explain(cart models.ShoppingCart) Explanation
+}
+
+// Explanation - represents the inputs of a rule for a cart and if its precondition passed
+type Explanation struct {
+	Inputs             map[string]string
+	PreconditionPassed bool
+	// Reason - why the precondition didn't pass
+	Reason string
+}
+
+// Trace - represents how every rule is evaluated for a cart and why its discount is applied or not
+type Trace struct {
+	Policy      Policy
+	EvaluatedAt time.Time
+	Amount      decimal.Decimal
+	Rules       []RuleTrace
+}
+
+// RuleTrace - represents the evaluation of a rule
+type RuleTrace struct {
+	RuleID           string
+	Label            string
+	Priority         int
+	ExclusivityGroup string
+	// Active - rule is active at the evaluation time by its schedule
+	Active bool
+	Explanation
+	// Amount - discount calculated by the rule, AppliedAmount - part of it applied with the policy
+	Amount        decimal.Decimal
+	AppliedAmount decimal.Decimal
+	Applied       bool
+	// Outcome - why the discount is applied or not
+	Outcome string
+}
+
+// explain - returns the explanation of the given rule, rules which can't explain themselves only tell if they give a discount
+func explain(rule Rule, cart models.ShoppingCart) Explanation {
+	if explainer, ok := rule.(Explainer); ok {
+		return explainer.Explain(cart)
+	}
+	return Explanation{PreconditionPassed: true}
+}
+
+// Explain - returns the explanation of the wrapped rule
+func (r PrioritizedRule) Explain(cart models.ShoppingCart) Explanation {
+	return explain(r.Rule, cart)
+}
+
+// Explain - calculates the discount for the given cart and returns how every rule is evaluated and why it is applied or not
+func (dc *DiscountCalculator) Explain(cart models.ShoppingCart) Trace {
+	now := dc.clock.Now()
+	calculation := dc.Calculate(cart)
+	applied := make(map[string]Result)
+	for _, result := range calculation.Applied {
+		applied[result.RuleID] = result
+	}
+	appliedGroups := make(map[string]string)
+	for _, evaluation := range byPriority(dc.evaluate(cart)) {
+		if _, ok := applied[evaluation.result.RuleID]; ok && evaluation.rule.ExclusivityGroup != "" {
+			appliedGroups[evaluation.rule.ExclusivityGroup] = evaluation.result.RuleID
+		}
+	}
+
+	trace := Trace{Policy: dc.policy, EvaluatedAt: now, Amount: calculation.Amount}
+	for _, rule := range dc.rules {
+		prioritized := asPrioritized(rule)
+		result := dc.evaluateRule(prioritized, cart)
+		ruleTrace := RuleTrace{
+			RuleID:           result.RuleID,
+			Label:            result.Label,
+			Priority:         prioritized.Priority,
+			ExclusivityGroup: prioritized.ExclusivityGroup,
+			Active:           prioritized.Schedule.IsActiveAt(now),
+			Explanation:      explain(rule, cart),
+			Amount:           result.Amount,
+			AppliedAmount:    decimal.Zero,
+		}
+		appliedResult, isApplied := applied[result.RuleID]
+		switch {
+		case !ruleTrace.Active:
+			ruleTrace.Amount = decimal.Zero
+			ruleTrace.Outcome = fmt.Sprintf("not active at %s by its schedule", now.Format(time.RFC3339))
+		case !ruleTrace.PreconditionPassed:
+			ruleTrace.Outcome = "precondition not met"
+			if ruleTrace.Reason != "" {
+				ruleTrace.Outcome += ": " + ruleTrace.Reason
+			}
+		case !result.Amount.IsPositive():
+			ruleTrace.Outcome = "no discount for the cart"
+		case isApplied:
+			ruleTrace.Applied = true
+			ruleTrace.AppliedAmount = appliedResult.Amount
+			ruleTrace.Outcome = fmt.Sprintf("applied with the %s policy", trace.Policy)
+		default:
+			ruleTrace.Outcome = dc.lostReason(prioritized, calculation, appliedGroups)
+		}
+		trace.Rules = append(trace.Rules, ruleTrace)
+	}
+	return trace
+}
+
+// lostReason - returns why the discount of a rule which passed its precondition is not applied
+func (dc *DiscountCalculator) lostReason(rule PrioritizedRule, calculation Calculation, appliedGroups map[string]string) string {
+	switch dc.policy {
+	case PolicyPriority:
+		if winner, ok := appliedGroups[rule.ExclusivityGroup]; ok && rule.ExclusivityGroup != "" {
+			return fmt.Sprintf("%s of the exclusivity group %s is applied before it", winner, rule.ExclusivityGroup)
+		}
+	case PolicySequential:
+		return "nothing remained of the cart amount after the rules with higher priority"
+	case PolicySum:
+	default:
+		if len(calculation.Applied) > 0 {
+			return fmt.Sprintf("lost against the higher discount of %s", calculation.Applied[0].RuleID)
+		}
+	}
+	return "not applied with the " + string(dc.policy) + " policy"
+}
@@ -0,0 +1,57 @@
+package campaign
+
+import (
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestDiscountCalculator_Explain(t *testing.T) {
+	now := time.Date(2022, 7, 1, 12, 0, 0, 0, time.UTC)
+	expired := now.AddDate(0, 0, -1)
+	cart := models.ShoppingCart{
+		Items: []models.ShoppingCartItem{
+			{Quantity: 4, Price: decimal.New(100, 0), VatRate: decimal.New(18, 0)},
+		},
+	}
+	cart.CalculateTotalPrice()
+	rules := []Rule{
+		PrioritizedRule{Rule: NewPurchaseAmountRule(decimal.New(500, 0), decimal.New(200, 0)), ID: "purchase-amount"},
+		PrioritizedRule{Rule: NewSameProductRule(3, decimal.New(8, 0)), ID: "same-product"},
+		PrioritizedRule{Rule: NewPercentageRule(decimal.Zero, decimal.New(10, 0)), ID: "ten-percent"},
+		PrioritizedRule{Rule: NewPercentageRule(decimal.Zero, decimal.New(50, 0)), ID: "expired", Schedule: Schedule{ValidUntil: &expired}},
+	}
+	trace := NewDiscountCalculator(rules).WithClock(FixedClock(now)).Explain(cart)
+
+	if !trace.Amount.Equal(decimal.New(40, 0)) || len(trace.Rules) != len(rules) {
+		t.Fatalf("Expected trace of 4 rules with 40 discount, got %+v", trace)
+	}
+	expected := []struct {
+		ruleID             string
+		preconditionPassed bool
+		applied            bool
+		amount             string
+	}{
+		{ruleID: "purchase-amount", preconditionPassed: false, applied: false, amount: "0"},
+		// one unit over the min quantity: 100 * 0.08
+		{ruleID: "same-product", preconditionPassed: true, applied: false, amount: "8"},
+		{ruleID: "ten-percent", preconditionPassed: true, applied: true, amount: "40"},
+		{ruleID: "expired", preconditionPassed: true, applied: false, amount: "0"},
+	}
+	for i, ruleTrace := range trace.Rules {
+		if ruleTrace.RuleID != expected[i].ruleID || ruleTrace.PreconditionPassed != expected[i].preconditionPassed ||
+			ruleTrace.Applied != expected[i].applied || !ruleTrace.Amount.Equal(decimal.RequireFromString(expected[i].amount)) {
+			t.Errorf("Expected trace of %s to be %+v, got %+v", expected[i].ruleID, expected[i], ruleTrace)
+		}
+		if ruleTrace.Outcome == "" {
+			t.Errorf("Expected trace of %s to have an outcome", ruleTrace.RuleID)
+		}
+	}
+	if trace.Rules[0].Inputs["customer_purchase_amount_in_month"] != "200" {
+		t.Errorf("Expected customer purchase amount input to be 200, got %v", trace.Rules[0].Inputs)
+	}
+	if trace.Rules[1].Outcome != "lost against the higher discount of ten-percent" {
+		t.Errorf("Expected same product rule to lose against ten percent, got %s", trace.Rules[1].Outcome)
+	}
+}
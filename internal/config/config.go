@@ -0,0 +1,461 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/campaign"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/erdemcemal/basket-service/internal/tax"
+	"github.com/shopspring/decimal"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultServerAddress  = "0.0.0.0:8080"
+	defaultRequestTimeout = 15 * time.Second
+	defaultDatabasePort   = "5432"
+	defaultSSLMode        = "disable"
+	defaultHistoryMonths  = 1
+	defaultReservationTTL = 15 * time.Minute
+	defaultSweepInterval  = time.Minute
+	defaultTierMonths     = 12
+	defaultSilverAmount   = 1000
+	defaultGoldAmount     = 5000
+	defaultJWKSCacheTTL   = 10 * time.Minute
+	defaultTokenLeeway    = 30 * time.Second
+)
+
+var (
+	ErrInvalidConfig = errors.New("invalid configuration")
+)
+
+// Config - contains all the settings of the application, loaded once at startup
+type Config struct {
+	Server   ServerConfig   `json:"server"`
+	Database DatabaseConfig `json:"database"`
+	Store    StoreConfig    `json:"store"`
+	Campaign CampaignConfig `json:"campaign"`
+	Customer CustomerConfig `json:"customer"`
+	Tax      TaxConfig      `json:"tax"`
+	Currency CurrencyConfig `json:"currency"`
+	Auth     AuthConfig     `json:"auth"`
+	Basket   BasketConfig   `json:"basket"`
+}
+
+// ServerConfig - contains the http server settings
+type ServerConfig struct {
+	Address        string   `json:"address"`
+	RequestTimeout Duration `json:"request_timeout"`
+}
+
+// DatabaseConfig - contains the database connection settings
+type DatabaseConfig struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	SSLMode  string `json:"ssl_mode"`
+}
+
+// StoreConfig - contains the basket store settings
+type StoreConfig struct {
+	// HistoryMonths - how many months of sales history the purchase amount of a user is calculated from
+	HistoryMonths int `json:"history_months"`
+	// ReservationTTL - how long the stock of a product added to a basket is reserved for it
+	ReservationTTL Duration `json:"reservation_ttl"`
+	// ReservationSweepInterval - how often the expired stock reservations are deleted
+	ReservationSweepInterval Duration `json:"reservation_sweep_interval"`
+}
+
+// CampaignConfig - contains the campaign settings
+type CampaignConfig struct {
+	// GivenAmount - purchase amount threshold used by the campaigns which don't define their own
+	GivenAmount *decimal.Decimal `json:"given_amount"`
+	// File - json file of the campaign definitions, default campaigns are used if it is empty
+	File string `json:"file"`
+	// Definitions - campaign definitions loaded from the file
+	Definitions campaign.Config `json:"-"`
+}
+
+// CustomerConfig - contains the customer segment settings
+type CustomerConfig struct {
+	// TierMonths - how many months of sales history the loyalty tier of a user is calculated from
+	TierMonths int `json:"tier_months"`
+	// SilverTierAmount and GoldTierAmount - purchase amounts in the tier months a user reaches the tier with
+	SilverTierAmount *decimal.Decimal `json:"silver_tier_amount"`
+	GoldTierAmount   *decimal.Decimal `json:"gold_tier_amount"`
+}
+
+// TaxConfig - contains the vat settings
+type TaxConfig struct {
+	// PricesIncludeVat - catalogue prices include vat, vat is taken out of the prices instead of being added on them
+	PricesIncludeVat bool `json:"prices_include_vat"`
+	// Rates - effective dated vat rates of the tax classes
+	Rates []tax.Rate `json:"rates"`
+}
+
+// CurrencyConfig - contains the basket currency settings
+type CurrencyConfig struct {
+	// Default - currency of the baskets whose client doesn't select one
+	Default string `json:"default"`
+	// Supported - currencies a basket can be in, products are sold in the currencies of their price lists
+	Supported []string `json:"supported"`
+}
+
+// AuthConfig - contains the bearer token settings, tokens are signed with the HS256 secret or a RS256 key of the jwks
+type AuthConfig struct {
+	// Issuer and Audience - the "iss" claim and a value of the "aud" claim the tokens must have
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+	// Secret - shared secret of the HS256 tokens, HS256 tokens are not accepted if it is empty
+	Secret string `json:"secret"`
+	// JWKSFile and JWKSURL - json web key set of the RS256 tokens, it is fetched again from the url after the cache ttl
+	JWKSFile     string   `json:"jwks_file"`
+	JWKSURL      string   `json:"jwks_url"`
+	JWKSCacheTTL Duration `json:"jwks_cache_ttl"`
+	// Leeway - clock skew allowed when the expiry of the tokens is checked
+	Leeway Duration `json:"leeway"`
+	// RolesClaim - claim of the tokens the roles of the user are read from, "roles" if it is empty
+	RolesClaim string `json:"roles_claim"`
+}
+
+// BasketConfig - contains the guest basket settings
+type BasketConfig struct {
+	// MergePolicy - how the quantities of the products in both baskets are combined when a guest basket is merged at login,
+	// the client can choose another policy for a merge
+	MergePolicy models.MergePolicy `json:"merge_policy"`
+}
+
+// Duration - is a time.Duration read from strings such as "15s"
+type Duration time.Duration
+
+// UnmarshalJSON - reads the duration from a json string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Load - loads the configuration from the defaults, the json config file, the environment and the command line flags,
+// each source overrides the previous one, and validates it
+func Load(args []string) (Config, error) {
+	cfg := defaultConfig()
+
+	flags := flag.NewFlagSet("basket-service", flag.ContinueOnError)
+	configFile := flags.String("config", os.Getenv("CONFIG_FILE"), "json configuration file")
+	address := flags.String("address", "", "address the http server listens on")
+	givenAmount := flags.String("given-amount", "", "purchase amount threshold of the campaigns")
+	campaignFile := flags.String("campaign-file", "", "json file of the campaign definitions")
+	if err := flags.Parse(args); err != nil {
+		return Config{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	if *configFile != "" {
+		if err := loadFile(*configFile, &cfg); err != nil {
+			return Config{}, err
+		}
+	}
+	var problems []string
+	problems = append(problems, loadEnv(&cfg)...)
+
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "address":
+			cfg.Server.Address = *address
+		case "given-amount":
+			if err := setDecimal(&cfg.Campaign.GivenAmount, *givenAmount); err != nil {
+				problems = append(problems, fmt.Sprintf("given-amount flag: %v", err))
+			}
+		case "campaign-file":
+			cfg.Campaign.File = *campaignFile
+		}
+	})
+
+	cfg.Currency = cfg.Currency.normalized()
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return Config{}, fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+
+	cfg.Campaign.Definitions = campaign.DefaultConfig()
+	if cfg.Campaign.File != "" {
+		definitions, err := campaign.LoadConfig(cfg.Campaign.File)
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+		cfg.Campaign.Definitions = definitions
+	}
+	return cfg, nil
+}
+
+// defaultConfig - returns the settings used when no source defines them
+func defaultConfig() Config {
+	return Config{
+		Server: ServerConfig{
+			Address:        defaultServerAddress,
+			RequestTimeout: Duration(defaultRequestTimeout),
+		},
+		Database: DatabaseConfig{
+			Port:    defaultDatabasePort,
+			SSLMode: defaultSSLMode,
+		},
+		Store: StoreConfig{
+			HistoryMonths:            defaultHistoryMonths,
+			ReservationTTL:           Duration(defaultReservationTTL),
+			ReservationSweepInterval: Duration(defaultSweepInterval),
+		},
+		Customer: CustomerConfig{
+			TierMonths:       defaultTierMonths,
+			SilverTierAmount: decimalPtr(decimal.New(defaultSilverAmount, 0)),
+			GoldTierAmount:   decimalPtr(decimal.New(defaultGoldAmount, 0)),
+		},
+		Tax: TaxConfig{
+			Rates: tax.DefaultTable().Rates,
+		},
+		Currency: CurrencyConfig{
+			Default:   models.BaseCurrency,
+			Supported: []string{models.BaseCurrency},
+		},
+		Auth: AuthConfig{
+			JWKSCacheTTL: Duration(defaultJWKSCacheTTL),
+			Leeway:       Duration(defaultTokenLeeway),
+		},
+		Basket: BasketConfig{
+			MergePolicy: models.MergeSumQuantities,
+		},
+	}
+}
+
+// loadFile - reads the json config file over the given config
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: failed to read config file %s: %v", ErrInvalidConfig, path, err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("%w: failed to parse config file %s: %v", ErrInvalidConfig, path, err)
+	}
+	return nil
+}
+
+// loadEnv - reads the environment variables which are set over the given config and returns the values which can't be parsed
+func loadEnv(cfg *Config) []string {
+	var problems []string
+	setString(&cfg.Server.Address, "SERVER_ADDRESS")
+	if value, ok := os.LookupEnv("REQUEST_TIMEOUT"); ok {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("REQUEST_TIMEOUT: %v", err))
+		}
+		cfg.Server.RequestTimeout = Duration(timeout)
+	}
+	setString(&cfg.Database.Host, "DB_HOST")
+	setString(&cfg.Database.Port, "DB_PORT")
+	setString(&cfg.Database.Username, "DB_USERNAME")
+	setString(&cfg.Database.Password, "DB_PASSWORD")
+	setString(&cfg.Database.Name, "DB_TABLE")
+	setString(&cfg.Database.SSLMode, "SSL_MODE")
+	if value, ok := os.LookupEnv("HISTORY_MONTHS"); ok {
+		months, err := strconv.Atoi(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("HISTORY_MONTHS: %v", err))
+		}
+		cfg.Store.HistoryMonths = months
+	}
+	if value, ok := os.LookupEnv("STOCK_RESERVATION_TTL"); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("STOCK_RESERVATION_TTL: %v", err))
+		}
+		cfg.Store.ReservationTTL = Duration(ttl)
+	}
+	if value, ok := os.LookupEnv("RESERVATION_SWEEP_INTERVAL"); ok {
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("RESERVATION_SWEEP_INTERVAL: %v", err))
+		}
+		cfg.Store.ReservationSweepInterval = Duration(interval)
+	}
+	if value, ok := os.LookupEnv("GIVEN_AMOUNT"); ok {
+		if err := setDecimal(&cfg.Campaign.GivenAmount, value); err != nil {
+			problems = append(problems, fmt.Sprintf("GIVEN_AMOUNT: %v", err))
+		}
+	}
+	setString(&cfg.Campaign.File, "CAMPAIGN_FILE")
+	if value, ok := os.LookupEnv("TIER_MONTHS"); ok {
+		months, err := strconv.Atoi(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("TIER_MONTHS: %v", err))
+		}
+		cfg.Customer.TierMonths = months
+	}
+	if value, ok := os.LookupEnv("SILVER_TIER_AMOUNT"); ok {
+		if err := setDecimal(&cfg.Customer.SilverTierAmount, value); err != nil {
+			problems = append(problems, fmt.Sprintf("SILVER_TIER_AMOUNT: %v", err))
+		}
+	}
+	if value, ok := os.LookupEnv("GOLD_TIER_AMOUNT"); ok {
+		if err := setDecimal(&cfg.Customer.GoldTierAmount, value); err != nil {
+			problems = append(problems, fmt.Sprintf("GOLD_TIER_AMOUNT: %v", err))
+		}
+	}
+	if value, ok := os.LookupEnv("PRICES_INCLUDE_VAT"); ok {
+		pricesIncludeVat, err := strconv.ParseBool(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("PRICES_INCLUDE_VAT: %v", err))
+		}
+		cfg.Tax.PricesIncludeVat = pricesIncludeVat
+	}
+	setString(&cfg.Currency.Default, "DEFAULT_CURRENCY")
+	if value, ok := os.LookupEnv("SUPPORTED_CURRENCIES"); ok {
+		cfg.Currency.Supported = strings.Split(value, ",")
+	}
+	setString(&cfg.Auth.Issuer, "JWT_ISSUER")
+	setString(&cfg.Auth.Audience, "JWT_AUDIENCE")
+	setString(&cfg.Auth.Secret, "JWT_SECRET")
+	setString(&cfg.Auth.JWKSFile, "JWKS_FILE")
+	setString(&cfg.Auth.JWKSURL, "JWKS_URL")
+	setString(&cfg.Auth.RolesClaim, "JWT_ROLES_CLAIM")
+	if value, ok := os.LookupEnv("BASKET_MERGE_POLICY"); ok {
+		cfg.Basket.MergePolicy = models.MergePolicy(value)
+	}
+	if value, ok := os.LookupEnv("JWKS_CACHE_TTL"); ok {
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("JWKS_CACHE_TTL: %v", err))
+		}
+		cfg.Auth.JWKSCacheTTL = Duration(ttl)
+	}
+	if value, ok := os.LookupEnv("JWT_LEEWAY"); ok {
+		leeway, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("JWT_LEEWAY: %v", err))
+		}
+		cfg.Auth.Leeway = Duration(leeway)
+	}
+	return problems
+}
+
+// validate - returns the problems of the config
+func (c Config) validate() []string {
+	var problems []string
+	if _, _, err := net.SplitHostPort(c.Server.Address); err != nil {
+		problems = append(problems, fmt.Sprintf("server address %q: %v", c.Server.Address, err))
+	}
+	if c.Server.RequestTimeout <= 0 {
+		problems = append(problems, "request timeout must be positive")
+	}
+	if c.Database.Host == "" {
+		problems = append(problems, "database host is required (DB_HOST)")
+	}
+	if port, err := strconv.Atoi(c.Database.Port); err != nil || port <= 0 || port > 65535 {
+		problems = append(problems, fmt.Sprintf("database port %q is not a valid port (DB_PORT)", c.Database.Port))
+	}
+	if c.Database.Username == "" {
+		problems = append(problems, "database username is required (DB_USERNAME)")
+	}
+	if c.Database.Name == "" {
+		problems = append(problems, "database name is required (DB_TABLE)")
+	}
+	if c.Store.HistoryMonths <= 0 {
+		problems = append(problems, "history months must be positive (HISTORY_MONTHS)")
+	}
+	if c.Store.ReservationTTL <= 0 || c.Store.ReservationSweepInterval <= 0 {
+		problems = append(problems, "stock reservation ttl and sweep interval must be positive (STOCK_RESERVATION_TTL, RESERVATION_SWEEP_INTERVAL)")
+	}
+	if c.Campaign.GivenAmount == nil {
+		problems = append(problems, "campaign given amount is required (GIVEN_AMOUNT)")
+	} else if c.Campaign.GivenAmount.IsNegative() {
+		problems = append(problems, "campaign given amount must not be negative (GIVEN_AMOUNT)")
+	}
+	if c.Customer.TierMonths <= 0 {
+		problems = append(problems, "tier months must be positive (TIER_MONTHS)")
+	}
+	if c.Customer.SilverTierAmount == nil || c.Customer.GoldTierAmount == nil {
+		problems = append(problems, "silver and gold tier amounts are required (SILVER_TIER_AMOUNT, GOLD_TIER_AMOUNT)")
+	} else if c.Customer.SilverTierAmount.IsNegative() || c.Customer.GoldTierAmount.LessThan(*c.Customer.SilverTierAmount) {
+		problems = append(problems, "gold tier amount must not be less than the silver tier amount and tier amounts must not be negative (SILVER_TIER_AMOUNT, GOLD_TIER_AMOUNT)")
+	}
+	if err := c.Tax.Table().Validate(); err != nil {
+		problems = append(problems, fmt.Sprintf("tax rates: %v", err))
+	}
+	if !c.Currency.IsSupported(c.Currency.Default) {
+		problems = append(problems, fmt.Sprintf("default currency %q must be one of the supported currencies (DEFAULT_CURRENCY, SUPPORTED_CURRENCIES)", c.Currency.Default))
+	}
+	if c.Auth.Issuer == "" || c.Auth.Audience == "" {
+		problems = append(problems, "token issuer and audience are required (JWT_ISSUER, JWT_AUDIENCE)")
+	}
+	if c.Auth.Secret == "" && c.Auth.JWKSFile == "" && c.Auth.JWKSURL == "" {
+		problems = append(problems, "a token secret or a json web key set is required (JWT_SECRET, JWKS_FILE, JWKS_URL)")
+	}
+	if c.Auth.JWKSFile != "" && c.Auth.JWKSURL != "" {
+		problems = append(problems, "only one of the jwks file and the jwks url can be set (JWKS_FILE, JWKS_URL)")
+	}
+	if c.Auth.JWKSCacheTTL <= 0 || c.Auth.Leeway < 0 {
+		problems = append(problems, "jwks cache ttl must be positive and token leeway must not be negative (JWKS_CACHE_TTL, JWT_LEEWAY)")
+	}
+	if !c.Basket.MergePolicy.IsValid() {
+		problems = append(problems, fmt.Sprintf("basket merge policy %q must be one of %s, %s and %s (BASKET_MERGE_POLICY)", c.Basket.MergePolicy, models.MergeSumQuantities, models.MergeKeepNewest, models.MergeCapAtStock))
+	}
+	return problems
+}
+
+// normalized - returns the currency settings with the currency codes in the form they are stored
+func (c CurrencyConfig) normalized() CurrencyConfig {
+	supported := make([]string, 0, len(c.Supported))
+	for _, currency := range c.Supported {
+		supported = append(supported, models.NormalizeCurrency(currency))
+	}
+	return CurrencyConfig{Default: models.NormalizeCurrency(c.Default), Supported: supported}
+}
+
+// IsSupported - checks if a basket can be in the given currency
+func (c CurrencyConfig) IsSupported(currency string) bool {
+	for _, supported := range c.Supported {
+		if supported == currency {
+			return true
+		}
+	}
+	return false
+}
+
+// Table - returns the vat rate table of the tax settings
+func (c TaxConfig) Table() tax.Table {
+	return tax.Table{Rates: c.Rates}
+}
+
+// setString - sets the value of the environment variable if it is set
+func setString(value *string, key string) {
+	if envValue, ok := os.LookupEnv(key); ok {
+		*value = envValue
+	}
+}
+
+// setDecimal - parses the given value as a decimal
+func setDecimal(value **decimal.Decimal, raw string) error {
+	amount, err := decimal.NewFromString(raw)
+	if err != nil {
+		return err
+	}
+	*value = &amount
+	return nil
+}
+
+// decimalPtr - returns a pointer to the given decimal
+func decimalPtr(value decimal.Decimal) *decimal.Decimal {
+	return &value
+}
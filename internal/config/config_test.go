@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DB_HOST", "db")
+	t.Setenv("DB_USERNAME", "postgres")
+	t.Setenv("DB_TABLE", "postgres")
+	t.Setenv("GIVEN_AMOUNT", "150")
+	t.Setenv("JWT_ISSUER", "https://auth.example.com/")
+	t.Setenv("JWT_AUDIENCE", "basket-service")
+	t.Setenv("JWT_SECRET", "secret")
+}
+
+func TestLoad_FromEnvAndFlags(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("REQUEST_TIMEOUT", "5s")
+	t.Setenv("STOCK_RESERVATION_TTL", "10m")
+	cfg, err := Load([]string{"-address", "127.0.0.1:9090", "-campaign-file", "../../config/campaigns.json"})
+	if err != nil {
+		t.Fatalf("Expected config to be loaded, got %v", err)
+	}
+	if cfg.Server.Address != "127.0.0.1:9090" {
+		t.Errorf("Expected address flag to override the default, got %s", cfg.Server.Address)
+	}
+	if time.Duration(cfg.Server.RequestTimeout) != 5*time.Second {
+		t.Errorf("Expected request timeout to be 5s, got %s", time.Duration(cfg.Server.RequestTimeout))
+	}
+	if time.Duration(cfg.Store.ReservationTTL) != 10*time.Minute {
+		t.Errorf("Expected stock reservation ttl to be 10m, got %s", time.Duration(cfg.Store.ReservationTTL))
+	}
+	if time.Duration(cfg.Store.ReservationSweepInterval) != defaultSweepInterval {
+		t.Errorf("Expected default reservation sweep interval, got %s", time.Duration(cfg.Store.ReservationSweepInterval))
+	}
+	if cfg.Database.Port != defaultDatabasePort {
+		t.Errorf("Expected default database port, got %s", cfg.Database.Port)
+	}
+	if cfg.Campaign.GivenAmount == nil || cfg.Campaign.GivenAmount.String() != "150" {
+		t.Errorf("Expected given amount to be 150, got %v", cfg.Campaign.GivenAmount)
+	}
+	if len(cfg.Campaign.Definitions.Campaigns) != 3 {
+		t.Errorf("Expected campaign definitions to be loaded from the file, got %d", len(cfg.Campaign.Definitions.Campaigns))
+	}
+}
+
+func TestLoad_FailsWithAllProblems(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("GIVEN_AMOUNT", "a lot")
+	t.Setenv("DB_PORT", "postgres")
+	t.Setenv("BASKET_MERGE_POLICY", "newest")
+	t.Setenv("STOCK_RESERVATION_TTL", "0s")
+	_, err := Load(nil)
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Expected invalid config error, got %v", err)
+	}
+	for _, problem := range []string{"GIVEN_AMOUNT", "DB_PORT", "BASKET_MERGE_POLICY", "STOCK_RESERVATION_TTL"} {
+		if !strings.Contains(err.Error(), problem) {
+			t.Errorf("Expected error to mention %s, got %v", problem, err)
+		}
+	}
+}
+
+func TestLoad_Currencies(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DEFAULT_CURRENCY", "eur")
+	t.Setenv("SUPPORTED_CURRENCIES", "try, eur")
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Expected config to be loaded, got %v", err)
+	}
+	if cfg.Currency.Default != "EUR" {
+		t.Errorf("Expected default currency to be EUR, got %s", cfg.Currency.Default)
+	}
+	if !cfg.Currency.IsSupported("TRY") || cfg.Currency.IsSupported("USD") {
+		t.Errorf("Expected only TRY and EUR to be supported, got %v", cfg.Currency.Supported)
+	}
+
+	t.Setenv("SUPPORTED_CURRENCIES", "TRY")
+	if _, err := Load(nil); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected unsupported default currency to be invalid, got %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package customer
+
+import (
+	"context"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/config"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/erdemcemal/basket-service/internal/models"
+	customerstore "github.com/erdemcemal/basket-service/internal/store/customer"
+	"github.com/shopspring/decimal"
+	log "github.com/siruspen/logrus"
+	"gorm.io/gorm"
+	"time"
+)
+
+var (
+	ErrGettingCustomer = errors.New("error getting customer")
+	ErrSavingCustomer  = errors.New("error saving customer")
+)
+
+// CustomerService - represents the customer service
+type CustomerService interface {
+	GetCustomer(ctx context.Context, userId string) (dto.CustomerDTO, error)
+	UpdateCustomer(ctx context.Context, userId string, update dto.UpdateCustomerDTO) (dto.CustomerDTO, error)
+}
+
+// ProfileProvider - provides the profiles the campaigns are targeted with
+type ProfileProvider interface {
+	GetProfile(ctx context.Context, userId string) (Profile, error)
+}
+
+// Service - represents the customer service implementation
+type Service struct {
+	store      customerstore.CustomerStore
+	tierMonths int
+	thresholds TierThresholds
+}
+
+// NewService - creates a new customer service with the given store and segment settings
+func NewService(store customerstore.CustomerStore, cfg config.CustomerConfig) *Service {
+	thresholds := TierThresholds{Silver: decimal.Zero, Gold: decimal.Zero}
+	if cfg.SilverTierAmount != nil {
+		thresholds.Silver = *cfg.SilverTierAmount
+	}
+	if cfg.GoldTierAmount != nil {
+		thresholds.Gold = *cfg.GoldTierAmount
+	}
+	return &Service{store: store, tierMonths: cfg.TierMonths, thresholds: thresholds}
+}
+
+// GetProfile - returns the profile of the given user with the tier calculated from the sales history
+func (s *Service) GetProfile(ctx context.Context, userId string) (Profile, error) {
+	storedProfile, err := s.getStoredProfile(ctx, userId)
+	if err != nil {
+		return Profile{}, err
+	}
+	orderCount, err := s.store.CountUserOrders(ctx, userId)
+	if err != nil {
+		return Profile{}, err
+	}
+	tierAmount, err := s.store.GetUserOrderAmount(ctx, userId, time.Now().AddDate(0, -s.tierMonths, 0))
+	if err != nil {
+		return Profile{}, err
+	}
+	return NewProfile(userId, storedProfile.VIP, orderCount, tierAmount, s.thresholds), nil
+}
+
+// GetCustomer - returns the profile and the segments of the given user
+func (s *Service) GetCustomer(ctx context.Context, userId string) (dto.CustomerDTO, error) {
+	profile, err := s.GetProfile(ctx, userId)
+	if err != nil {
+		log.Error(err)
+		return dto.CustomerDTO{}, ErrGettingCustomer
+	}
+	return fromProfile(profile), nil
+}
+
+// UpdateCustomer - updates the manual flags of the given user
+func (s *Service) UpdateCustomer(ctx context.Context, userId string, update dto.UpdateCustomerDTO) (dto.CustomerDTO, error) {
+	storedProfile, err := s.getStoredProfile(ctx, userId)
+	if err != nil {
+		log.Error(err)
+		return dto.CustomerDTO{}, ErrGettingCustomer
+	}
+	if update.VIP != nil {
+		storedProfile.VIP = *update.VIP
+	}
+	if err := s.store.SaveCustomerProfile(ctx, storedProfile); err != nil {
+		log.Error(err)
+		return dto.CustomerDTO{}, ErrSavingCustomer
+	}
+	return s.GetCustomer(ctx, userId)
+}
+
+// getStoredProfile - returns the stored profile of the given user, a new profile if the user has none
+func (s *Service) getStoredProfile(ctx context.Context, userId string) (models.CustomerProfile, error) {
+	storedProfile, err := s.store.GetCustomerProfile(ctx, userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.NewCustomerProfile(userId), nil
+		}
+		return models.CustomerProfile{}, err
+	}
+	return storedProfile, nil
+}
+
+// fromProfile - converts a customer profile to a customer dto
+func fromProfile(profile Profile) dto.CustomerDTO {
+	return dto.CustomerDTO{
+		UserID:         profile.UserID,
+		Tier:           string(profile.Tier),
+		VIP:            profile.VIP,
+		FirstTimeBuyer: profile.IsFirstTimeBuyer(),
+		OrderCount:     profile.OrderCount,
+		TierAmount:     profile.TierAmount,
+		Segments:       profile.Segments(),
+	}
+}
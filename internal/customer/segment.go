@@ -0,0 +1,77 @@
+package customer
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Tier - represents the loyalty tier of a customer calculated from the sales history
+type Tier string
+
+const (
+	TierBronze Tier = "bronze"
+	TierSilver Tier = "silver"
+	TierGold   Tier = "gold"
+)
+
+const (
+	// SegmentVIP - customers marked as vip by the crm team
+	SegmentVIP = "vip"
+	// SegmentFirstTimeBuyer - customers without any order
+	SegmentFirstTimeBuyer = "first_time_buyer"
+)
+
+// TierThresholds - represents the purchase amounts the silver and gold tiers are reached with
+type TierThresholds struct {
+	Silver decimal.Decimal
+	Gold   decimal.Decimal
+}
+
+// TierOf - returns the tier reached with the given purchase amount
+func (t TierThresholds) TierOf(amount decimal.Decimal) Tier {
+	switch {
+	case amount.GreaterThanOrEqual(t.Gold):
+		return TierGold
+	case amount.GreaterThanOrEqual(t.Silver):
+		return TierSilver
+	}
+	return TierBronze
+}
+
+// Profile - represents what is known about a customer to target campaigns
+type Profile struct {
+	UserID string
+	Tier   Tier
+	VIP    bool
+	// OrderCount - number of all orders of the customer
+	OrderCount int64
+	// TierAmount - purchase amount the tier is calculated from
+	TierAmount decimal.Decimal
+}
+
+// NewProfile - creates a customer profile with the tier reached with the given purchase amount
+func NewProfile(userId string, vip bool, orderCount int64, tierAmount decimal.Decimal, thresholds TierThresholds) Profile {
+	return Profile{
+		UserID:     userId,
+		Tier:       thresholds.TierOf(tierAmount),
+		VIP:        vip,
+		OrderCount: orderCount,
+		TierAmount: tierAmount,
+	}
+}
+
+// IsFirstTimeBuyer - checks if the customer has no order yet
+func (p Profile) IsFirstTimeBuyer() bool {
+	return p.OrderCount == 0
+}
+
+// Segments - returns the segments of the customer campaigns can target, the tier is always one of them
+func (p Profile) Segments() []string {
+	segments := []string{string(p.Tier)}
+	if p.VIP {
+		segments = append(segments, SegmentVIP)
+	}
+	if p.IsFirstTimeBuyer() {
+		segments = append(segments, SegmentFirstTimeBuyer)
+	}
+	return segments
+}
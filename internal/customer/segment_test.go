@@ -0,0 +1,28 @@
+package customer
+
+import (
+	"github.com/shopspring/decimal"
+	"reflect"
+	"testing"
+)
+
+func TestNewProfile_Segments(t *testing.T) {
+	thresholds := TierThresholds{Silver: decimal.New(1000, 0), Gold: decimal.New(5000, 0)}
+	tests := []struct {
+		name       string
+		vip        bool
+		orderCount int64
+		tierAmount decimal.Decimal
+		expected   []string
+	}{
+		{name: "first time buyer", orderCount: 0, tierAmount: decimal.Zero, expected: []string{"bronze", SegmentFirstTimeBuyer}},
+		{name: "silver customer", orderCount: 3, tierAmount: decimal.New(1000, 0), expected: []string{"silver"}},
+		{name: "gold vip customer", vip: true, orderCount: 12, tierAmount: decimal.New(7500, 0), expected: []string{"gold", SegmentVIP}},
+	}
+	for _, test := range tests {
+		profile := NewProfile("user", test.vip, test.orderCount, test.tierAmount, thresholds)
+		if segments := profile.Segments(); !reflect.DeepEqual(segments, test.expected) {
+			t.Errorf("Expected segments of %s to be %v, got %v", test.name, test.expected, segments)
+		}
+	}
+}
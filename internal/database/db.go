@@ -2,23 +2,23 @@ package database
 
 import (
 	"fmt"
+	"github.com/erdemcemal/basket-service/internal/config"
 	log "github.com/siruspen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"os"
 )
 
-// NewDatabase - creates a new database connection.
-func NewDatabase() (*gorm.DB, error) {
+// NewDatabase - creates a new database connection with the given settings.
+func NewDatabase(cfg config.DatabaseConfig) (*gorm.DB, error) {
 	log.Info("Setting up new database connection")
 	dsn := fmt.Sprintf(
 		"host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
-		os.Getenv("DB_HOST"),
-		os.Getenv("DB_PORT"),
-		os.Getenv("DB_USERNAME"),
-		os.Getenv("DB_TABLE"),
-		os.Getenv("DB_PASSWORD"),
-		os.Getenv("SSL_MODE"),
+		cfg.Host,
+		cfg.Port,
+		cfg.Username,
+		cfg.Name,
+		cfg.Password,
+		cfg.SSLMode,
 	)
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
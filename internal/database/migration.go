@@ -3,35 +3,63 @@ package database
 import (
 	"errors"
 	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/erdemcemal/basket-service/internal/tax"
 	"github.com/gofrs/uuid"
 	"github.com/shopspring/decimal"
 	log "github.com/siruspen/logrus"
 	"gorm.io/gorm"
 )
 
-const (
-	lowVatRate    = 1.0
-	normalVatRate = 8.0
-	highVatRate   = 18.0
-)
-
 // MigrateDB - migrate our database and creates our comment table
 func MigrateDB(db *gorm.DB) error {
-	if err := db.AutoMigrate(&models.Product{}, &models.ShoppingCart{}, &models.ShoppingCartItem{}, &models.SalesHistory{}, &models.SalesHistoryItem{}); err == nil && db.Migrator().HasTable(&models.Product{}) {
+	if err := db.AutoMigrate(&models.Product{}, &models.ShoppingCart{}, &models.ShoppingCartItem{}, &models.SalesHistory{}, &models.SalesHistoryItem{}, &models.Coupon{}, &models.CouponRedemption{}, &models.Campaign{}, &models.CampaignRedemption{}, &models.CustomerProfile{}, &models.ShippingMethod{}, &models.ProductPrice{}, &models.StockReservation{}); err == nil && db.Migrator().HasTable(&models.Product{}) {
 		if err := db.First(&models.Product{}).Error; errors.Is(err, gorm.ErrRecordNotFound) {
-			if err := db.Create(&models.Product{Base: models.Base{ID: uuid.Must(uuid.NewV4())}, Name: "IPhone 9", UnitPrice: decimal.New(549, 0), VatRate: normalVatRate, Quantity: 94}).Error; err != nil {
-				log.Error(err)
-				return err
+			// products are priced in euro as well, the unit price is the price in the base currency
+			products := []models.Product{
+				{Base: models.Base{ID: uuid.Must(uuid.NewV4())}, Name: "IPhone 9", UnitPrice: decimal.New(549, 0), TaxClass: tax.ClassReduced, Quantity: 94, Brand: "Apple", Categories: models.StringList{"phones"}, Weight: decimal.RequireFromString("0.4")},
+				{Base: models.Base{ID: uuid.Must(uuid.NewV4())}, Name: "MacBook Pro", UnitPrice: decimal.New(1749, 0), TaxClass: tax.ClassStandard, Quantity: 83, Brand: "Apple", Categories: models.StringList{"computers"}, Weight: decimal.RequireFromString("2.1")},
+				{Base: models.Base{ID: uuid.Must(uuid.NewV4())}, Name: "Key Holder", UnitPrice: decimal.New(30, 0), TaxClass: tax.ClassSuperReduced, Quantity: 54, Categories: models.StringList{"accessories"}, Weight: decimal.RequireFromString("0.1")},
 			}
-			if err := db.Create(&models.Product{Base: models.Base{ID: uuid.Must(uuid.NewV4())}, Name: "MacBook Pro", UnitPrice: decimal.New(1749, 0), VatRate: highVatRate, Quantity: 83}).Error; err != nil {
-				log.Error(err)
-				return err
+			euroPrices := []decimal.Decimal{decimal.New(19, 0), decimal.New(59, 0), decimal.RequireFromString("0.99")}
+			for i, product := range products {
+				product.Prices = []models.ProductPrice{models.NewProductPrice(product.ID, "EUR", euroPrices[i])}
+				if err := db.Create(&product).Error; err != nil {
+					log.Error(err)
+					return err
+				}
 			}
-			if err := db.Create(&models.Product{Base: models.Base{ID: uuid.Must(uuid.NewV4())}, Name: "Key Holder", UnitPrice: decimal.New(30, 0), VatRate: lowVatRate, Quantity: 54}).Error; err != nil {
+		}
+	}
+	if db.Migrator().HasTable(&models.Coupon{}) {
+		if err := db.First(&models.Coupon{}).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := db.Create(&models.Coupon{Base: models.Base{ID: uuid.Must(uuid.NewV4())}, Code: "WELCOME10", Type: models.CouponTypePercent, Value: decimal.New(10, 0), MaxRedemptionsPerUser: 1}).Error; err != nil {
 				log.Error(err)
 				return err
 			}
 		}
 	}
+	if db.Migrator().HasTable(&models.ShippingMethod{}) {
+		if err := db.First(&models.ShippingMethod{}).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			heavyWeight := decimal.New(5, 0)
+			standard := models.NewShippingMethod("standard", "Standard delivery", models.ShippingRates{
+				{MaxWeight: &heavyWeight, Price: decimal.New(30, 0)},
+				{Price: decimal.New(60, 0)},
+				{MaxWeight: &heavyWeight, Price: decimal.RequireFromString("4.90"), Currency: "EUR"},
+				{Price: decimal.RequireFromString("9.90"), Currency: "EUR"},
+			})
+			express := models.NewShippingMethod("express", "Express delivery", models.ShippingRates{
+				{MaxWeight: &heavyWeight, Price: decimal.New(75, 0)},
+				{Price: decimal.New(120, 0)},
+				{MaxWeight: &heavyWeight, Price: decimal.RequireFromString("12.90"), Currency: "EUR"},
+				{Price: decimal.RequireFromString("19.90"), Currency: "EUR"},
+			})
+			for _, method := range []models.ShippingMethod{standard, express} {
+				if err := db.Create(&method).Error; err != nil {
+					log.Error(err)
+					return err
+				}
+			}
+		}
+	}
 	return nil
 }
@@ -1,23 +1,60 @@
 package dto
 
-import "github.com/shopspring/decimal"
+import (
+	"github.com/erdemcemal/basket-service/internal/campaign"
+	"github.com/shopspring/decimal"
+	"time"
+)
 
 type ProductDTO struct {
-	ID        string          `json:"id"`
-	Name      string          `json:"name"`
-	UnitPrice decimal.Decimal `json:"price"`
-	VatRate   int32           `json:"vatRate"`
-	Quantity  int32           `json:"quantity"`
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	UnitPrice  decimal.Decimal `json:"price"`
+	Currency   string          `json:"currency"`
+	VatRate    decimal.Decimal `json:"vatRate"`
+	Quantity   int32           `json:"quantity"`
+	Brand      string          `json:"brand,omitempty"`
+	Categories []string        `json:"categories,omitempty"`
+	Tags       []string        `json:"tags,omitempty"`
+	Weight     decimal.Decimal `json:"weight"`
+	TaxClass   string          `json:"tax_class,omitempty"`
 }
 
 type ShoppingCartDTO struct {
-	ID            string                `json:"id"`
-	UserID        string                `json:"user_id"`
+	ID string `json:"id"`
+	// Version - increased on every update of the cart, the id and the version make up the ETag of the basket
+	Version int64  `json:"version"`
+	UserID  string `json:"user_id"`
+	// Currency - currency of the item prices and all totals of the cart
+	Currency      string                `json:"currency"`
 	Items         []ShoppingCartItemDTO `json:"items"`
 	TotalPrice    decimal.Decimal       `json:"total_price"`
 	TotalVat      decimal.Decimal       `json:"total_vat"`
 	TotalDiscount decimal.Decimal       `json:"total_discount"`
 	SubTotal      decimal.Decimal       `json:"sub_total"`
+	// AppliedCampaigns - campaigns making up the total discount
+	AppliedCampaigns []AppliedCampaignDTO `json:"applied_campaigns"`
+	CouponCode       string               `json:"coupon_code,omitempty"`
+	// PricesIncludeVat - item prices and the total price include the total vat, it is not added to the sub total
+	PricesIncludeVat bool `json:"prices_include_vat"`
+	// ShippingCost - price of the selected shipping method, ShippingDiscount is the part of the total discount given on it
+	ShippingMethod   string          `json:"shipping_method,omitempty"`
+	ShippingRegion   string          `json:"shipping_region,omitempty"`
+	ShippingCost     decimal.Decimal `json:"shipping_cost"`
+	ShippingDiscount decimal.Decimal `json:"shipping_discount"`
+	TotalWeight      decimal.Decimal `json:"total_weight"`
+}
+
+type AppliedCampaignDTO struct {
+	CampaignID string                   `json:"campaign_id"`
+	Label      string                   `json:"label"`
+	Amount     decimal.Decimal          `json:"amount"`
+	Items      []AppliedCampaignItemDTO `json:"items"`
+}
+
+type AppliedCampaignItemDTO struct {
+	ProductID string          `json:"product_id"`
+	Amount    decimal.Decimal `json:"amount"`
 }
 
 type ShoppingCartItemDTO struct {
@@ -25,8 +62,12 @@ type ShoppingCartItemDTO struct {
 	ProductID string          `json:"product_id"`
 	Quantity  int32           `json:"quantity"`
 	Price     decimal.Decimal `json:"price"`
-	VatRate   int32           `json:"vat_rate"`
+	VatRate   decimal.Decimal `json:"vat_rate"`
 	Name      string          `json:"name"`
+	Discount  decimal.Decimal `json:"discount"`
+	TaxClass  string          `json:"tax_class,omitempty"`
+	// VatAmount - vat of the item calculated on its price after the discount
+	VatAmount decimal.Decimal `json:"vat_amount"`
 }
 
 type AddItemToBasketDTO struct {
@@ -38,3 +79,84 @@ type UpdateItemInBasketDTO struct {
 	Quantity  int32  `json:"quantity" validate:"gte=1,required"`
 	ProductID string `json:"product_id" validate:"required"`
 }
+
+type ShippingMethodDTO struct {
+	Code     string          `json:"code"`
+	Name     string          `json:"name"`
+	Cost     decimal.Decimal `json:"cost"`
+	Currency string          `json:"currency"`
+}
+
+type UpdateProductStockDTO struct {
+	Quantity int32 `json:"quantity" validate:"gte=0"`
+}
+
+type SetBasketCurrencyDTO struct {
+	Currency string `json:"currency" validate:"required,len=3"`
+}
+
+type SelectShippingMethodDTO struct {
+	Method string `json:"method" validate:"required"`
+	// Region - region the basket is delivered to, the rates without a region are used if it is empty
+	Region string `json:"region,omitempty"`
+}
+
+type GuestBasketDTO struct {
+	// CartToken - opaque token the guest basket is used with in the "X-Cart-Token" header or the "cart_token" cookie
+	CartToken string          `json:"cart_token"`
+	Basket    ShoppingCartDTO `json:"basket"`
+}
+
+type MergeBasketDTO struct {
+	// Policy - how the quantities of the products in both baskets are combined, the configured policy is used if it is empty
+	Policy string `json:"policy,omitempty" validate:"omitempty,oneof=sum keep_newest cap_at_stock"`
+}
+
+type ApplyCouponDTO struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type SimulateCampaignsDTO struct {
+	Items []SimulatedItemDTO `json:"items" validate:"required,min=1,dive"`
+	// UserID - user whose purchase history is used, history overrides take precedence
+	UserID                string           `json:"user_id,omitempty" validate:"omitempty,uuid"`
+	MonthlyPurchaseAmount *decimal.Decimal `json:"monthly_purchase_amount,omitempty"`
+	// OrderCount - number of previous orders of the user in the lookback window of the campaigns
+	OrderCount *int32 `json:"order_count,omitempty" validate:"omitempty,min=0,max=10000"`
+	// LastOrdersAmount - total of the last orders of the user the every nth order campaigns are checked with
+	LastOrdersAmount *decimal.Decimal `json:"last_orders_amount,omitempty"`
+	// Segments - customer segments such as "gold", "vip" or "first_time_buyer"
+	Segments   []string `json:"segments,omitempty"`
+	CouponCode string   `json:"coupon_code,omitempty"`
+	// Currency - currency the cart is priced in, the currency selected by the client or the default currency if it is empty
+	Currency string `json:"currency,omitempty"`
+	// ShippingMethod and ShippingRegion - shipping the cart is simulated with, such as to preview a free shipping campaign
+	ShippingMethod string `json:"shipping_method,omitempty"`
+	ShippingRegion string `json:"shipping_region,omitempty"`
+	// At - time the campaigns are evaluated at, now if it is empty
+	At *time.Time `json:"at,omitempty"`
+	// Campaigns - campaign definitions previewed together with the current ones
+	Campaigns []campaign.Definition `json:"campaigns,omitempty"`
+}
+
+type SimulatedItemDTO struct {
+	ProductID string `json:"product_id" validate:"required"`
+	Quantity  int32  `json:"quantity" validate:"gte=1,required"`
+}
+
+type CampaignSimulationDTO struct {
+	Policy           string                 `json:"policy"`
+	TotalPrice       decimal.Decimal        `json:"total_price"`
+	TotalDiscount    decimal.Decimal        `json:"total_discount"`
+	ShippingCost     decimal.Decimal        `json:"shipping_cost"`
+	Campaigns        []SimulatedCampaignDTO `json:"campaigns"`
+	AppliedCampaigns []AppliedCampaignDTO   `json:"applied_campaigns"`
+}
+
+type SimulatedCampaignDTO struct {
+	CampaignID string                   `json:"campaign_id"`
+	Label      string                   `json:"label"`
+	Amount     decimal.Decimal          `json:"amount"`
+	Applied    bool                     `json:"applied"`
+	Items      []AppliedCampaignItemDTO `json:"items"`
+}
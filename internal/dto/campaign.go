@@ -0,0 +1,57 @@
+package dto
+
+import (
+	"github.com/erdemcemal/basket-service/internal/campaign"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+type CampaignDTO struct {
+	ID         string              `json:"id"`
+	Status     string              `json:"status"`
+	Definition campaign.Definition `json:"definition"`
+	// SpentAmount and RedemptionCount - total discount given and number of orders the campaign is applied on
+	SpentAmount     decimal.Decimal `json:"spent_amount"`
+	RedemptionCount int64           `json:"redemption_count"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+type CreateCampaignDTO struct {
+	Definition campaign.Definition `json:"definition"`
+	// Disabled - campaign is created disabled and is not applied until it is enabled
+	Disabled bool `json:"disabled"`
+}
+
+type DiscountTraceDTO struct {
+	UserID        string             `json:"user_id"`
+	Policy        string             `json:"policy"`
+	EvaluatedAt   time.Time          `json:"evaluated_at"`
+	CartAmount    decimal.Decimal    `json:"cart_amount"`
+	Facts         CustomerFactsDTO   `json:"facts"`
+	TotalDiscount decimal.Decimal    `json:"total_discount"`
+	Campaigns     []CampaignTraceDTO `json:"campaigns"`
+}
+
+type CustomerFactsDTO struct {
+	GivenAmount   decimal.Decimal `json:"given_amount"`
+	MonthlyAmount decimal.Decimal `json:"monthly_amount"`
+	// RecentOrderCount - orders of the user in the longest lookback window of the campaigns
+	RecentOrderCount    int              `json:"recent_order_count"`
+	Segments            []string         `json:"segments"`
+	CampaignRedemptions map[string]int64 `json:"campaign_redemptions"`
+}
+
+type CampaignTraceDTO struct {
+	CampaignID         string            `json:"campaign_id"`
+	Label              string            `json:"label"`
+	Priority           int               `json:"priority"`
+	ExclusivityGroup   string            `json:"exclusivity_group,omitempty"`
+	Active             bool              `json:"active"`
+	Inputs             map[string]string `json:"inputs"`
+	PreconditionPassed bool              `json:"precondition_passed"`
+	Amount             decimal.Decimal   `json:"amount"`
+	AppliedAmount      decimal.Decimal   `json:"applied_amount"`
+	Applied            bool              `json:"applied"`
+	Outcome            string            `json:"outcome"`
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "github.com/shopspring/decimal"
+
+type CustomerDTO struct {
+	UserID         string          `json:"user_id"`
+	Tier           string          `json:"tier"`
+	VIP            bool            `json:"vip"`
+	FirstTimeBuyer bool            `json:"first_time_buyer"`
+	OrderCount     int64           `json:"order_count"`
+	TierAmount     decimal.Decimal `json:"tier_amount"`
+	Segments       []string        `json:"segments"`
+}
+
+type UpdateCustomerDTO struct {
+	VIP *bool `json:"vip" validate:"required"`
+}
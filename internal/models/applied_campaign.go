@@ -0,0 +1,83 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"github.com/shopspring/decimal"
+)
+
+// AppliedCampaign - represents a campaign applied on a shopping cart with its discount allocated to the items.
+type AppliedCampaign struct {
+	CampaignID string                `json:"campaign_id"`
+	Label      string                `json:"label"`
+	Amount     decimal.Decimal       `json:"amount"`
+	Items      []AppliedCampaignItem `json:"items"`
+}
+
+// AppliedCampaignItem - represents the part of a campaign discount allocated to a shopping cart item.
+type AppliedCampaignItem struct {
+	ProductID string          `json:"product_id"`
+	Amount    decimal.Decimal `json:"amount"`
+}
+
+// AppliedCampaigns - represents the campaigns applied on a shopping cart, stored as a json column.
+type AppliedCampaigns []AppliedCampaign
+
+// Value - converts the applied campaigns to a json value for the database.
+func (a AppliedCampaigns) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan - reads the applied campaigns from a json value of the database.
+func (a *AppliedCampaigns) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	}
+	return errors.New("unsupported applied campaigns value")
+}
+
+// TotalAmount - returns the sum of the applied campaign discounts.
+func (a AppliedCampaigns) TotalAmount() decimal.Decimal {
+	total := decimal.Zero
+	for _, campaign := range a {
+		total = total.Add(campaign.Amount)
+	}
+	return total
+}
+
+// ItemDiscount - returns the sum of the campaign discounts allocated to the given product.
+func (a AppliedCampaigns) ItemDiscount(productId string) decimal.Decimal {
+	total := decimal.Zero
+	for _, campaign := range a {
+		for _, item := range campaign.Items {
+			if item.ProductID == productId {
+				total = total.Add(item.Amount)
+			}
+		}
+	}
+	return total
+}
+
+// Find - returns the applied campaign with the given campaign id.
+func (a AppliedCampaigns) Find(campaignId string) (AppliedCampaign, bool) {
+	for _, campaign := range a {
+		if campaign.CampaignID == campaignId {
+			return campaign, true
+		}
+	}
+	return AppliedCampaign{}, false
+}
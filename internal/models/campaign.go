@@ -0,0 +1,100 @@
+package models
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// CampaignStatus - represents whether a campaign is applied on baskets.
+type CampaignStatus string
+
+const (
+	// CampaignStatusActive - campaign is applied on baskets.
+	CampaignStatusActive CampaignStatus = "active"
+	// CampaignStatusDisabled - campaign is not applied until it is enabled again.
+	CampaignStatusDisabled CampaignStatus = "disabled"
+	// CampaignStatusArchived - campaign is not applied and can't be enabled anymore.
+	CampaignStatusArchived CampaignStatus = "archived"
+)
+
+// Campaign - represents a campaign managed by the operations with its definition.
+type Campaign struct {
+	Base
+	// Key - id of the campaign definition, results of the campaign are labelled with it
+	Key        string         `gorm:"uniqueIndex"`
+	Status     CampaignStatus `gorm:"index"`
+	Definition JSON           `gorm:"type:jsonb"`
+	// Budget - total discount the campaign can give, unlimited if it is empty
+	Budget *decimal.Decimal
+	// BudgetCurrency - currency of the budget and the spent amount, the base currency if it is empty
+	BudgetCurrency string
+	// MaxRedemptionsPerUser - how many orders of a user the campaign can be applied on, unlimited if it is zero
+	MaxRedemptionsPerUser int64
+	// SpentAmount and RedemptionCount - total discount given and number of orders the campaign is applied on
+	SpentAmount     decimal.Decimal
+	RedemptionCount int64
+}
+
+// CampaignRedemption - represents a campaign discount given on an order.
+type CampaignRedemption struct {
+	Base
+	CampaignKey    string `gorm:"index"`
+	UserID         string `gorm:"index"`
+	SalesHistoryID uint
+	Amount         decimal.Decimal
+}
+
+// NewCampaign - creates a new campaign with the given key, status and json definition.
+func NewCampaign(key string, status CampaignStatus, definition JSON) Campaign {
+	return Campaign{
+		Base:        Base{ID: uuid.Must(uuid.NewV4())},
+		Key:         key,
+		Status:      status,
+		Definition:  definition,
+		SpentAmount: decimal.Zero,
+	}
+}
+
+// RemainingBudget - returns the discount the campaign can still give, nil if the budget is unlimited.
+func (c Campaign) RemainingBudget() *decimal.Decimal {
+	if c.Budget == nil {
+		return nil
+	}
+	remaining := decimal.Max(c.Budget.Sub(c.SpentAmount), decimal.Zero)
+	return &remaining
+}
+
+// HasRedemptionsLeft - checks if a user who has redeemed the campaign the given times can redeem it again.
+func (c Campaign) HasRedemptionsLeft(userRedemptionCount int64) bool {
+	return c.MaxRedemptionsPerUser <= 0 || userRedemptionCount < c.MaxRedemptionsPerUser
+}
+
+// IsBudgetedIn - checks if the discounts in the given currency can be spent from the budget of the campaign.
+func (c Campaign) IsBudgetedIn(currency string) bool {
+	return c.Budget == nil || CurrencyOrBase(c.BudgetCurrency) == CurrencyOrBase(currency)
+}
+
+// HasBudgetFor - checks if the remaining budget of the campaign covers the given discount.
+func (c Campaign) HasBudgetFor(amount decimal.Decimal) bool {
+	return c.Budget == nil || c.SpentAmount.Add(amount).LessThanOrEqual(*c.Budget)
+}
+
+// NewCampaignRedemption - creates a new redemption of the campaign discount given to the user on the order.
+func NewCampaignRedemption(campaign Campaign, userId string, salesHistoryId uint, amount decimal.Decimal) CampaignRedemption {
+	return CampaignRedemption{
+		Base:           Base{ID: uuid.Must(uuid.NewV4())},
+		CampaignKey:    campaign.Key,
+		UserID:         userId,
+		SalesHistoryID: salesHistoryId,
+		Amount:         amount,
+	}
+}
+
+// IsValid - checks if the status is one of the known statuses.
+func (s CampaignStatus) IsValid() bool {
+	switch s {
+	case CampaignStatusActive, CampaignStatusDisabled, CampaignStatusArchived:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+// CouponType - represents how a coupon discount is calculated.
+type CouponType string
+
+const (
+	// CouponTypePercent - coupon value is a percentage of the eligible items.
+	CouponTypePercent CouponType = "percent"
+	// CouponTypeFixed - coupon value is a fixed amount taken from the eligible items.
+	CouponTypeFixed CouponType = "fixed"
+	// CouponTypeFreeItem - one unit of the cheapest eligible item is free.
+	CouponTypeFreeItem CouponType = "free_item"
+)
+
+// Coupon - represents a promo code a shopper can apply on the shopping cart.
+type Coupon struct {
+	Base
+	Code  string `gorm:"uniqueIndex"`
+	Type  CouponType
+	Value decimal.Decimal
+	// MaxRedemptions - how many times the coupon can be redeemed in total, zero means unlimited.
+	MaxRedemptions int32
+	// MaxRedemptionsPerUser - how many times a user can redeem the coupon, zero means unlimited.
+	MaxRedemptionsPerUser int32
+	RedemptionCount       int32
+	ValidFrom             *time.Time
+	ValidUntil            *time.Time
+	MinBasketAmount       decimal.Decimal
+	// Currency - currency of the fixed value and the min basket amount, the base currency if it is empty.
+	Currency string
+	// EligibleProductIDs - products the coupon applies to, empty means every product.
+	EligibleProductIDs StringList `gorm:"type:jsonb"`
+}
+
+// CouponRedemption - represents a coupon redeemed by a user with an order.
+type CouponRedemption struct {
+	Base
+	CouponID       uuid.UUID `gorm:"type:uuid;index"`
+	UserID         string    `gorm:"index"`
+	SalesHistoryID uint
+	Amount         decimal.Decimal
+}
+
+// NormalizeCouponCode - returns the coupon code in the form it is stored.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// AppliesIn - checks if the coupon can be applied on a shopping cart in the given currency,
+// coupons with amounts, such as fixed coupons and coupons with a min basket amount, are applied only in their currency.
+func (c Coupon) AppliesIn(currency string) bool {
+	if c.Type != CouponTypeFixed && c.MinBasketAmount.IsZero() {
+		return true
+	}
+	return CurrencyOrBase(c.Currency) == CurrencyOrBase(currency)
+}
+
+// CampaignID - returns the id of the campaign the coupon discount is applied with.
+func (c Coupon) CampaignID() string {
+	return "coupon:" + c.Code
+}
+
+// IsValidAt - checks if the coupon validity window contains the given time.
+func (c Coupon) IsValidAt(now time.Time) bool {
+	if c.ValidFrom != nil && now.Before(*c.ValidFrom) {
+		return false
+	}
+	if c.ValidUntil != nil && !now.Before(*c.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+// IsEligible - checks if the coupon applies to the given product.
+func (c Coupon) IsEligible(productId string) bool {
+	return len(c.EligibleProductIDs) == 0 || c.EligibleProductIDs.Contains(productId)
+}
+
+// HasRedemptionsLeft - checks if the coupon can be redeemed again by a user who has redeemed it the given times.
+func (c Coupon) HasRedemptionsLeft(userRedemptionCount int64) bool {
+	if c.MaxRedemptions > 0 && c.RedemptionCount >= c.MaxRedemptions {
+		return false
+	}
+	if c.MaxRedemptionsPerUser > 0 && userRedemptionCount >= int64(c.MaxRedemptionsPerUser) {
+		return false
+	}
+	return true
+}
+
+// NewCouponRedemption - creates a new coupon redemption for the given user and order.
+func NewCouponRedemption(coupon Coupon, userId string, salesHistoryId uint, amount decimal.Decimal) CouponRedemption {
+	return CouponRedemption{
+		Base:           Base{ID: uuid.Must(uuid.NewV4())},
+		CouponID:       coupon.ID,
+		UserID:         userId,
+		SalesHistoryID: salesHistoryId,
+		Amount:         amount,
+	}
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+// BaseCurrency - is the currency of the amounts stored without a currency, such as the unit prices of the products.
+const BaseCurrency = "TRY"
+
+// defaultCurrencyPrecision - decimal places of the currencies which are not listed in currency precisions.
+const defaultCurrencyPrecision = 2
+
+// currencyPrecisions - decimal places of the currencies whose minor unit is not the cent.
+var currencyPrecisions = map[string]int32{
+	"JPY": 0,
+	"KRW": 0,
+	"BHD": 3,
+	"KWD": 3,
+	"OMR": 3,
+}
+
+// ProductPrice - represents the unit price of a product in a currency of its price list.
+type ProductPrice struct {
+	Base
+	ProductID uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_product_price_currency"`
+	Currency  string    `gorm:"uniqueIndex:idx_product_price_currency"`
+	UnitPrice decimal.Decimal
+}
+
+// NewProductPrice - creates a new price of the given product in the given currency.
+func NewProductPrice(productID uuid.UUID, currency string, unitPrice decimal.Decimal) ProductPrice {
+	return ProductPrice{
+		Base:      Base{ID: uuid.Must(uuid.NewV4())},
+		ProductID: productID,
+		Currency:  NormalizeCurrency(currency),
+		UnitPrice: unitPrice,
+	}
+}
+
+// NormalizeCurrency - returns the currency code in the form it is stored.
+func NormalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+// CurrencyOrBase - returns the currency, the base currency if it is empty.
+func CurrencyOrBase(currency string) string {
+	if currency == "" {
+		return BaseCurrency
+	}
+	return currency
+}
+
+// CurrencyPrecision - returns the number of decimal places amounts of the currency are rounded to.
+func CurrencyPrecision(currency string) int32 {
+	if precision, ok := currencyPrecisions[CurrencyOrBase(currency)]; ok {
+		return precision
+	}
+	return defaultCurrencyPrecision
+}
+
+// PriceIn - returns the unit price of the product in the given currency from its price list,
+// the unit price of the product is its price in the base currency.
+func (p Product) PriceIn(currency string) (decimal.Decimal, bool) {
+	currency = CurrencyOrBase(currency)
+	for _, price := range p.Prices {
+		if price.Currency == currency {
+			return price.UnitPrice, true
+		}
+	}
+	if currency == BaseCurrency {
+		return p.UnitPrice, true
+	}
+	return decimal.Zero, false
+}
@@ -0,0 +1,19 @@
+package models
+
+import "github.com/gofrs/uuid"
+
+// CustomerProfile - represents the customer settings which are not derived from the sales history.
+type CustomerProfile struct {
+	Base
+	UserID string `gorm:"uniqueIndex"`
+	// VIP - customer is marked as vip by the crm team
+	VIP bool
+}
+
+// NewCustomerProfile - creates a new customer profile for the given user.
+func NewCustomerProfile(userId string) CustomerProfile {
+	return CustomerProfile{
+		Base:   Base{ID: uuid.Must(uuid.NewV4())},
+		UserID: userId,
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+)
+
+// JSON - represents a raw json document stored as a json column.
+type JSON []byte
+
+// Value - returns the json document as a value for the database.
+func (j JSON) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return "null", nil
+	}
+	return string(j), nil
+}
+
+// Scan - reads the json document from a value of the database.
+func (j *JSON) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+		return nil
+	case []byte:
+		*j = append(JSON{}, v...)
+		return nil
+	case string:
+		*j = JSON(v)
+		return nil
+	}
+	return errors.New("unsupported json value")
+}
+
+// MarshalJSON - returns the json document as it is.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
+
+// UnmarshalJSON - keeps a copy of the json document.
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	*j = append(JSON{}, data...)
+	return nil
+}
@@ -0,0 +1,39 @@
+package models
+
+// MergePolicy - decides the quantity of a product which is both in the guest basket and in the basket of the user it is merged into
+type MergePolicy string
+
+const (
+	// MergeSumQuantities - the quantities of the baskets are added up
+	MergeSumQuantities MergePolicy = "sum"
+	// MergeKeepNewest - the quantity of the basket the item is changed in last is kept
+	MergeKeepNewest MergePolicy = "keep_newest"
+	// MergeCapAtStock - the quantities are added up but not above the stock of the product, items out of stock are not merged
+	MergeCapAtStock MergePolicy = "cap_at_stock"
+)
+
+// IsValid - checks if the merge policy is one of the known policies
+func (p MergePolicy) IsValid() bool {
+	switch p {
+	case MergeSumQuantities, MergeKeepNewest, MergeCapAtStock:
+		return true
+	}
+	return false
+}
+
+// MergedQuantity - returns the quantity of a product with the given quantities in the user and the guest baskets and its stock,
+// userNewer tells if the item is changed in the basket of the user after the guest basket
+func (p MergePolicy) MergedQuantity(userQuantity int32, guestQuantity int32, userNewer bool, stock int32) int32 {
+	switch p {
+	case MergeKeepNewest:
+		if userNewer {
+			return userQuantity
+		}
+		return guestQuantity
+	case MergeCapAtStock:
+		if userQuantity+guestQuantity > stock {
+			return stock
+		}
+	}
+	return userQuantity + guestQuantity
+}
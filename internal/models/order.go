@@ -9,11 +9,22 @@ import (
 type SalesHistory struct {
 	gorm.Model
 	SalesHistoryItems []SalesHistoryItem
-	UserID            string
-	TotalPrice        decimal.Decimal
-	TotalVat          decimal.Decimal
-	TotalDiscount     decimal.Decimal
-	SubTotal          decimal.Decimal
+	UserID            string `gorm:"index;uniqueIndex:idx_sales_histories_user_order_sequence"`
+	// OrderSequence - number of the order among all orders of the user, starting from 1
+	OrderSequence int64 `gorm:"uniqueIndex:idx_sales_histories_user_order_sequence"`
+	TotalPrice    decimal.Decimal
+	TotalVat      decimal.Decimal
+	TotalDiscount decimal.Decimal
+	SubTotal      decimal.Decimal
+	// Currency - currency of the prices and the totals of the order
+	Currency string
+	// PricesIncludeVat - prices of the order include vat, the total vat is a part of the total price
+	PricesIncludeVat bool
+	// ShippingMethod, ShippingRegion, ShippingCost and ShippingDiscount - delivery of the order, the shipping discount is a part of the total discount
+	ShippingMethod   string
+	ShippingRegion   string
+	ShippingCost     decimal.Decimal
+	ShippingDiscount decimal.Decimal
 }
 
 // SalesHistoryItem represents a sales history item.
@@ -22,6 +33,11 @@ type SalesHistoryItem struct {
 	ProductID      string
 	Quantity       int32
 	SalesHistoryID uint
+	// Price, Discount, VatRate and VatAmount - pricing of the item at checkout, vat is calculated on the price after the discount
+	Price     decimal.Decimal
+	Discount  decimal.Decimal
+	VatRate   decimal.Decimal
+	VatAmount decimal.Decimal
 }
 
 // NewSalesHistory - creates a new sales history from a shopping cart.
@@ -32,6 +48,12 @@ func NewSalesHistory(cart ShoppingCart) SalesHistory {
 		TotalVat:          cart.TotalVat,
 		TotalDiscount:     cart.TotalDiscount,
 		SubTotal:          cart.SubTotal,
+		Currency:          CurrencyOrBase(cart.Currency),
+		PricesIncludeVat:  cart.PricesIncludeVat,
+		ShippingMethod:    cart.ShippingMethod,
+		ShippingRegion:    cart.ShippingRegion,
+		ShippingCost:      cart.ShippingCost,
+		ShippingDiscount:  cart.ShippingDiscount,
 		SalesHistoryItems: fromShoppingCartItems(cart.Items),
 	}
 }
@@ -40,7 +62,12 @@ func NewSalesHistory(cart ShoppingCart) SalesHistory {
 func fromShoppingCartItems(items []ShoppingCartItem) []SalesHistoryItem {
 	var orderItems []SalesHistoryItem
 	for _, item := range items {
-		orderItems = append(orderItems, newSalesHistoryItem(item.ProductID.String(), item.Quantity))
+		orderItem := newSalesHistoryItem(item.ProductID.String(), item.Quantity)
+		orderItem.Price = item.Price
+		orderItem.Discount = item.Discount
+		orderItem.VatRate = item.VatRate
+		orderItem.VatAmount = item.VatAmount
+		orderItems = append(orderItems, orderItem)
 	}
 	return orderItems
 }
@@ -5,8 +5,19 @@ import "github.com/shopspring/decimal"
 // Product - represents a product.
 type Product struct {
 	Base
-	Name      string
+	Name string
+	// UnitPrice - price of the product in the base currency
 	UnitPrice decimal.Decimal
-	VatRate   int32
-	Quantity  int32
+	// Prices - price list of the product in the other currencies
+	Prices []ProductPrice `gorm:"foreignKey:ProductID"`
+	// VatRate - vat percentage of the products without a tax class
+	VatRate decimal.Decimal
+	// TaxClass - tax class the vat rate of the product is resolved with, such as "standard" or "reduced"
+	TaxClass   string
+	Quantity   int32
+	Brand      string
+	Categories StringList `gorm:"type:jsonb"`
+	Tags       StringList `gorm:"type:jsonb"`
+	// Weight - shipping weight of a unit in kilograms
+	Weight decimal.Decimal
 }
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ShippingLineID - is the product id of the campaign discount lines given on the shipping cost instead of an item.
+const ShippingLineID = "shipping"
+
+// ShippingMethod - represents a delivery option the shopper can select for the shopping cart.
+type ShippingMethod struct {
+	Base
+	Code   string `gorm:"uniqueIndex"`
+	Name   string
+	Active bool
+	// Rates - prices of the method, the first rate matching the region, the weight and the amount of the cart is used.
+	Rates ShippingRates `gorm:"type:jsonb"`
+}
+
+// ShippingRate - represents the price of a shipping method for the carts in a region, weight range and amount range.
+type ShippingRate struct {
+	// Region - region the rate is used in, every region if it is empty.
+	Region string `json:"region,omitempty"`
+	// MinWeight and MaxWeight - total weight of the cart in kilograms, unlimited if they are not set.
+	MinWeight *decimal.Decimal `json:"min_weight,omitempty"`
+	MaxWeight *decimal.Decimal `json:"max_weight,omitempty"`
+	// MinAmount - total price of the cart the rate is used from, such as free delivery over an amount.
+	MinAmount *decimal.Decimal `json:"min_amount,omitempty"`
+	Price     decimal.Decimal  `json:"price"`
+	// Currency - currency of the price and the min amount, carts in other currencies don't use the rate, the base currency if it is empty.
+	Currency string `json:"currency,omitempty"`
+}
+
+// ShippingRates - represents the rates of a shipping method, stored as a json column.
+type ShippingRates []ShippingRate
+
+// NewShippingMethod - creates a new active shipping method with the given code, name and rates.
+func NewShippingMethod(code string, name string, rates ShippingRates) ShippingMethod {
+	return ShippingMethod{
+		Base:   Base{ID: uuid.Must(uuid.NewV4())},
+		Code:   code,
+		Name:   name,
+		Active: true,
+		Rates:  rates,
+	}
+}
+
+// Quote - returns the shipping cost of a cart with the given total weight and total price in the given currency and region,
+// false if no rate of the method matches the cart.
+func (m ShippingMethod) Quote(region string, weight decimal.Decimal, amount decimal.Decimal, currency string) (decimal.Decimal, bool) {
+	for _, rate := range m.Rates {
+		if CurrencyOrBase(rate.Currency) == CurrencyOrBase(currency) && rate.matches(region, weight, amount) {
+			return rate.Price, true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// matches - checks if the rate is used for a cart with the given total weight and total price in the given region.
+func (r ShippingRate) matches(region string, weight decimal.Decimal, amount decimal.Decimal) bool {
+	if r.Region != "" && r.Region != region {
+		return false
+	}
+	if r.MinWeight != nil && weight.LessThan(*r.MinWeight) {
+		return false
+	}
+	if r.MaxWeight != nil && weight.GreaterThan(*r.MaxWeight) {
+		return false
+	}
+	if r.MinAmount != nil && amount.LessThan(*r.MinAmount) {
+		return false
+	}
+	return true
+}
+
+// Value - converts the shipping rates to a json value for the database.
+func (r ShippingRates) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan - reads the shipping rates from a json value of the database.
+func (r *ShippingRates) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	}
+	return errors.New("unsupported shipping rates value")
+}
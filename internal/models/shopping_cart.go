@@ -8,12 +8,29 @@ import (
 // ShoppingCart - represents a shopping cart.
 type ShoppingCart struct {
 	Base
+	// Version - increased on every update of the cart, the cart is only updated if it is not changed since it is read
+	Version       int64              `json:"version" gorm:"not null;default:1"`
 	UserID        string             `json:"user_id"`
 	Items         []ShoppingCartItem `json:"items"`
 	TotalPrice    decimal.Decimal    `json:"total_price"`
 	TotalVat      decimal.Decimal    `json:"total_vat"`
 	TotalDiscount decimal.Decimal    `json:"total_discount"`
 	SubTotal      decimal.Decimal    `json:"total_after_vat"`
+	// AppliedCampaigns - breakdown of the total discount by campaign
+	AppliedCampaigns AppliedCampaigns `json:"applied_campaigns" gorm:"type:jsonb"`
+	// CouponCode - promo code applied by the shopper
+	CouponCode string `json:"coupon_code"`
+	// Currency - currency of the item prices and the totals, prices of the items are taken from the price list of their product in it
+	Currency string `json:"currency"`
+	// PricesIncludeVat - item prices include vat, so vat is a part of the total price instead of being added on it
+	PricesIncludeVat bool `json:"prices_include_vat"`
+	// ShippingMethod and ShippingRegion - delivery option selected by the shopper and the region it is delivered to
+	ShippingMethod string `json:"shipping_method"`
+	ShippingRegion string `json:"shipping_region"`
+	// ShippingCost - price of the selected shipping method for the cart
+	ShippingCost decimal.Decimal `json:"shipping_cost"`
+	// ShippingDiscount - part of the total discount given on the shipping cost
+	ShippingDiscount decimal.Decimal `json:"shipping_discount"`
 }
 
 // NewShoppingCart - creates a new shopping cart from a user ID.
@@ -23,12 +40,16 @@ func NewShoppingCart(userID string) ShoppingCart {
 		Base: Base{
 			ID: cartId,
 		},
-		UserID:        userID,
-		Items:         []ShoppingCartItem{},
-		TotalPrice:    decimal.Zero,
-		TotalDiscount: decimal.Zero,
-		TotalVat:      decimal.Zero,
-		SubTotal:      decimal.Zero,
+		Version:          1,
+		UserID:           userID,
+		Items:            []ShoppingCartItem{},
+		TotalPrice:       decimal.Zero,
+		TotalDiscount:    decimal.Zero,
+		TotalVat:         decimal.Zero,
+		SubTotal:         decimal.Zero,
+		ShippingCost:     decimal.Zero,
+		ShippingDiscount: decimal.Zero,
+		AppliedCampaigns: AppliedCampaigns{},
 	}
 }
 
@@ -82,16 +103,49 @@ func (s *ShoppingCart) GetCartItemByProductId(productId string) (ShoppingCartIte
 
 // CalculateTotalPrice - recalculates the total price of the shopping cart.
 func (s *ShoppingCart) CalculateTotalPrice() {
-	// calculate total price with vat rate and quantity
+	// vat of every item is calculated on its price after the discount allocated to it
 	totalVat := decimal.Zero
 	totalPrice := decimal.Zero
-	for _, item := range s.Items {
-		totalPrice = totalPrice.Add(item.Price.Mul(decimal.NewFromInt32(item.Quantity)))
-		totalVat = totalVat.Add(item.Price.Mul(decimal.NewFromInt32(item.Quantity)).Mul(decimal.NewFromInt32(item.VatRate)).Div(decimal.NewFromInt32(100)))
+	for i, item := range s.Items {
+		totalPrice = totalPrice.Add(item.LineAmount())
+		s.Items[i].VatAmount = item.CalculateVat(s.PricesIncludeVat, CurrencyPrecision(s.Currency))
+		totalVat = totalVat.Add(s.Items[i].VatAmount)
 	}
 	s.TotalPrice = totalPrice
 	s.TotalVat = totalVat
-	s.SubTotal = decimal.Sum(totalPrice, totalVat).Sub(s.TotalDiscount)
+	s.SubTotal = decimal.Sum(totalPrice, s.ShippingCost).Sub(s.TotalDiscount)
+	if !s.PricesIncludeVat {
+		s.SubTotal = s.SubTotal.Add(totalVat)
+	}
+}
+
+// TotalWeight - returns the shipping weight of the shopping cart.
+func (s *ShoppingCart) TotalWeight() decimal.Decimal {
+	totalWeight := decimal.Zero
+	for _, item := range s.Items {
+		totalWeight = totalWeight.Add(item.Weight.Mul(decimal.NewFromInt32(item.Quantity)))
+	}
+	return totalWeight
+}
+
+// AmountWithShipping - returns the total price of the shopping cart with the shipping cost.
+func (s *ShoppingCart) AmountWithShipping() decimal.Decimal {
+	return s.TotalPrice.Add(s.ShippingCost)
+}
+
+// SelectShipping - sets the shipping method and region of the shopping cart with the shipping cost and recalculates the total price.
+func (s *ShoppingCart) SelectShipping(method string, region string, cost decimal.Decimal) {
+	s.ShippingMethod = method
+	s.ShippingRegion = region
+	s.ShippingCost = cost
+	s.CalculateTotalPrice()
+}
+
+// ClearShipping - removes the shipping method of the shopping cart, the region is kept for the next selection.
+func (s *ShoppingCart) ClearShipping() {
+	s.ShippingMethod = ""
+	s.ShippingCost = decimal.Zero
+	s.CalculateTotalPrice()
 }
 
 // UpdateItemQuantity - updates the quantity of an item in the shopping cart and recalculates the total price.
@@ -105,8 +159,23 @@ func (s *ShoppingCart) UpdateItemQuantity(productId string, newQuantity int32) {
 	}
 }
 
-// ApplyDiscount - applies a discount to the shopping cart.
-func (s *ShoppingCart) ApplyDiscount(discount decimal.Decimal) {
-	s.TotalDiscount = discount
+// ApplyCoupon - sets the coupon code of the shopping cart, discounts are recalculated with the campaigns.
+func (s *ShoppingCart) ApplyCoupon(code string) {
+	s.CouponCode = code
+}
+
+// RemoveCoupon - removes the coupon code from the shopping cart.
+func (s *ShoppingCart) RemoveCoupon() {
+	s.CouponCode = ""
+}
+
+// ApplyCampaigns - applies the campaigns to the shopping cart, allocates their discounts to the items and recalculates the total price.
+func (s *ShoppingCart) ApplyCampaigns(campaigns AppliedCampaigns) {
+	s.AppliedCampaigns = campaigns
+	s.TotalDiscount = campaigns.TotalAmount()
+	s.ShippingDiscount = campaigns.ItemDiscount(ShippingLineID)
+	for i, item := range s.Items {
+		s.Items[i].Discount = campaigns.ItemDiscount(item.ProductID.String())
+	}
 	s.CalculateTotalPrice()
 }
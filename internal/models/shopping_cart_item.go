@@ -3,21 +3,34 @@ package models
 import (
 	"github.com/gofrs/uuid"
 	"github.com/shopspring/decimal"
+	"time"
 )
 
 // ShoppingCartItem - represents a shopping cart item.
 type ShoppingCartItem struct {
 	Base
-	ProductID      uuid.UUID       `json:"product_id"`
-	ProductName    string          `json:"product_name"`
-	Quantity       int32           `json:"quantity"`
-	Price          decimal.Decimal `json:"price"`
-	VatRate        int32           `json:"vat_rate"`
+	ProductID   uuid.UUID       `json:"product_id"`
+	ProductName string          `json:"product_name"`
+	Quantity    int32           `json:"quantity"`
+	Price       decimal.Decimal `json:"price"`
+	VatRate     decimal.Decimal `json:"vat_rate"`
+	// TaxClass - tax class the vat rate of the item is resolved with, the vat rate is kept if it is empty
+	TaxClass string `json:"tax_class"`
+	// VatAmount - vat of the item calculated on its price after the discount
+	VatAmount      decimal.Decimal `json:"vat_amount"`
 	ShoppingCartID string          `json:"shopping_cart_id"`
+	// Brand, Categories and Tags - product details campaigns select the item with
+	Brand      string     `json:"brand"`
+	Categories StringList `json:"categories" gorm:"type:jsonb"`
+	Tags       StringList `json:"tags" gorm:"type:jsonb"`
+	// Weight - shipping weight of a unit in kilograms
+	Weight decimal.Decimal `json:"weight"`
+	// Discount - part of the cart discount allocated to the item
+	Discount decimal.Decimal `json:"discount"`
 }
 
 // NewShoppingCartItem - creates a new shopping cart item from a product ID and quantity and shopping cart ID and vat rate.
-func NewShoppingCartItem(productID uuid.UUID, productName string, quantity int32, price decimal.Decimal, vatRate int32, shoppingCartID string) ShoppingCartItem {
+func NewShoppingCartItem(productID uuid.UUID, productName string, quantity int32, price decimal.Decimal, vatRate decimal.Decimal, shoppingCartID string) ShoppingCartItem {
 	cartItemId := uuid.Must(uuid.NewV4())
 	return ShoppingCartItem{
 		Base: Base{
@@ -29,5 +42,41 @@ func NewShoppingCartItem(productID uuid.UUID, productName string, quantity int32
 		Price:          price,
 		VatRate:        vatRate,
 		ShoppingCartID: shoppingCartID,
+		Discount:       decimal.Zero,
 	}
 }
+
+// NewShoppingCartItemFromProduct - creates a new shopping cart item from a product with its details and quantity and shopping cart ID.
+func NewShoppingCartItemFromProduct(product Product, quantity int32, shoppingCartID string) ShoppingCartItem {
+	item := NewShoppingCartItem(product.ID, product.Name, quantity, product.UnitPrice, product.VatRate, shoppingCartID)
+	item.Brand = product.Brand
+	item.Categories = product.Categories
+	item.Tags = product.Tags
+	item.Weight = product.Weight
+	item.TaxClass = product.TaxClass
+	return item
+}
+
+// LineAmount - returns the price of the item for its quantity.
+func (i ShoppingCartItem) LineAmount() decimal.Decimal {
+	return i.Price.Mul(decimal.NewFromInt32(i.Quantity))
+}
+
+// CalculateVat - returns the vat of the item on its line amount after the discount rounded to the given precision of the currency,
+// vat is taken out of the amount if the prices include vat and it is added on the amount otherwise.
+func (i ShoppingCartItem) CalculateVat(pricesIncludeVat bool, precision int32) decimal.Decimal {
+	taxableAmount := decimal.Max(i.LineAmount().Sub(i.Discount), decimal.Zero)
+	rate := i.VatRate
+	if pricesIncludeVat {
+		return taxableAmount.Mul(rate).Div(rate.Add(decimal.NewFromInt32(100))).Round(precision)
+	}
+	return taxableAmount.Mul(rate).Div(decimal.NewFromInt32(100)).Round(precision)
+}
+
+// ChangedAt - returns the last time the item is added or updated.
+func (i ShoppingCartItem) ChangedAt() time.Time {
+	if i.UpdatedAt.After(i.CreatedAt) {
+		return i.UpdatedAt
+	}
+	return i.CreatedAt
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// StockReservation - represents a quantity of a product held for a shopping cart until it expires,
+// a cart has one reservation of every product in it.
+type StockReservation struct {
+	Base
+	ShoppingCartID string    `gorm:"uniqueIndex:idx_stock_reservation_cart_product"`
+	ProductID      uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_stock_reservation_cart_product;index"`
+	Quantity       int32
+	// ExpiresAt - the reserved quantity is available for the other carts again after the time
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// NewStockReservation - creates a new reservation of the given quantity of the product for the shopping cart until the given time.
+func NewStockReservation(shoppingCartId string, productId uuid.UUID, quantity int32, expiresAt time.Time) StockReservation {
+	return StockReservation{
+		Base:           Base{ID: uuid.Must(uuid.NewV4())},
+		ShoppingCartID: shoppingCartId,
+		ProductID:      productId,
+		Quantity:       quantity,
+		ExpiresAt:      expiresAt,
+	}
+}
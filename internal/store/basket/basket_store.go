@@ -4,8 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/erdemcemal/basket-service/internal/config"
 	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sort"
 	"time"
 )
 
@@ -13,27 +17,50 @@ import (
 type BasketStore interface {
 	GetProducts(ctx context.Context) ([]models.Product, error)
 	GetProductById(ctx context.Context, id string) (models.Product, error)
+	UpdateProductStock(ctx context.Context, id string, quantity int32) (models.Product, error)
+	GetReservedStock(ctx context.Context) (map[string]int32, error)
+	AvailableStock(ctx context.Context, productId string, exceptCartIds ...string) (int32, error)
+	ReleaseStock(ctx context.Context, cartId string, productId string) error
+	ReleaseExpiredReservations(ctx context.Context) (int64, error)
 	GetBasket(ctx context.Context, userId string) (models.ShoppingCart, error)
-	UpdateBasket(ctx context.Context, userId string, newCart models.ShoppingCart) error
-	RemoveItemFromBasket(ctx context.Context, cartItem models.ShoppingCartItem, newCart models.ShoppingCart) error
+	GetBasketByUserId(ctx context.Context, userId string) (models.ShoppingCart, error)
+	UpdateBasket(ctx context.Context, cart *models.ShoppingCart) error
+	UpdateBasketWithReservation(ctx context.Context, cart *models.ShoppingCart, productId string, quantity int32) error
+	RemoveItemFromBasket(ctx context.Context, cartItem models.ShoppingCartItem, cart *models.ShoppingCart) error
+	MergeBasket(ctx context.Context, cart *models.ShoppingCart, guestCart models.ShoppingCart, reservations map[string]int32) error
 	CheckoutBasket(ctx context.Context, cart models.ShoppingCart) error
-	GetUserMonthlyOrderAmount(ctx context.Context, userId string) (float64, error)
-	GetEveryFourthOrderAmount(ctx context.Context) (float64, error)
+	GetUserMonthlyOrderAmount(ctx context.Context, userId string, currency string) (decimal.Decimal, error)
+	GetUserOrderCount(ctx context.Context, userId string, since time.Time) (int64, error)
+	GetUserRecentOrders(ctx context.Context, userId string, since time.Time) ([]models.SalesHistory, error)
+	GetCouponByCode(ctx context.Context, code string) (models.Coupon, error)
+	CountUserCouponRedemptions(ctx context.Context, couponId string, userId string) (int64, error)
+	GetShippingMethods(ctx context.Context) ([]models.ShippingMethod, error)
+	GetShippingMethodByCode(ctx context.Context, code string) (models.ShippingMethod, error)
 }
 
+var (
+	ErrCouponRedemptionLimitReached   = errors.New("coupon redemption limit reached")
+	ErrCampaignRedemptionLimitReached = errors.New("campaign redemption limit reached")
+	ErrCampaignBudgetExhausted        = errors.New("campaign budget exhausted")
+	ErrCampaignBudgetCurrency         = errors.New("campaign budget is in another currency")
+	ErrBasketVersionConflict          = errors.New("basket is updated by another request since it is read")
+	ErrStockNotAvailable              = errors.New("stock of the product is not available")
+)
+
 type basketStore struct {
-	db *gorm.DB
+	db  *gorm.DB
+	cfg config.StoreConfig
 }
 
-// NewBasketStore - creates a new basket store instance with the given database connection
-func NewBasketStore(db *gorm.DB) BasketStore {
-	return &basketStore{db}
+// NewBasketStore - creates a new basket store instance with the given database connection and settings
+func NewBasketStore(db *gorm.DB, cfg config.StoreConfig) BasketStore {
+	return &basketStore{db: db, cfg: cfg}
 }
 
 // GetProducts - returns all products in the database s
 func (bs *basketStore) GetProducts(ctx context.Context) ([]models.Product, error) {
 	var products []models.Product
-	if result := bs.db.WithContext(ctx).Find(&products); result.Error != nil {
+	if result := bs.db.WithContext(ctx).Preload("Prices").Find(&products); result.Error != nil {
 		return nil, result.Error
 	}
 	return products, nil
@@ -42,12 +69,106 @@ func (bs *basketStore) GetProducts(ctx context.Context) ([]models.Product, error
 // GetProductById - returns a product with the given id
 func (bs *basketStore) GetProductById(ctx context.Context, id string) (models.Product, error) {
 	var product models.Product
-	if result := bs.db.WithContext(ctx).Where("id = ?", id).First(&product); result.Error != nil {
+	if result := bs.db.WithContext(ctx).Preload("Prices").Where("id = ?", id).First(&product); result.Error != nil {
 		return models.Product{}, result.Error
 	}
 	return product, nil
 }
 
+// UpdateProductStock - sets the stock quantity of the product with the given id and returns the product
+func (bs *basketStore) UpdateProductStock(ctx context.Context, id string, quantity int32) (models.Product, error) {
+	result := bs.db.WithContext(ctx).Model(&models.Product{}).Where("id = ?", id).Update("quantity", quantity)
+	if result.Error != nil {
+		return models.Product{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.Product{}, gorm.ErrRecordNotFound
+	}
+	return bs.GetProductById(ctx, id)
+}
+
+// GetReservedStock - returns the quantities of the products reserved by the active reservations by product id
+func (bs *basketStore) GetReservedStock(ctx context.Context) (map[string]int32, error) {
+	var rows []struct {
+		ProductID string
+		Reserved  int64
+	}
+	result := bs.db.WithContext(ctx).Model(&models.StockReservation{}).Select("product_id, SUM(quantity) AS reserved").
+		Where("expires_at > ?", time.Now()).Group("product_id").Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	reserved := make(map[string]int32, len(rows))
+	for _, row := range rows {
+		reserved[row.ProductID] = int32(row.Reserved)
+	}
+	return reserved, nil
+}
+
+// AvailableStock - returns the stock of the product which is not reserved by the carts other than the given carts
+func (bs *basketStore) AvailableStock(ctx context.Context, productId string, exceptCartIds ...string) (int32, error) {
+	product, err := bs.GetProductById(ctx, productId)
+	if err != nil {
+		return 0, err
+	}
+	reserved, err := reservedQuantity(bs.db.WithContext(ctx), productId, exceptCartIds...)
+	if err != nil {
+		return 0, err
+	}
+	if product.Quantity < reserved {
+		return 0, nil
+	}
+	return product.Quantity - reserved, nil
+}
+
+// reserveStock - reserves the given quantity of the product for the cart for the reservation ttl in the transaction, the reservation replaces
+// the previous reservation of the product for the cart, ErrStockNotAvailable is returned if the stock not reserved by the other carts is less than the quantity
+func (bs *basketStore) reserveStock(tx *gorm.DB, cartId string, productId string, quantity int32) error {
+	// the product row is locked until the end of the transaction, so the reservations of the product are made one after another
+	var product models.Product
+	if result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", productId).First(&product); result.Error != nil {
+		return result.Error
+	}
+	reserved, err := reservedQuantity(tx, productId, cartId)
+	if err != nil {
+		return err
+	}
+	if product.Quantity-reserved < quantity {
+		return fmt.Errorf("%w: %s", ErrStockNotAvailable, productId)
+	}
+	reservation := models.NewStockReservation(cartId, product.ID, quantity, time.Now().Add(time.Duration(bs.cfg.ReservationTTL)))
+	upsert := clause.OnConflict{
+		Columns:   []clause.Column{{Name: "shopping_cart_id"}, {Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"quantity", "expires_at", "updated_at"}),
+	}
+	return tx.Clauses(upsert).Create(&reservation).Error
+}
+
+// ReleaseStock - deletes the reservation of the product for the cart
+func (bs *basketStore) ReleaseStock(ctx context.Context, cartId string, productId string) error {
+	return bs.db.WithContext(ctx).Where("shopping_cart_id = ? AND product_id = ?", cartId, productId).Delete(&models.StockReservation{}).Error
+}
+
+// ReleaseExpiredReservations - deletes the expired reservations and returns how many are deleted
+func (bs *basketStore) ReleaseExpiredReservations(ctx context.Context) (int64, error) {
+	result := bs.db.WithContext(ctx).Where("expires_at <= ?", time.Now()).Delete(&models.StockReservation{})
+	return result.RowsAffected, result.Error
+}
+
+// reservedQuantity - returns the quantity of the product reserved by the active reservations of the carts other than the given carts
+func reservedQuantity(db *gorm.DB, productId string, exceptCartIds ...string) (int32, error) {
+	var reserved int64
+	query := db.Model(&models.StockReservation{}).Select("COALESCE(SUM(quantity), 0)").Where("product_id = ? AND expires_at > ?", productId, time.Now())
+	if len(exceptCartIds) > 0 {
+		query = query.Where("shopping_cart_id NOT IN ?", exceptCartIds)
+	}
+	result := query.Scan(&reserved)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return int32(reserved), nil
+}
+
 // generateShoppingCart - generates a new shopping cart for the given user
 func generateShoppingCart(userId string) models.ShoppingCart {
 	shoppingCart := models.NewShoppingCart(userId)
@@ -80,45 +201,155 @@ func (bs *basketStore) GetBasket(ctx context.Context, userId string) (models.Sho
 	return cart, nil
 }
 
-// UpdateBasket - updates the shopping cart for the given user and returns the new shopping cart
-func (bs *basketStore) UpdateBasket(ctx context.Context, userId string, newCart models.ShoppingCart) error {
-	_, err := bs.GetBasket(ctx, userId)
-	if err != nil {
+// UpdateBasket - updates the shopping cart with its items if it is not updated since it is read and increases its version,
+// ErrBasketVersionConflict is returned if another request has updated the cart in the meantime
+func (bs *basketStore) UpdateBasket(ctx context.Context, cart *models.ShoppingCart) error {
+	readVersion := cart.Version
+	tx := bs.db.WithContext(ctx).Begin()
+	if err := updateCart(tx, cart); err != nil {
+		tx.Rollback()
 		return err
 	}
-	if result := bs.db.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Updates(&newCart); result.Error != nil {
+	if err := tx.Commit().Error; err != nil {
+		cart.Version = readVersion
 		return err
 	}
 	return nil
 }
 
-// RemoveItemFromBasket - removes the given item from the shopping cart
-func (bs *basketStore) RemoveItemFromBasket(ctx context.Context, cartItem models.ShoppingCartItem, newCart models.ShoppingCart) error {
+// UpdateBasketWithReservation - updates the shopping cart like UpdateBasket and reserves the given quantity of the product for it in a transaction,
+// so the reservation is made only if the cart is updated, ErrStockNotAvailable is returned if the quantity can't be reserved
+func (bs *basketStore) UpdateBasketWithReservation(ctx context.Context, cart *models.ShoppingCart, productId string, quantity int32) error {
+	readVersion := cart.Version
+	tx := bs.db.WithContext(ctx).Begin()
+	// the cart row is locked before the product row like in the checkout, so they don't wait for each other
+	if err := updateCart(tx, cart); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := bs.reserveStock(tx, cart.ID.String(), productId, quantity); err != nil {
+		tx.Rollback()
+		cart.Version = readVersion
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		cart.Version = readVersion
+		return err
+	}
+	return nil
+}
+
+// RemoveItemFromBasket - removes the given item from the shopping cart and updates the cart if it is not updated since it is read
+func (bs *basketStore) RemoveItemFromBasket(ctx context.Context, cartItem models.ShoppingCartItem, cart *models.ShoppingCart) error {
+	readVersion := cart.Version
 	tx := bs.db.WithContext(ctx).Begin()
 	if result := tx.Delete(&cartItem); result.Error != nil {
 		tx.Rollback()
 		return result.Error
 	}
-	if result := tx.Save(&newCart); result.Error != nil {
+	if err := updateCart(tx, cart); err != nil {
 		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		cart.Version = readVersion
+		return err
+	}
+	return nil
+}
+
+// updateCart - writes the cart and its items in the transaction on the condition that the stored cart has the version the cart is read at,
+// the version of the cart is increased if it is written and kept otherwise
+func updateCart(tx *gorm.DB, cart *models.ShoppingCart) error {
+	readVersion := cart.Version
+	cart.Version++
+	// every column is written, so cleared fields such as the coupon code are written as well
+	result := tx.Model(cart).Where("version = ?", readVersion).Select("*").Omit("Items", "CreatedAt").Updates(cart)
+	if result.Error == nil && result.RowsAffected == 0 {
+		result.Error = ErrBasketVersionConflict
+	}
+	if result.Error == nil && len(cart.Items) > 0 {
+		result = tx.Save(&cart.Items)
+	}
+	if result.Error != nil {
+		cart.Version = readVersion
 		return result.Error
 	}
-	tx.Commit()
 	return nil
 }
 
-// CheckoutBasket - checks out the given shopping cart and delete the shopping cart and all its items
+// MergeBasket - saves the shopping cart the guest cart is merged into and deletes the guest cart with its items in a transaction,
+// so the items of the guest cart are not merged twice. The reservations of the guest cart are released and the given quantities
+// of the products are reserved for the shopping cart in the same transaction, ErrStockNotAvailable is returned if one can't be reserved
+func (bs *basketStore) MergeBasket(ctx context.Context, cart *models.ShoppingCart, guestCart models.ShoppingCart, reservations map[string]int32) error {
+	readVersion := cart.Version
+	tx := bs.db.WithContext(ctx).Begin()
+	result := tx.Select("Items").Delete(&guestCart)
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		// a concurrent merge has already deleted the guest cart
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
+	}
+	if result := tx.Where("shopping_cart_id = ?", guestCart.ID.String()).Delete(&models.StockReservation{}); result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	// the products are locked in the order of their ids, so concurrent merges don't lock them in different orders
+	productIds := make([]string, 0, len(reservations))
+	for productId := range reservations {
+		productIds = append(productIds, productId)
+	}
+	sort.Strings(productIds)
+	for _, productId := range productIds {
+		if err := bs.reserveStock(tx, cart.ID.String(), productId, reservations[productId]); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := updateCart(tx, cart); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		cart.Version = readVersion
+		return err
+	}
+	return nil
+}
+
+// CheckoutBasket - checks out the given shopping cart and delete the shopping cart and all its items,
+// ErrBasketVersionConflict is returned if the cart is updated since it is read, so the order has the items the shopper has seen.
+// The reservations of the cart are converted into the sale, the stock reserved by the other carts can't be sold
 func (bs *basketStore) CheckoutBasket(ctx context.Context, cart models.ShoppingCart) error {
 	tx := bs.db.WithContext(ctx).Begin()
+	// the cart row stays locked until the end of the transaction, so the cart can't be updated or checked out concurrently
+	result := tx.Model(&models.ShoppingCart{}).Where("id = ? AND version = ?", cart.ID, cart.Version).Update("version", gorm.Expr("version + 1"))
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return ErrBasketVersionConflict
+	}
 	for _, item := range cart.Items {
-		product, err := bs.productExists(item.ProductID.String())
+		product, err := lockProduct(tx, item.ProductID.String())
 		if err != nil {
 			tx.Rollback()
 			return err
 		}
-		if product.Quantity < item.Quantity {
+		reserved, err := reservedQuantity(tx, item.ProductID.String(), cart.ID.String())
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if product.Quantity-reserved < item.Quantity {
 			tx.Rollback()
-			return errors.New("not enough stock for product: " + item.ProductID.String())
+			return fmt.Errorf("%w: not enough stock for product: %s", ErrStockNotAvailable, item.ProductID.String())
 		}
 		product.Quantity -= item.Quantity
 		if result := tx.Save(&product); result.Error != nil {
@@ -127,23 +358,142 @@ func (bs *basketStore) CheckoutBasket(ctx context.Context, cart models.ShoppingC
 		}
 	}
 	orderHistory := models.NewSalesHistory(cart)
+	// the order sequence of the user is counted while the cart row of the user is locked,
+	// the unique index of the user and the sequence rejects the order if another cart of the user is checked out concurrently
+	var userOrderCount int64
+	if result := tx.Model(&models.SalesHistory{}).Where("user_id = ?", cart.UserID).Count(&userOrderCount); result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	orderHistory.OrderSequence = userOrderCount + 1
 	if result := tx.WithContext(ctx).Session(&gorm.Session{FullSaveAssociations: true}).Save(&orderHistory); result.Error != nil {
 		tx.Rollback()
 		return result.Error
 	}
+	if cart.CouponCode != "" {
+		if err := redeemCoupon(tx, cart, orderHistory); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := redeemCampaigns(tx, cart, orderHistory); err != nil {
+		tx.Rollback()
+		return err
+	}
+	// the sold quantities are taken out of the stock above, so the reservations of the cart are not needed anymore
+	if result := tx.Where("shopping_cart_id = ?", cart.ID.String()).Delete(&models.StockReservation{}); result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
 	// delete shopping_cart_items relations when deleting shopping_cart
 	if result := tx.Select("Items").Delete(&cart); result.Error != nil {
 		tx.Rollback()
 		return result.Error
 	}
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// redeemCoupon - records the redemption of the cart coupon, the coupon row is locked so the limits can't be exceeded by concurrent checkouts
+func redeemCoupon(tx *gorm.DB, cart models.ShoppingCart, orderHistory models.SalesHistory) error {
+	var coupon models.Coupon
+	if result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", cart.CouponCode).First(&coupon); result.Error != nil {
+		return result.Error
+	}
+	appliedCampaign, applied := cart.AppliedCampaigns.Find(coupon.CampaignID())
+	if !applied {
+		// coupon discount lost against another campaign, so the coupon is not redeemed
+		return nil
+	}
+	var userRedemptionCount int64
+	if result := tx.Model(&models.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", coupon.ID, cart.UserID).Count(&userRedemptionCount); result.Error != nil {
+		return result.Error
+	}
+	redemptionCount, err := couponRedemptionCount(coupon, userRedemptionCount)
+	if err != nil {
+		return err
+	}
+	if result := tx.Model(&coupon).Update("redemption_count", redemptionCount); result.Error != nil {
+		return result.Error
+	}
+	redemption := models.NewCouponRedemption(coupon, cart.UserID, orderHistory.ID, appliedCampaign.Amount)
+	if result := tx.Create(&redemption); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// couponRedemptionCount - returns the redemption count of the coupon after it is redeemed by a user who has redeemed it the given times,
+// or an error if the total or the per user limit of the coupon is reached
+func couponRedemptionCount(coupon models.Coupon, userRedemptionCount int64) (int32, error) {
+	if !coupon.HasRedemptionsLeft(userRedemptionCount) {
+		return 0, fmt.Errorf("%w: %s", ErrCouponRedemptionLimitReached, coupon.Code)
+	}
+	return coupon.RedemptionCount + 1, nil
+}
+
+// redeemCampaigns - records the redemptions of the campaigns applied on the cart and adds their discounts to the campaign budgets,
+// every campaign row is locked so the budgets and the limits can't be exceeded by concurrent checkouts
+func redeemCampaigns(tx *gorm.DB, cart models.ShoppingCart, orderHistory models.SalesHistory) error {
+	for _, appliedCampaign := range cart.AppliedCampaigns {
+		var storedCampaigns []models.Campaign
+		if result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", appliedCampaign.CampaignID).Limit(1).Find(&storedCampaigns); result.Error != nil {
+			return result.Error
+		}
+		if len(storedCampaigns) == 0 {
+			// coupons are not stored as campaigns, they are redeemed on their own
+			continue
+		}
+		storedCampaign := storedCampaigns[0]
+		var userRedemptionCount int64
+		if storedCampaign.MaxRedemptionsPerUser > 0 {
+			if result := tx.Model(&models.CampaignRedemption{}).Where("campaign_key = ? AND user_id = ?", storedCampaign.Key, cart.UserID).Count(&userRedemptionCount); result.Error != nil {
+				return result.Error
+			}
+		}
+		redeemed, err := redeemedCampaign(storedCampaign, userRedemptionCount, appliedCampaign.Amount, cart.Currency)
+		if err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"spent_amount":     redeemed.SpentAmount,
+			"redemption_count": redeemed.RedemptionCount,
+		}
+		if result := tx.Model(&storedCampaign).Updates(updates); result.Error != nil {
+			return result.Error
+		}
+		redemption := models.NewCampaignRedemption(storedCampaign, cart.UserID, orderHistory.ID, appliedCampaign.Amount)
+		if result := tx.Create(&redemption); result.Error != nil {
+			return result.Error
+		}
+	}
 	return nil
 }
 
-// productExists - checks if the product with the given id exists
-func (bs *basketStore) productExists(productId string) (models.Product, error) {
+// redeemedCampaign - returns the campaign with the discount in the given currency added to its budget and the redemption counted,
+// or an error if the user limit of the campaign is reached or its remaining budget doesn't cover the discount
+func redeemedCampaign(campaign models.Campaign, userRedemptionCount int64, amount decimal.Decimal, currency string) (models.Campaign, error) {
+	if !campaign.HasRedemptionsLeft(userRedemptionCount) {
+		return models.Campaign{}, fmt.Errorf("%w: %s", ErrCampaignRedemptionLimitReached, campaign.Key)
+	}
+	if !campaign.IsBudgetedIn(currency) {
+		return models.Campaign{}, fmt.Errorf("%w: %s is budgeted in %s", ErrCampaignBudgetCurrency, campaign.Key, campaign.BudgetCurrency)
+	}
+	if !campaign.HasBudgetFor(amount) {
+		return models.Campaign{}, fmt.Errorf("%w: %s", ErrCampaignBudgetExhausted, campaign.Key)
+	}
+	campaign.SpentAmount = campaign.SpentAmount.Add(amount)
+	campaign.RedemptionCount++
+	return campaign, nil
+}
+
+// lockProduct - returns the product with the given id and locks its row until the end of the transaction,
+// so the stock of the product is not sold or reserved concurrently
+func lockProduct(tx *gorm.DB, productId string) (models.Product, error) {
 	var product models.Product
-	if result := bs.db.Where("id = ?", productId).First(&product); result.Error != nil {
+	if result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", productId).First(&product); result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return models.Product{}, errors.New("product does not exist: " + productId)
 		}
@@ -152,36 +502,81 @@ func (bs *basketStore) productExists(productId string) (models.Product, error) {
 	return product, nil
 }
 
-// GetUserMonthlyOrderAmount - returns the total amount of orders for the given user in a month
-func (bs *basketStore) GetUserMonthlyOrderAmount(ctx context.Context, userId string) (float64, error) {
+// historyStart - returns the time the sales history is taken into account from
+func (bs *basketStore) historyStart() time.Time {
+	return time.Now().AddDate(0, -bs.cfg.HistoryMonths, 0)
+}
+
+// GetUserMonthlyOrderAmount - returns the total amount of orders for the given user in a month in the given currency,
+// orders in other currencies are not added up with them
+func (bs *basketStore) GetUserMonthlyOrderAmount(ctx context.Context, userId string, currency string) (decimal.Decimal, error) {
 	var orders []models.SalesHistory
-	if result := bs.db.WithContext(ctx).Where("user_id = ? AND created_at > ?", userId, time.Now().AddDate(0, -1, 0)).Find(&orders); result.Error != nil {
-		return 0, result.Error
+	if result := bs.db.WithContext(ctx).Where("user_id = ? AND created_at > ?", userId, bs.historyStart()).Find(&orders); result.Error != nil {
+		return decimal.Zero, result.Error
 	}
-	var total float64
+	return orderAmountsByCurrency(orders)[models.CurrencyOrBase(currency)], nil
+}
+
+// orderAmountsByCurrency - returns the total amount of the given orders per currency, orders without a currency are in the base currency
+func orderAmountsByCurrency(orders []models.SalesHistory) map[string]decimal.Decimal {
+	totals := make(map[string]decimal.Decimal)
 	for _, order := range orders {
-		total += order.SubTotal.InexactFloat64()
+		currency := models.CurrencyOrBase(order.Currency)
+		totals[currency] = totals[currency].Add(order.SubTotal)
 	}
-	return total, nil
+	return totals
 }
 
-// GetEveryFourthOrderAmount - returns the total amount of every fourth order in a month
-func (bs *basketStore) GetEveryFourthOrderAmount(ctx context.Context) (float64, error) {
-	var transactionCount int64
-	var total float64
-
-	if result := bs.db.WithContext(ctx).Where("created_at > ?", time.Now().AddDate(0, -1, 0)).Order("created_at desc").Count(&transactionCount); result.Error != nil {
+// GetUserOrderCount - returns the number of orders of the given user since the given time
+func (bs *basketStore) GetUserOrderCount(ctx context.Context, userId string, since time.Time) (int64, error) {
+	var count int64
+	if result := bs.db.WithContext(ctx).Model(&models.SalesHistory{}).Where("user_id = ? AND created_at > ?", userId, since).Count(&count); result.Error != nil {
 		return 0, result.Error
 	}
-	// if transactionCount divisible by 4, then get last 4 orders
+	return count, nil
+}
+
+// GetUserRecentOrders - returns the orders of the given user since the given time, the latest order comes first
+func (bs *basketStore) GetUserRecentOrders(ctx context.Context, userId string, since time.Time) ([]models.SalesHistory, error) {
 	var orders []models.SalesHistory
-	if transactionCount%4 == 0 {
-		if result := bs.db.WithContext(ctx).Where("created_at > ?", time.Now().AddDate(0, -1, 0)).Order("created_at desc").Limit(4).Find(&orders); result.Error != nil {
-			return 0, result.Error
-		}
-		for _, order := range orders {
-			total += order.SubTotal.InexactFloat64()
-		}
+	if result := bs.db.WithContext(ctx).Where("user_id = ? AND created_at > ?", userId, since).Order("created_at desc").Find(&orders); result.Error != nil {
+		return nil, result.Error
+	}
+	return orders, nil
+}
+
+// GetCouponByCode - returns the coupon with the given code
+func (bs *basketStore) GetCouponByCode(ctx context.Context, code string) (models.Coupon, error) {
+	var coupon models.Coupon
+	if result := bs.db.WithContext(ctx).Where("code = ?", code).First(&coupon); result.Error != nil {
+		return models.Coupon{}, result.Error
+	}
+	return coupon, nil
+}
+
+// CountUserCouponRedemptions - returns how many times the given user has redeemed the coupon
+func (bs *basketStore) CountUserCouponRedemptions(ctx context.Context, couponId string, userId string) (int64, error) {
+	var count int64
+	if result := bs.db.WithContext(ctx).Model(&models.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", couponId, userId).Count(&count); result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// GetShippingMethods - returns the active shipping methods ordered by code
+func (bs *basketStore) GetShippingMethods(ctx context.Context) ([]models.ShippingMethod, error) {
+	var methods []models.ShippingMethod
+	if result := bs.db.WithContext(ctx).Where("active = ?", true).Order("code").Find(&methods); result.Error != nil {
+		return nil, result.Error
+	}
+	return methods, nil
+}
+
+// GetShippingMethodByCode - returns the active shipping method with the given code
+func (bs *basketStore) GetShippingMethodByCode(ctx context.Context, code string) (models.ShippingMethod, error) {
+	var method models.ShippingMethod
+	if result := bs.db.WithContext(ctx).Where("code = ? AND active = ?", code, true).First(&method); result.Error != nil {
+		return models.ShippingMethod{}, result.Error
 	}
-	return total, nil
+	return method, nil
 }
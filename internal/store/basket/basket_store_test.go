@@ -0,0 +1,89 @@
+package basket
+
+import (
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestCouponRedemptionCount(t *testing.T) {
+	tests := []struct {
+		name                  string
+		maxRedemptions        int32
+		maxRedemptionsPerUser int32
+		redemptionCount       int32
+		userRedemptionCount   int64
+		expected              int32
+		expectedErr           error
+	}{
+		{name: "unlimited coupon", redemptionCount: 7, userRedemptionCount: 3, expected: 8},
+		{name: "coupon under the total limit", maxRedemptions: 10, redemptionCount: 9, expected: 10},
+		{name: "coupon at the total limit", maxRedemptions: 10, redemptionCount: 10, expectedErr: ErrCouponRedemptionLimitReached},
+		{name: "coupon under the user limit", maxRedemptionsPerUser: 2, redemptionCount: 5, userRedemptionCount: 1, expected: 6},
+		{name: "coupon at the user limit", maxRedemptionsPerUser: 2, redemptionCount: 5, userRedemptionCount: 2, expectedErr: ErrCouponRedemptionLimitReached},
+	}
+	for _, test := range tests {
+		coupon := models.Coupon{Code: "SPRING", MaxRedemptions: test.maxRedemptions, MaxRedemptionsPerUser: test.maxRedemptionsPerUser, RedemptionCount: test.redemptionCount}
+		redemptionCount, err := couponRedemptionCount(coupon, test.userRedemptionCount)
+		if !errors.Is(err, test.expectedErr) {
+			t.Errorf("Expected %s error to be %v, got %v", test.name, test.expectedErr, err)
+			continue
+		}
+		if redemptionCount != test.expected {
+			t.Errorf("Expected %s redemption count to be %d, got %d", test.name, test.expected, redemptionCount)
+		}
+	}
+}
+
+func TestRedeemedCampaign(t *testing.T) {
+	budget := decimal.New(100, 0)
+	tests := []struct {
+		name                  string
+		budget                *decimal.Decimal
+		maxRedemptionsPerUser int64
+		spentAmount           string
+		userRedemptionCount   int64
+		amount                string
+		currency              string
+		expectedSpentAmount   string
+		expectedErr           error
+	}{
+		{name: "unlimited campaign", spentAmount: "500", userRedemptionCount: 4, amount: "20", currency: "EUR", expectedSpentAmount: "520"},
+		{name: "campaign with budget left", budget: &budget, spentAmount: "70", amount: "30", currency: "TRY", expectedSpentAmount: "100"},
+		{name: "campaign over budget", budget: &budget, spentAmount: "70", amount: "30.01", currency: "TRY", expectedErr: ErrCampaignBudgetExhausted},
+		{name: "campaign budgeted in another currency", budget: &budget, spentAmount: "0", amount: "10", currency: "EUR", expectedErr: ErrCampaignBudgetCurrency},
+		{name: "campaign under the user limit", maxRedemptionsPerUser: 2, spentAmount: "0", userRedemptionCount: 1, amount: "10", expectedSpentAmount: "10"},
+		{name: "campaign at the user limit", budget: &budget, maxRedemptionsPerUser: 2, spentAmount: "0", userRedemptionCount: 2, amount: "10", expectedErr: ErrCampaignRedemptionLimitReached},
+	}
+	for _, test := range tests {
+		campaign := models.Campaign{Key: "spring", Budget: test.budget, BudgetCurrency: "TRY", MaxRedemptionsPerUser: test.maxRedemptionsPerUser, SpentAmount: decimal.RequireFromString(test.spentAmount), RedemptionCount: 3}
+		redeemed, err := redeemedCampaign(campaign, test.userRedemptionCount, decimal.RequireFromString(test.amount), test.currency)
+		if !errors.Is(err, test.expectedErr) {
+			t.Errorf("Expected %s error to be %v, got %v", test.name, test.expectedErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !redeemed.SpentAmount.Equal(decimal.RequireFromString(test.expectedSpentAmount)) || redeemed.RedemptionCount != 4 {
+			t.Errorf("Expected %s to have spent %s in 4 redemptions, got %s in %d", test.name, test.expectedSpentAmount, redeemed.SpentAmount, redeemed.RedemptionCount)
+		}
+	}
+}
+
+func TestOrderAmountsByCurrency(t *testing.T) {
+	orders := []models.SalesHistory{
+		{SubTotal: decimal.New(100, 0), Currency: "TRY"},
+		{SubTotal: decimal.New(50, 0)},
+		{SubTotal: decimal.RequireFromString("19.99"), Currency: "EUR"},
+		{SubTotal: decimal.New(10, 0), Currency: "EUR"},
+	}
+	totals := orderAmountsByCurrency(orders)
+	expected := map[string]string{"TRY": "150", "EUR": "29.99", "USD": "0"}
+	for currency, amount := range expected {
+		if !totals[currency].Equal(decimal.RequireFromString(amount)) {
+			t.Errorf("Expected %s orders to total %s, got %s", currency, amount, totals[currency])
+		}
+	}
+}
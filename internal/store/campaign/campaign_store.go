@@ -0,0 +1,95 @@
+package campaign
+
+import (
+	"context"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"gorm.io/gorm"
+	"time"
+)
+
+// CampaignStore - defines the interface we need our campaign storage layer to implement
+type CampaignStore interface {
+	GetCampaigns(ctx context.Context, statuses ...models.CampaignStatus) ([]models.Campaign, error)
+	GetCampaignByKey(ctx context.Context, key string) (models.Campaign, error)
+	CreateCampaign(ctx context.Context, campaign models.Campaign) error
+	UpdateCampaignStatus(ctx context.Context, key string, status models.CampaignStatus) error
+	CountCampaigns(ctx context.Context) (int64, error)
+	CountUserCampaignRedemptions(ctx context.Context, userId string) (map[string]int64, error)
+}
+
+type campaignStore struct {
+	db *gorm.DB
+}
+
+// NewCampaignStore - creates a new campaign store instance with the given database connection
+func NewCampaignStore(db *gorm.DB) CampaignStore {
+	return &campaignStore{db: db}
+}
+
+// GetCampaigns - returns the campaigns with the given statuses ordered by creation time, all campaigns if no status is given
+func (cs *campaignStore) GetCampaigns(ctx context.Context, statuses ...models.CampaignStatus) ([]models.Campaign, error) {
+	var campaigns []models.Campaign
+	query := cs.db.WithContext(ctx).Order("created_at")
+	if len(statuses) > 0 {
+		query = query.Where("status IN ?", statuses)
+	}
+	if result := query.Find(&campaigns); result.Error != nil {
+		return nil, result.Error
+	}
+	return campaigns, nil
+}
+
+// GetCampaignByKey - returns the campaign with the given key
+func (cs *campaignStore) GetCampaignByKey(ctx context.Context, key string) (models.Campaign, error) {
+	var campaign models.Campaign
+	if result := cs.db.WithContext(ctx).Where("key = ?", key).First(&campaign); result.Error != nil {
+		return models.Campaign{}, result.Error
+	}
+	return campaign, nil
+}
+
+// CreateCampaign - creates the given campaign
+func (cs *campaignStore) CreateCampaign(ctx context.Context, campaign models.Campaign) error {
+	if result := cs.db.WithContext(ctx).Create(&campaign); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// UpdateCampaignStatus - updates the status of the campaign with the given key
+func (cs *campaignStore) UpdateCampaignStatus(ctx context.Context, key string, status models.CampaignStatus) error {
+	result := cs.db.WithContext(ctx).Model(&models.Campaign{}).Where("key = ?", key).Updates(map[string]interface{}{"status": status, "updated_at": time.Now()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CountCampaigns - returns the number of campaigns in any status
+func (cs *campaignStore) CountCampaigns(ctx context.Context) (int64, error) {
+	var count int64
+	if result := cs.db.WithContext(ctx).Model(&models.Campaign{}).Count(&count); result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// CountUserCampaignRedemptions - returns how many times the given user has redeemed every campaign by campaign key
+func (cs *campaignStore) CountUserCampaignRedemptions(ctx context.Context, userId string) (map[string]int64, error) {
+	var rows []struct {
+		CampaignKey string
+		Count       int64
+	}
+	result := cs.db.WithContext(ctx).Model(&models.CampaignRedemption{}).Select("campaign_key, count(*) as count").Where("user_id = ?", userId).Group("campaign_key").Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	counts := make(map[string]int64)
+	for _, row := range rows {
+		counts[row.CampaignKey] = row.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,65 @@
+package customer
+
+import (
+	"context"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"time"
+)
+
+// CustomerStore - defines the interface we need our customer storage layer to implement
+type CustomerStore interface {
+	GetCustomerProfile(ctx context.Context, userId string) (models.CustomerProfile, error)
+	SaveCustomerProfile(ctx context.Context, profile models.CustomerProfile) error
+	CountUserOrders(ctx context.Context, userId string) (int64, error)
+	GetUserOrderAmount(ctx context.Context, userId string, since time.Time) (decimal.Decimal, error)
+}
+
+type customerStore struct {
+	db *gorm.DB
+}
+
+// NewCustomerStore - creates a new customer store instance with the given database connection
+func NewCustomerStore(db *gorm.DB) CustomerStore {
+	return &customerStore{db: db}
+}
+
+// GetCustomerProfile - returns the profile of the given user
+func (cs *customerStore) GetCustomerProfile(ctx context.Context, userId string) (models.CustomerProfile, error) {
+	var profile models.CustomerProfile
+	if result := cs.db.WithContext(ctx).Where("user_id = ?", userId).First(&profile); result.Error != nil {
+		return models.CustomerProfile{}, result.Error
+	}
+	return profile, nil
+}
+
+// SaveCustomerProfile - creates or updates the given customer profile
+func (cs *customerStore) SaveCustomerProfile(ctx context.Context, profile models.CustomerProfile) error {
+	if result := cs.db.WithContext(ctx).Save(&profile); result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// CountUserOrders - returns the number of all orders of the given user
+func (cs *customerStore) CountUserOrders(ctx context.Context, userId string) (int64, error) {
+	var count int64
+	if result := cs.db.WithContext(ctx).Model(&models.SalesHistory{}).Where("user_id = ?", userId).Count(&count); result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
+// GetUserOrderAmount - returns the total amount of the orders of the given user since the given time
+func (cs *customerStore) GetUserOrderAmount(ctx context.Context, userId string, since time.Time) (decimal.Decimal, error) {
+	var orders []models.SalesHistory
+	if result := cs.db.WithContext(ctx).Where("user_id = ? AND created_at > ?", userId, since).Find(&orders); result.Error != nil {
+		return decimal.Zero, result.Error
+	}
+	total := decimal.Zero
+	for _, order := range orders {
+		total = total.Add(order.SubTotal)
+	}
+	return total, nil
+}
@@ -0,0 +1,119 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/shopspring/decimal"
+	"sort"
+	"time"
+)
+
+const (
+	// ClassStandard - tax class of most products
+	ClassStandard = "standard"
+	// ClassReduced - tax class of products such as books and basic goods
+	ClassReduced = "reduced"
+	// ClassSuperReduced - tax class of products such as staple food
+	ClassSuperReduced = "super_reduced"
+)
+
+var (
+	ErrUnknownTaxClass = errors.New("unknown tax class")
+	ErrInvalidTaxRate  = errors.New("tax rate percentage must be between 0 and 100")
+	ErrMissingTaxClass = errors.New("tax class is required")
+)
+
+// Rate - represents the vat percentage of a tax class from the time it is effective, the percentage may have decimals such as 5.5
+type Rate struct {
+	Class      string          `json:"class"`
+	Percentage decimal.Decimal `json:"percentage"`
+	ValidFrom  time.Time       `json:"valid_from"`
+}
+
+// Table - represents the effective dated vat rates of the tax classes
+type Table struct {
+	Rates []Rate `json:"rates"`
+}
+
+// Engine - resolves the vat rates of the cart items by their tax class and sets how the cart prices include vat
+type Engine struct {
+	table Table
+	// pricesIncludeVat - catalogue prices include vat, vat is taken out of the prices instead of being added on them
+	pricesIncludeVat bool
+}
+
+// DefaultTable - returns the vat rates used when no rate is configured
+func DefaultTable() Table {
+	return Table{Rates: []Rate{
+		{Class: ClassStandard, Percentage: decimal.New(18, 0)},
+		{Class: ClassReduced, Percentage: decimal.New(8, 0)},
+		{Class: ClassSuperReduced, Percentage: decimal.New(1, 0)},
+	}}
+}
+
+// NewEngine - creates a new tax engine with the given rate table and catalogue pricing
+func NewEngine(table Table, pricesIncludeVat bool) Engine {
+	return Engine{table: table, pricesIncludeVat: pricesIncludeVat}
+}
+
+// Validate - checks the classes and percentages of the rates
+func (t Table) Validate() error {
+	for _, rate := range t.Rates {
+		if rate.Class == "" {
+			return ErrMissingTaxClass
+		}
+		if rate.Percentage.IsNegative() || rate.Percentage.GreaterThan(decimal.New(100, 0)) {
+			return fmt.Errorf("%w: %s", ErrInvalidTaxRate, rate.Class)
+		}
+	}
+	return nil
+}
+
+// RateOf - returns the vat percentage of the tax class effective at the given time, which is the rate with the latest valid from time before it
+func (t Table) RateOf(class string, at time.Time) (decimal.Decimal, bool) {
+	rates := make([]Rate, 0, len(t.Rates))
+	for _, rate := range t.Rates {
+		if rate.Class == class && !rate.ValidFrom.After(at) {
+			rates = append(rates, rate)
+		}
+	}
+	if len(rates) == 0 {
+		return decimal.Zero, false
+	}
+	sort.SliceStable(rates, func(i, j int) bool {
+		return rates[i].ValidFrom.After(rates[j].ValidFrom)
+	})
+	return rates[0].Percentage, true
+}
+
+// ProductRate - returns the vat percentage of the product at the given time,
+// products without a tax class keep the vat rate they are stored with
+func (e Engine) ProductRate(product models.Product, at time.Time) (decimal.Decimal, error) {
+	if product.TaxClass == "" {
+		return product.VatRate, nil
+	}
+	rate, ok := e.table.RateOf(product.TaxClass, at)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("%w: %q of product %s", ErrUnknownTaxClass, product.TaxClass, product.ID)
+	}
+	return rate, nil
+}
+
+// ApplyRates - sets the vat rates of the cart items effective at the given time by their tax class and recalculates the cart totals,
+// items without a tax class keep their vat rate. Shipping is not taxed, the vat of the cart is the vat of its items
+func (e Engine) ApplyRates(cart *models.ShoppingCart, at time.Time) error {
+	cart.PricesIncludeVat = e.pricesIncludeVat
+	for i, item := range cart.Items {
+		if item.TaxClass == "" {
+			continue
+		}
+		rate, ok := e.table.RateOf(item.TaxClass, at)
+		if !ok {
+			return fmt.Errorf("%w: %q of product %s", ErrUnknownTaxClass, item.TaxClass, item.ProductID)
+		}
+		cart.Items[i].VatRate = rate
+	}
+	cart.CalculateTotalPrice()
+	return nil
+}
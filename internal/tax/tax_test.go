@@ -0,0 +1,73 @@
+package tax
+
+import (
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/models"
+	"github.com/gofrs/uuid"
+	"github.com/shopspring/decimal"
+	"testing"
+	"time"
+)
+
+func TestTable_RateOf(t *testing.T) {
+	change := time.Date(2023, 7, 10, 0, 0, 0, 0, time.UTC)
+	table := Table{Rates: []Rate{
+		{Class: ClassStandard, Percentage: decimal.New(18, 0)},
+		{Class: ClassStandard, Percentage: decimal.RequireFromString("20.5"), ValidFrom: change},
+		{Class: ClassReduced, Percentage: decimal.RequireFromString("5.5")},
+	}}
+	tests := []struct {
+		name     string
+		class    string
+		at       time.Time
+		expected string
+		found    bool
+	}{
+		{name: "standard before the change", class: ClassStandard, at: change.Add(-time.Second), expected: "18", found: true},
+		{name: "standard from the change", class: ClassStandard, at: change, expected: "20.5", found: true},
+		{name: "reduced", class: ClassReduced, at: change, expected: "5.5", found: true},
+		{name: "unknown class", class: "luxury", at: change, expected: "0"},
+	}
+	for _, test := range tests {
+		rate, found := table.RateOf(test.class, test.at)
+		if !rate.Equal(decimal.RequireFromString(test.expected)) || found != test.found {
+			t.Errorf("Expected %s rate to be %s (%t), got %s (%t)", test.name, test.expected, test.found, rate, found)
+		}
+	}
+}
+
+func TestEngine_ApplyRates(t *testing.T) {
+	tests := []struct {
+		name             string
+		pricesIncludeVat bool
+		expectedVat      string
+		expectedSubTotal string
+	}{
+		{name: "exclusive prices", expectedVat: "14.4", expectedSubTotal: "94.4"},
+		{name: "inclusive prices", pricesIncludeVat: true, expectedVat: "12.2", expectedSubTotal: "80"},
+	}
+	for _, test := range tests {
+		cart := models.NewShoppingCart("user")
+		cart.AddItem(models.ShoppingCartItem{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, Price: decimal.New(100, 0), TaxClass: ClassStandard})
+		if err := NewEngine(DefaultTable(), test.pricesIncludeVat).ApplyRates(&cart, time.Now()); err != nil {
+			t.Fatalf("Expected vat rates of %s to be applied, got %v", test.name, err)
+		}
+		// vat is calculated on the price after the discount of the item
+		productId := cart.Items[0].ProductID.String()
+		cart.ApplyCampaigns(models.AppliedCampaigns{{CampaignID: "campaign", Amount: decimal.New(20, 0), Items: []models.AppliedCampaignItem{{ProductID: productId, Amount: decimal.New(20, 0)}}}})
+		if !cart.TotalVat.Equal(decimal.RequireFromString(test.expectedVat)) {
+			t.Errorf("Expected %s vat to be %s, got %s", test.name, test.expectedVat, cart.TotalVat)
+		}
+		if !cart.SubTotal.Equal(decimal.RequireFromString(test.expectedSubTotal)) {
+			t.Errorf("Expected %s sub total to be %s, got %s", test.name, test.expectedSubTotal, cart.SubTotal)
+		}
+	}
+}
+
+func TestEngine_ApplyRates_UnknownTaxClass(t *testing.T) {
+	cart := models.NewShoppingCart("user")
+	cart.AddItem(models.ShoppingCartItem{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, Price: decimal.New(100, 0), TaxClass: "luxury"})
+	if err := NewEngine(DefaultTable(), false).ApplyRates(&cart, time.Now()); !errors.Is(err, ErrUnknownTaxClass) {
+		t.Errorf("Expected unknown tax class error, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// GetUserBasket - returns the basket of the user with the user id without changing it
+func (h *Handler) GetUserBasket(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["userId"]
+	if userId == "" {
+		sendErrorResponse(w, "userId is required", ErrUserIdNotFound)
+		return
+	}
+	cart, err := h.service.ViewBasket(r.Context(), userId)
+	if err != nil {
+		sendErrorResponse(w, "Failed to get basket of user", err)
+		return
+	}
+	if err := sendOkResponse(w, cart); err != nil {
+		panic(err)
+	}
+}
+
+// UpdateProductStock - sets the stock quantity of the product with the product id
+func (h *Handler) UpdateProductStock(w http.ResponseWriter, r *http.Request) {
+	productId := mux.Vars(r)["productId"]
+	if productId == "" {
+		sendErrorResponse(w, "productId is required", ErrProductIdNotFound)
+		return
+	}
+	var stock dto.UpdateProductStockDTO
+	if err := json.NewDecoder(r.Body).Decode(&stock); err != nil {
+		sendErrorResponse(w, "Failed to decode JSON Body", invalidJSON(err))
+		return
+	}
+	validate := newValidator()
+	err := validate.Struct(stock)
+	if err != nil {
+		sendErrorResponse(w, "Failed to validate request", err)
+		return
+	}
+	product, err := h.service.UpdateProductStock(r.Context(), productId, stock.Quantity)
+	if err != nil {
+		sendErrorResponse(w, "Failed to update product stock", err)
+		return
+	}
+	if err := sendOkResponse(w, product); err != nil {
+		panic(err)
+	}
+}
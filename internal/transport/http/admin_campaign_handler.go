@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+var (
+	ErrCampaignIdNotFound = errors.New("campaign id not found")
+)
+
+// GetCampaigns - returns the campaigns, the "status" query parameter filters them by status
+func (h *Handler) GetCampaigns(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := h.campaignService.GetCampaigns(r.Context(), r.URL.Query().Get("status"))
+	if err != nil {
+		sendErrorResponse(w, "Failed to get campaigns", err)
+		return
+	}
+	if err := sendOkResponse(w, campaigns); err != nil {
+		panic(err)
+	}
+}
+
+// GetCampaign - returns the campaign with the campaign id
+func (h *Handler) GetCampaign(w http.ResponseWriter, r *http.Request) {
+	h.handleCampaign(w, r, "Failed to get campaign", h.campaignService.GetCampaign)
+}
+
+// CreateCampaign - creates a campaign from the campaign definition
+func (h *Handler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var newCampaign dto.CreateCampaignDTO
+	if err := json.NewDecoder(r.Body).Decode(&newCampaign); err != nil {
+		sendErrorResponse(w, "Failed to decode JSON Body", invalidJSON(err))
+		return
+	}
+	campaign, err := h.campaignService.CreateCampaign(r.Context(), newCampaign)
+	if err != nil {
+		sendErrorResponse(w, "Failed to create campaign", err)
+		return
+	}
+	if err := sendOkResponse(w, campaign); err != nil {
+		panic(err)
+	}
+}
+
+// EnableCampaign - enables the campaign with the campaign id
+func (h *Handler) EnableCampaign(w http.ResponseWriter, r *http.Request) {
+	h.handleCampaign(w, r, "Failed to enable campaign", h.campaignService.EnableCampaign)
+}
+
+// DisableCampaign - disables the campaign with the campaign id
+func (h *Handler) DisableCampaign(w http.ResponseWriter, r *http.Request) {
+	h.handleCampaign(w, r, "Failed to disable campaign", h.campaignService.DisableCampaign)
+}
+
+// ArchiveCampaign - archives the campaign with the campaign id
+func (h *Handler) ArchiveCampaign(w http.ResponseWriter, r *http.Request) {
+	h.handleCampaign(w, r, "Failed to archive campaign", h.campaignService.ArchiveCampaign)
+}
+
+// handleCampaign - calls the given campaign operation with the campaign id of the request and sends the campaign
+func (h *Handler) handleCampaign(w http.ResponseWriter, r *http.Request, message string, operation func(ctx context.Context, id string) (dto.CampaignDTO, error)) {
+	campaignId := mux.Vars(r)["campaignId"]
+	if campaignId == "" {
+		sendErrorResponse(w, "campaignId is required", ErrCampaignIdNotFound)
+		return
+	}
+	campaign, err := operation(r.Context(), campaignId)
+	if err != nil {
+		sendErrorResponse(w, message, err)
+		return
+	}
+	if err := sendOkResponse(w, campaign); err != nil {
+		panic(err)
+	}
+}
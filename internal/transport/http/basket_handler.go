@@ -3,8 +3,8 @@ package http
 import (
 	"encoding/json"
 	"errors"
+	"github.com/erdemcemal/basket-service/internal/auth"
 	"github.com/erdemcemal/basket-service/internal/dto"
-	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"net/http"
 )
@@ -16,7 +16,11 @@ var (
 // Response object for JSON responses
 type Response struct {
 	Message string `json:"message"`
-	Error   string `json:"error"`
+	// Code - stable machine readable code of the error, such as "out_of_stock"
+	Code  string `json:"code"`
+	Error string `json:"error"`
+	// Fields - fields of the request body which failed validation
+	Fields []FieldError `json:"fields,omitempty"`
 }
 
 // GetProducts - get all products
@@ -33,13 +37,13 @@ func (h *Handler) GetProducts(w http.ResponseWriter, r *http.Request) {
 
 // GetBasket - get basket for user with user id
 func (h *Handler) GetBasket(w http.ResponseWriter, r *http.Request) {
-	userId := r.Header.Get("user_id")
+	userId := auth.UserID(r.Context())
 	basket, err := h.service.GetBasket(r.Context(), userId)
 	if err != nil {
 		sendErrorResponse(w, "failed to get basket", err)
 		return
 	}
-	if err := sendOkResponse(w, basket); err != nil {
+	if err := sendBasketResponse(w, basket); err != nil {
 		panic(err)
 	}
 }
@@ -48,22 +52,22 @@ func (h *Handler) GetBasket(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) AddItemToBasket(w http.ResponseWriter, r *http.Request) {
 	var item dto.AddItemToBasketDTO
 	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
-		sendErrorResponse(w, "Failed to decode JSON Body", err)
+		sendErrorResponse(w, "Failed to decode JSON Body", invalidJSON(err))
 		return
 	}
-	validate := validator.New()
+	validate := newValidator()
 	err := validate.Struct(item)
 	if err != nil {
 		sendErrorResponse(w, "Failed to validate request", err)
 		return
 	}
-	userId := r.Header.Get("user_id")
+	userId := auth.UserID(r.Context())
 	cart, err := h.service.AddItemToBasket(r.Context(), userId, item)
 	if err != nil {
 		sendErrorResponse(w, "Failed to add item to basket", err)
 		return
 	}
-	if err := sendOkResponse(w, cart); err != nil {
+	if err := sendBasketResponse(w, cart); err != nil {
 		panic(err)
 	}
 }
@@ -75,13 +79,13 @@ func (h *Handler) RemoveItemFromBasket(w http.ResponseWriter, r *http.Request) {
 		sendErrorResponse(w, "productId is required", ErrProductIdNotFound)
 		return
 	}
-	userId := r.Header.Get("user_id")
+	userId := auth.UserID(r.Context())
 	cart, err := h.service.RemoveItemFromBasket(r.Context(), userId, productId)
 	if err != nil {
 		sendErrorResponse(w, "Failed to remove item from basket", err)
 		return
 	}
-	if err := sendOkResponse(w, cart); err != nil {
+	if err := sendBasketResponse(w, cart); err != nil {
 		panic(err)
 	}
 }
@@ -90,29 +94,29 @@ func (h *Handler) RemoveItemFromBasket(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) UpdateItemInBasket(w http.ResponseWriter, r *http.Request) {
 	var item dto.UpdateItemInBasketDTO
 	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
-		sendErrorResponse(w, "Failed to decode JSON Body", err)
+		sendErrorResponse(w, "Failed to decode JSON Body", invalidJSON(err))
 		return
 	}
-	validate := validator.New()
+	validate := newValidator()
 	err := validate.Struct(item)
 	if err != nil {
 		sendErrorResponse(w, "Failed to validate request", err)
 		return
 	}
-	userId := r.Header.Get("user_id")
+	userId := auth.UserID(r.Context())
 	cart, err := h.service.UpdateItemInBasket(r.Context(), userId, item.ProductID, item.Quantity)
 	if err != nil {
 		sendErrorResponse(w, "Failed to update item in basket", err)
 		return
 	}
-	if err := sendOkResponse(w, cart); err != nil {
+	if err := sendBasketResponse(w, cart); err != nil {
 		panic(err)
 	}
 }
 
 // CheckoutBasket - checkout user basket
 func (h *Handler) CheckoutBasket(w http.ResponseWriter, r *http.Request) {
-	userId := r.Header.Get("user_id")
+	userId := auth.UserID(r.Context())
 	err := h.service.CheckoutBasket(r.Context(), userId)
 	if err != nil {
 		sendErrorResponse(w, "Failed to checkout basket", err)
@@ -123,14 +127,127 @@ func (h *Handler) CheckoutBasket(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ApplyCoupon - applies a coupon code to user basket
+func (h *Handler) ApplyCoupon(w http.ResponseWriter, r *http.Request) {
+	var coupon dto.ApplyCouponDTO
+	if err := json.NewDecoder(r.Body).Decode(&coupon); err != nil {
+		sendErrorResponse(w, "Failed to decode JSON Body", invalidJSON(err))
+		return
+	}
+	validate := newValidator()
+	err := validate.Struct(coupon)
+	if err != nil {
+		sendErrorResponse(w, "Failed to validate request", err)
+		return
+	}
+	userId := auth.UserID(r.Context())
+	cart, err := h.service.ApplyCoupon(r.Context(), userId, coupon.Code)
+	if err != nil {
+		sendErrorResponse(w, "Failed to apply coupon to basket", err)
+		return
+	}
+	if err := sendBasketResponse(w, cart); err != nil {
+		panic(err)
+	}
+}
+
+// RemoveCoupon - removes the coupon code from user basket
+func (h *Handler) RemoveCoupon(w http.ResponseWriter, r *http.Request) {
+	userId := auth.UserID(r.Context())
+	cart, err := h.service.RemoveCoupon(r.Context(), userId)
+	if err != nil {
+		sendErrorResponse(w, "Failed to remove coupon from basket", err)
+		return
+	}
+	if err := sendBasketResponse(w, cart); err != nil {
+		panic(err)
+	}
+}
+
+// GetShippingMethods - returns the shipping methods which deliver user basket to the region of the query with their costs
+func (h *Handler) GetShippingMethods(w http.ResponseWriter, r *http.Request) {
+	userId := auth.UserID(r.Context())
+	methods, err := h.service.GetShippingMethods(r.Context(), userId, r.URL.Query().Get("region"))
+	if err != nil {
+		sendErrorResponse(w, "Failed to get shipping methods", err)
+		return
+	}
+	if err := sendOkResponse(w, methods); err != nil {
+		panic(err)
+	}
+}
+
+// SelectShippingMethod - selects the shipping method and region of user basket
+func (h *Handler) SelectShippingMethod(w http.ResponseWriter, r *http.Request) {
+	var selection dto.SelectShippingMethodDTO
+	if err := json.NewDecoder(r.Body).Decode(&selection); err != nil {
+		sendErrorResponse(w, "Failed to decode JSON Body", invalidJSON(err))
+		return
+	}
+	validate := newValidator()
+	err := validate.Struct(selection)
+	if err != nil {
+		sendErrorResponse(w, "Failed to validate request", err)
+		return
+	}
+	userId := auth.UserID(r.Context())
+	cart, err := h.service.SelectShippingMethod(r.Context(), userId, selection)
+	if err != nil {
+		sendErrorResponse(w, "Failed to select shipping method of basket", err)
+		return
+	}
+	if err := sendBasketResponse(w, cart); err != nil {
+		panic(err)
+	}
+}
+
+// SetBasketCurrency - changes the currency the user basket is priced in
+func (h *Handler) SetBasketCurrency(w http.ResponseWriter, r *http.Request) {
+	var selection dto.SetBasketCurrencyDTO
+	if err := json.NewDecoder(r.Body).Decode(&selection); err != nil {
+		sendErrorResponse(w, "Failed to decode JSON Body", invalidJSON(err))
+		return
+	}
+	validate := newValidator()
+	err := validate.Struct(selection)
+	if err != nil {
+		sendErrorResponse(w, "Failed to validate request", err)
+		return
+	}
+	userId := auth.UserID(r.Context())
+	cart, err := h.service.SetBasketCurrency(r.Context(), userId, selection.Currency)
+	if err != nil {
+		sendErrorResponse(w, "Failed to set currency of basket", err)
+		return
+	}
+	if err := sendBasketResponse(w, cart); err != nil {
+		panic(err)
+	}
+}
+
+// GetDiscountTrace - returns how every campaign is evaluated on the user basket and why it is applied or not
+func (h *Handler) GetDiscountTrace(w http.ResponseWriter, r *http.Request) {
+	userId := auth.UserID(r.Context())
+	trace, err := h.service.GetDiscountTrace(r.Context(), userId)
+	if err != nil {
+		sendErrorResponse(w, "Failed to get discount trace", err)
+		return
+	}
+	if err := sendOkResponse(w, trace); err != nil {
+		panic(err)
+	}
+}
+
 func sendOkResponse(w http.ResponseWriter, resp interface{}) error {
 	w.WriteHeader(http.StatusOK)
 	return json.NewEncoder(w).Encode(resp)
 }
 
+// sendErrorResponse - sends the error with the http status and the error code mapped from it, internal errors are sent with 500
 func sendErrorResponse(w http.ResponseWriter, message string, err error) {
-	w.WriteHeader(http.StatusInternalServerError)
-	if err := json.NewEncoder(w).Encode(Response{Message: message, Error: err.Error()}); err != nil {
+	status, code := errorStatus(err)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(Response{Message: message, Code: code, Error: err.Error(), Fields: fieldErrors(err)}); err != nil {
 		panic(err)
 	}
 }
@@ -0,0 +1,30 @@
+package http
+
+import (
+	"encoding/json"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"net/http"
+)
+
+// SimulateCampaigns - returns what every campaign would give for a hypothetical cart without touching any basket
+func (h *Handler) SimulateCampaigns(w http.ResponseWriter, r *http.Request) {
+	var simulation dto.SimulateCampaignsDTO
+	if err := json.NewDecoder(r.Body).Decode(&simulation); err != nil {
+		sendErrorResponse(w, "Failed to decode JSON Body", invalidJSON(err))
+		return
+	}
+	validate := newValidator()
+	err := validate.Struct(simulation)
+	if err != nil {
+		sendErrorResponse(w, "Failed to validate request", err)
+		return
+	}
+	result, err := h.service.SimulateCampaigns(r.Context(), simulation)
+	if err != nil {
+		sendErrorResponse(w, "Failed to simulate campaigns", err)
+		return
+	}
+	if err := sendOkResponse(w, result); err != nil {
+		panic(err)
+	}
+}
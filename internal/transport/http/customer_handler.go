@@ -0,0 +1,72 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/auth"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+var (
+	ErrUserIdNotFound = errors.New("user id not found")
+)
+
+// GetProfile - returns the loyalty tier and the segments of the user
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userId := auth.UserID(r.Context())
+	customer, err := h.customerService.GetCustomer(r.Context(), userId)
+	if err != nil {
+		sendErrorResponse(w, "Failed to get customer profile", err)
+		return
+	}
+	if err := sendOkResponse(w, customer); err != nil {
+		panic(err)
+	}
+}
+
+// GetCustomer - returns the loyalty tier and the segments of the customer with the user id
+func (h *Handler) GetCustomer(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["userId"]
+	if userId == "" {
+		sendErrorResponse(w, "userId is required", ErrUserIdNotFound)
+		return
+	}
+	customer, err := h.customerService.GetCustomer(r.Context(), userId)
+	if err != nil {
+		sendErrorResponse(w, "Failed to get customer", err)
+		return
+	}
+	if err := sendOkResponse(w, customer); err != nil {
+		panic(err)
+	}
+}
+
+// UpdateCustomer - updates the manual flags, such as vip, of the customer with the user id
+func (h *Handler) UpdateCustomer(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["userId"]
+	if userId == "" {
+		sendErrorResponse(w, "userId is required", ErrUserIdNotFound)
+		return
+	}
+	var update dto.UpdateCustomerDTO
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		sendErrorResponse(w, "Failed to decode JSON Body", invalidJSON(err))
+		return
+	}
+	validate := newValidator()
+	err := validate.Struct(update)
+	if err != nil {
+		sendErrorResponse(w, "Failed to validate request", err)
+		return
+	}
+	customer, err := h.customerService.UpdateCustomer(r.Context(), userId, update)
+	if err != nil {
+		sendErrorResponse(w, "Failed to update customer", err)
+		return
+	}
+	if err := sendOkResponse(w, customer); err != nil {
+		panic(err)
+	}
+}
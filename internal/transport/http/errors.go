@@ -0,0 +1,152 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/admin"
+	"github.com/erdemcemal/basket-service/internal/auth"
+	"github.com/erdemcemal/basket-service/internal/basket"
+	"github.com/go-playground/validator/v10"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+var (
+	ErrInvalidJSON = errors.New("request body is not valid json")
+)
+
+const (
+	// CodeInternal - code of the errors the client can't fix, such as a database failure
+	CodeInternal = "internal_error"
+	// CodeInvalidJSON - code of the request bodies which can't be decoded
+	CodeInvalidJSON = "invalid_json"
+	// CodeValidationFailed - code of the request bodies with invalid fields, the fields are listed in the response
+	CodeValidationFailed = "validation_failed"
+)
+
+// errorMapping - represents the http status and the error code a service error is sent with
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// errorMappings - http statuses and error codes of the service errors, errors which are not listed are internal errors
+var errorMappings = []errorMapping{
+	{err: auth.ErrMissingToken, status: http.StatusUnauthorized, code: "missing_token"},
+	{err: auth.ErrInvalidToken, status: http.StatusUnauthorized, code: "invalid_token"},
+	{err: auth.ErrInvalidCartToken, status: http.StatusUnauthorized, code: "invalid_cart_token"},
+	{err: ErrForbidden, status: http.StatusForbidden, code: "forbidden"},
+	{err: basket.ErrBasketNotFound, status: http.StatusNotFound, code: "basket_not_found"},
+	{err: ErrInvalidJSON, status: http.StatusBadRequest, code: CodeInvalidJSON},
+	{err: ErrProductIdNotFound, status: http.StatusBadRequest, code: "product_id_required"},
+	{err: ErrCampaignIdNotFound, status: http.StatusBadRequest, code: "campaign_id_required"},
+	{err: ErrUserIdNotFound, status: http.StatusBadRequest, code: "user_id_required"},
+	{err: auth.ErrMissingCartToken, status: http.StatusBadRequest, code: "cart_token_required"},
+	{err: basket.ErrUnsupportedCurrency, status: http.StatusBadRequest, code: "unsupported_currency"},
+	{err: basket.ErrProductNotFound, status: http.StatusNotFound, code: "product_not_found"},
+	{err: basket.ErrProductNotInBasket, status: http.StatusNotFound, code: "product_not_in_basket"},
+	{err: basket.ErrCouponNotFound, status: http.StatusNotFound, code: "coupon_not_found"},
+	{err: basket.ErrCouponNotInBasket, status: http.StatusNotFound, code: "coupon_not_in_basket"},
+	{err: basket.ErrShippingMethodNotFound, status: http.StatusNotFound, code: "shipping_method_not_found"},
+	{err: admin.ErrCampaignNotFound, status: http.StatusNotFound, code: "campaign_not_found"},
+	{err: basket.ErrProductStockNotEnough, status: http.StatusConflict, code: "out_of_stock"},
+	{err: basket.ErrProductAlreadyInBasket, status: http.StatusConflict, code: "product_already_in_basket"},
+	{err: basket.ErrCouponLimitReached, status: http.StatusConflict, code: "coupon_limit_reached"},
+	{err: basket.ErrCampaignNotAvailable, status: http.StatusConflict, code: "campaign_not_available"},
+	{err: admin.ErrCampaignAlreadyExists, status: http.StatusConflict, code: "campaign_already_exists"},
+	{err: admin.ErrCampaignArchived, status: http.StatusConflict, code: "campaign_archived"},
+	{err: basket.ErrBasketConflict, status: http.StatusConflict, code: "basket_conflict"},
+	{err: basket.ErrBasketVersionMismatch, status: http.StatusPreconditionFailed, code: "basket_version_mismatch"},
+	{err: basket.ErrCouponNotValid, status: http.StatusUnprocessableEntity, code: "coupon_not_valid"},
+	{err: basket.ErrCouponMinBasketAmount, status: http.StatusUnprocessableEntity, code: "coupon_min_basket_amount"},
+	{err: basket.ErrCouponCurrency, status: http.StatusUnprocessableEntity, code: "coupon_currency"},
+	{err: basket.ErrProductPriceNotFound, status: http.StatusUnprocessableEntity, code: "product_price_not_found"},
+	{err: basket.ErrShippingNotAvailable, status: http.StatusUnprocessableEntity, code: "shipping_not_available"},
+	{err: basket.ErrShippingMethodRequired, status: http.StatusUnprocessableEntity, code: "shipping_method_required"},
+	{err: basket.ErrInvalidCampaigns, status: http.StatusUnprocessableEntity, code: "invalid_campaigns"},
+	{err: admin.ErrInvalidCampaign, status: http.StatusUnprocessableEntity, code: "invalid_campaign"},
+	{err: basket.ErrInvalidMergePolicy, status: http.StatusUnprocessableEntity, code: "invalid_merge_policy"},
+	{err: admin.ErrInvalidCampaignStatus, status: http.StatusUnprocessableEntity, code: "invalid_campaign_status"},
+}
+
+// FieldError - represents a field of the request body which failed a validation rule
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// errorStatus - returns the http status and the error code of the given error
+func errorStatus(err error) (int, string) {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		return http.StatusUnprocessableEntity, CodeValidationFailed
+	}
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.err) {
+			return mapping.status, mapping.code
+		}
+	}
+	return http.StatusInternalServerError, CodeInternal
+}
+
+// invalidJSON - wraps the decoding error of a request body, so it is sent as a bad request
+func invalidJSON(err error) error {
+	return fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+}
+
+// newValidator - creates a request validator which names the fields with their json names
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return validate
+}
+
+// fieldErrors - returns the fields which failed a validation rule, nil if the error is not a validation error
+func fieldErrors(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil
+	}
+	fields := make([]FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		field := fieldError.Namespace()
+		// the namespace starts with the name of the request struct
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+		fields = append(fields, FieldError{
+			Field:   field,
+			Rule:    fieldError.Tag(),
+			Param:   fieldError.Param(),
+			Message: fieldMessage(field, fieldError),
+		})
+	}
+	return fields
+}
+
+// fieldMessage - returns a human readable description of the validation rule the field failed
+func fieldMessage(field string, fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "min", "gte":
+		return fmt.Sprintf("%s must be at least %s", field, fieldError.Param())
+	case "max", "lte":
+		return fmt.Sprintf("%s must be at most %s", field, fieldError.Param())
+	case "len":
+		return fmt.Sprintf("%s must have a length of %s", field, fieldError.Param())
+	case "uuid":
+		return fmt.Sprintf("%s must be a valid uuid", field)
+	}
+	return fmt.Sprintf("%s failed the %s rule", field, fieldError.Tag())
+}
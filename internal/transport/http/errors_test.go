@@ -0,0 +1,58 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/basket"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err            error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{err: basket.ErrProductNotFound, expectedStatus: http.StatusNotFound, expectedCode: "product_not_found"},
+		{err: basket.ErrProductStockNotEnough, expectedStatus: http.StatusConflict, expectedCode: "out_of_stock"},
+		{err: fmt.Errorf("%w: unknown rule type", basket.ErrInvalidCampaigns), expectedStatus: http.StatusUnprocessableEntity, expectedCode: "invalid_campaigns"},
+		{err: invalidJSON(fmt.Errorf("unexpected EOF")), expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidJSON},
+		{err: basket.ErrCheckoutBasket, expectedStatus: http.StatusInternalServerError, expectedCode: CodeInternal},
+	}
+	for _, test := range tests {
+		status, code := errorStatus(test.err)
+		if status != test.expectedStatus || code != test.expectedCode {
+			t.Errorf("Expected %v to be sent with %d %s, got %d %s", test.err, test.expectedStatus, test.expectedCode, status, code)
+		}
+	}
+}
+
+func TestSendErrorResponse_ValidationFields(t *testing.T) {
+	err := newValidator().Struct(dto.AddItemToBasketDTO{Quantity: 0})
+	recorder := httptest.NewRecorder()
+	sendErrorResponse(recorder, "Failed to validate request", err)
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status to be 422, got %d", recorder.Code)
+	}
+	var response Response
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Expected error response, got %v", err)
+	}
+	if response.Code != CodeValidationFailed {
+		t.Errorf("Expected code to be %s, got %s", CodeValidationFailed, response.Code)
+	}
+	fields := make(map[string]FieldError)
+	for _, field := range response.Fields {
+		fields[field.Field] = field
+	}
+	if fields["product_id"].Rule != "required" || fields["quantity"].Rule != "gte" {
+		t.Errorf("Expected product id and quantity fields to be invalid, got %+v", response.Fields)
+	}
+	if !strings.Contains(fields["quantity"].Message, "at least 1") {
+		t.Errorf("Expected quantity message to mention the minimum, got %s", fields["quantity"].Message)
+	}
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"fmt"
+	"github.com/erdemcemal/basket-service/internal/basket"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// basketETag - returns the strong entity tag of the basket made of its id and version
+func basketETag(cart dto.ShoppingCartDTO) string {
+	return fmt.Sprintf(`"%s.%d"`, cart.ID, cart.Version)
+}
+
+// parseBasketETag - returns the basket version of a strong entity tag created by basketETag
+func parseBasketETag(etag string) (basket.BasketVersion, bool) {
+	if len(etag) < 2 || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		return basket.BasketVersion{}, false
+	}
+	cartId, version, found := strings.Cut(strings.Trim(etag, `"`), ".")
+	if !found || cartId == "" {
+		return basket.BasketVersion{}, false
+	}
+	number, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return basket.BasketVersion{}, false
+	}
+	return basket.BasketVersion{CartID: cartId, Version: number}, true
+}
+
+// sendBasketResponse - sends the basket with its entity tag, the client sends it back in the If-Match header to change the basket
+// only if it is not changed by another request in the meantime
+func sendBasketResponse(w http.ResponseWriter, cart dto.ShoppingCartDTO) error {
+	w.Header().Set("ETag", basketETag(cart))
+	return sendOkResponse(w, cart)
+}
@@ -0,0 +1,105 @@
+package http
+
+import (
+	"context"
+	"github.com/erdemcemal/basket-service/internal/basket"
+	"github.com/erdemcemal/basket-service/internal/config"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseBasketETag(t *testing.T) {
+	cart := dto.ShoppingCartDTO{ID: "5b0d3e8c-8f0e-4b8a-9a57-5f2a6c1d7e10", Version: 7}
+	tests := []struct {
+		etag     string
+		expected basket.BasketVersion
+		valid    bool
+	}{
+		{etag: basketETag(cart), expected: basket.BasketVersion{CartID: cart.ID, Version: 7}, valid: true},
+		{etag: `W/"5b0d3e8c-8f0e-4b8a-9a57-5f2a6c1d7e10.7"`},
+		{etag: "5b0d3e8c-8f0e-4b8a-9a57-5f2a6c1d7e10.7"},
+		{etag: `"5b0d3e8c-8f0e-4b8a-9a57-5f2a6c1d7e10"`},
+		{etag: `".7"`},
+		{etag: `"5b0d3e8c-8f0e-4b8a-9a57-5f2a6c1d7e10.seven"`},
+		{etag: `"`},
+	}
+	for _, test := range tests {
+		version, valid := parseBasketETag(test.etag)
+		if valid != test.valid || version != test.expected {
+			t.Errorf("Expected %s to be parsed as %+v (%t), got %+v (%t)", test.etag, test.expected, test.valid, version, valid)
+		}
+	}
+}
+
+func TestIfMatchMiddleware(t *testing.T) {
+	called := false
+	handler := IfMatchMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	tests := []struct {
+		ifMatch        string
+		expectedStatus int
+	}{
+		{expectedStatus: http.StatusOK},
+		{ifMatch: "*", expectedStatus: http.StatusOK},
+		{ifMatch: `"5b0d3e8c-8f0e-4b8a-9a57-5f2a6c1d7e10.7"`, expectedStatus: http.StatusOK},
+		{ifMatch: `W/"5b0d3e8c-8f0e-4b8a-9a57-5f2a6c1d7e10.7"`, expectedStatus: http.StatusPreconditionFailed},
+	}
+	for _, test := range tests {
+		called = false
+		request := httptest.NewRequest(http.MethodPut, "/api/v1/basket", nil)
+		if test.ifMatch != "" {
+			request.Header.Set("If-Match", test.ifMatch)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != test.expectedStatus || called != (test.expectedStatus == http.StatusOK) {
+			t.Errorf("Expected If-Match %q to get %d, got %d", test.ifMatch, test.expectedStatus, recorder.Code)
+		}
+	}
+}
+
+// stubService - returns an empty basket, the other service methods are not implemented
+type stubService struct {
+	basket.BasketService
+}
+
+func (s stubService) GetBasket(context.Context, string) (dto.ShoppingCartDTO, error) {
+	return dto.ShoppingCartDTO{}, nil
+}
+
+func TestHandler_IfMatchOnlyOnBasketChanges(t *testing.T) {
+	h := NewHandler(stubService{}, nil, nil, stubVerifier{"shopper": {Subject: "shopper-user"}}, config.ServerConfig{RequestTimeout: config.Duration(time.Second)})
+	tests := []struct {
+		method         string
+		path           string
+		authorization  string
+		expectedStatus int
+	}{
+		{method: http.MethodPost, path: "/api/v1/basket", authorization: "Bearer shopper", expectedStatus: http.StatusPreconditionFailed},
+		{method: http.MethodDelete, path: "/api/v1/basket/coupon", authorization: "Bearer shopper", expectedStatus: http.StatusPreconditionFailed},
+		{method: http.MethodDelete, path: "/api/v1/basket/5b0d3e8c-8f0e-4b8a-9a57-5f2a6c1d7e10", authorization: "Bearer shopper", expectedStatus: http.StatusPreconditionFailed},
+		{method: http.MethodGet, path: "/api/v1/basket/checkout", authorization: "Bearer shopper", expectedStatus: http.StatusPreconditionFailed},
+		// the request is authenticated before its If-Match header is checked
+		{method: http.MethodPost, path: "/api/v1/basket", expectedStatus: http.StatusUnauthorized},
+		{method: http.MethodGet, path: "/api/v1/basket/checkout", expectedStatus: http.StatusUnauthorized},
+		// the routes which don't change the basket don't check If-Match
+		{method: http.MethodGet, path: "/api/v1/basket", authorization: "Bearer shopper", expectedStatus: http.StatusOK},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(test.method, test.path, nil)
+		request.Header.Set("If-Match", `W/"5b0d3e8c-8f0e-4b8a-9a57-5f2a6c1d7e10.7"`)
+		if test.authorization != "" {
+			request.Header.Set("Authorization", test.authorization)
+		}
+		recorder := httptest.NewRecorder()
+		h.Router.ServeHTTP(recorder, request)
+		if recorder.Code != test.expectedStatus {
+			t.Errorf("Expected %s %s to get %d, got %d", test.method, test.path, test.expectedStatus, recorder.Code)
+		}
+	}
+}
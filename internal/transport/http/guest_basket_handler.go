@@ -0,0 +1,91 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/auth"
+	"github.com/erdemcemal/basket-service/internal/dto"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// cartTokenHeader and cartTokenCookie - the guest basket is used with the cart token of the header or the cookie
+	cartTokenHeader = "X-Cart-Token"
+	cartTokenCookie = "cart_token"
+	cartTokenMaxAge = 30 * 24 * time.Hour
+)
+
+// CreateGuestBasket - creates a basket for a guest and returns its cart token, the token is set as a cookie as well
+func (h *Handler) CreateGuestBasket(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.NewCartToken()
+	if err != nil {
+		sendErrorResponse(w, "Failed to create cart token", err)
+		return
+	}
+	guest, err := auth.GuestPrincipal(token)
+	if err != nil {
+		sendErrorResponse(w, "Failed to create cart token", err)
+		return
+	}
+	basket, err := h.service.GetBasket(r.Context(), guest.UserID)
+	if err != nil {
+		sendErrorResponse(w, "Failed to create guest basket", err)
+		return
+	}
+	setCartTokenCookie(w, r, token, int(cartTokenMaxAge.Seconds()))
+	w.Header().Set("ETag", basketETag(basket))
+	if err := sendOkResponse(w, dto.GuestBasketDTO{CartToken: token, Basket: basket}); err != nil {
+		panic(err)
+	}
+}
+
+// MergeBasket - merges the guest basket of the cart token into the basket of the user after login, the cart token cookie is removed
+func (h *Handler) MergeBasket(w http.ResponseWriter, r *http.Request) {
+	token, ok := cartToken(r)
+	if !ok {
+		sendErrorResponse(w, "Failed to merge basket", auth.ErrMissingCartToken)
+		return
+	}
+	guest, err := auth.GuestPrincipal(token)
+	if err != nil {
+		sendErrorResponse(w, "Failed to merge basket", err)
+		return
+	}
+	var merge dto.MergeBasketDTO
+	// the body is optional, the configured merge policy is used without it
+	if err := json.NewDecoder(r.Body).Decode(&merge); err != nil && !errors.Is(err, io.EOF) {
+		sendErrorResponse(w, "Failed to decode JSON Body", invalidJSON(err))
+		return
+	}
+	validate := newValidator()
+	err = validate.Struct(merge)
+	if err != nil {
+		sendErrorResponse(w, "Failed to validate request", err)
+		return
+	}
+	userId := auth.UserID(r.Context())
+	cart, err := h.service.MergeBasket(r.Context(), userId, guest.UserID, merge.Policy)
+	if err != nil {
+		sendErrorResponse(w, "Failed to merge basket", err)
+		return
+	}
+	setCartTokenCookie(w, r, "", -1)
+	if err := sendBasketResponse(w, cart); err != nil {
+		panic(err)
+	}
+}
+
+// setCartTokenCookie - sets the cart token cookie with the given max age in seconds, a negative max age removes the cookie
+func setCartTokenCookie(w http.ResponseWriter, r *http.Request, token string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cartTokenCookie,
+		Value:    token,
+		Path:     "/api/v1",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -2,31 +2,44 @@ package http
 
 import (
 	"encoding/json"
+	"github.com/erdemcemal/basket-service/internal/admin"
+	"github.com/erdemcemal/basket-service/internal/auth"
 	"github.com/erdemcemal/basket-service/internal/basket"
+	"github.com/erdemcemal/basket-service/internal/config"
+	"github.com/erdemcemal/basket-service/internal/customer"
 	"github.com/gorilla/mux"
 	"net/http"
+	"time"
 )
 
 // Handler - is a http handler for the basket service
 type Handler struct {
-	Router  *mux.Router
-	service basket.BasketService
-	server  *http.Server
+	Router          *mux.Router
+	service         basket.BasketService
+	campaignService admin.CampaignService
+	customerService customer.CustomerService
+	// verifier - verifies the bearer tokens of the authenticated routes
+	verifier auth.TokenVerifier
+	server   *http.Server
 }
 
-// NewHandler - creates a new handler with the given service
-func NewHandler(service basket.BasketService) *Handler {
+// NewHandler - creates a new handler with the given services, token verifier and server settings
+func NewHandler(service basket.BasketService, campaignService admin.CampaignService, customerService customer.CustomerService, verifier auth.TokenVerifier, cfg config.ServerConfig) *Handler {
 	h := &Handler{
-		service: service,
+		service:         service,
+		campaignService: campaignService,
+		customerService: customerService,
+		verifier:        verifier,
 	}
 	h.Router = mux.NewRouter()
 	h.Router.Use(JSONMiddleware)
 	h.Router.Use(LoggingMiddleware)
-	h.Router.Use(TimeoutMiddleware)
+	h.Router.Use(TimeoutMiddleware(time.Duration(cfg.RequestTimeout)))
+	h.Router.Use(CurrencyMiddleware)
 	h.mapRoutes()
 
 	h.server = &http.Server{
-		Addr:    "0.0.0.0:8080",
+		Addr:    cfg.Address,
 		Handler: h.Router,
 	}
 	return h
@@ -40,15 +53,44 @@ func (h *Handler) Serve() error {
 	return nil
 }
 
-// mapRoutes - maps the routes to the handler
+// mapRoutes - maps the routes to the handler, basket routes are allowed for every authenticated user on their own basket
+// and for guests on the basket of their cart token, admin routes only for the given roles, admins are allowed everywhere
 func (h *Handler) mapRoutes() {
 	h.Router.HandleFunc("/alive", h.AliveCheck).Methods("GET")
 	h.Router.HandleFunc("/api/v1/products", h.GetProducts).Methods("GET")
-	h.Router.HandleFunc("/api/v1/basket", Auth(h.GetBasket)).Methods("GET")
-	h.Router.HandleFunc("/api/v1/basket", Auth(h.AddItemToBasket)).Methods("POST")
-	h.Router.HandleFunc("/api/v1/basket/{productId}", Auth(h.RemoveItemFromBasket)).Methods("DELETE")
-	h.Router.HandleFunc("/api/v1/basket", Auth(h.UpdateItemInBasket)).Methods("PUT")
-	h.Router.HandleFunc("/api/v1/basket/checkout", Auth(h.CheckoutBasket)).Methods("GET")
+	h.Router.HandleFunc("/api/v1/basket", h.Shopper(h.GetBasket)).Methods("GET")
+	h.Router.HandleFunc("/api/v1/basket/shipping-methods", h.Shopper(h.GetShippingMethods)).Methods("GET")
+	h.Router.HandleFunc("/api/v1/basket/guest", h.CreateGuestBasket).Methods("POST")
+	h.Router.HandleFunc("/api/v1/basket/merge", h.Auth(h.MergeBasket)).Methods("POST")
+	// the trace shows why the campaigns are rejected, so it is only for merchandisers who check the campaigns on their own basket
+	h.Router.HandleFunc("/api/v1/basket/discount-trace", h.Auth(h.Authorize(h.GetDiscountTrace, auth.RoleMerchandiser))).Methods("GET")
+	h.mapBasketChangeRoutes()
+	h.Router.HandleFunc("/api/v1/campaigns/simulate", h.Auth(h.Authorize(h.SimulateCampaigns, auth.RoleMerchandiser))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/customer/profile", h.Auth(h.GetProfile)).Methods("GET")
+	h.Router.HandleFunc("/api/v1/admin/campaigns", h.Auth(h.Authorize(h.GetCampaigns, auth.RoleMerchandiser))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/admin/campaigns", h.Auth(h.Authorize(h.CreateCampaign, auth.RoleMerchandiser))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/admin/campaigns/{campaignId}", h.Auth(h.Authorize(h.GetCampaign, auth.RoleMerchandiser))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/admin/campaigns/{campaignId}/enable", h.Auth(h.Authorize(h.EnableCampaign, auth.RoleMerchandiser))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/admin/campaigns/{campaignId}/disable", h.Auth(h.Authorize(h.DisableCampaign, auth.RoleMerchandiser))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/admin/campaigns/{campaignId}/archive", h.Auth(h.Authorize(h.ArchiveCampaign, auth.RoleMerchandiser))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/admin/customers/{userId}", h.Auth(h.Authorize(h.GetCustomer, auth.RoleSupport))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/admin/customers/{userId}", h.Auth(h.Authorize(h.UpdateCustomer, auth.RoleAdmin))).Methods("PUT")
+	h.Router.HandleFunc("/api/v1/admin/baskets/{userId}", h.Auth(h.Authorize(h.GetUserBasket, auth.RoleSupport))).Methods("GET")
+	h.Router.HandleFunc("/api/v1/admin/products/{productId}/stock", h.Auth(h.Authorize(h.UpdateProductStock, auth.RoleAdmin))).Methods("PUT")
+}
+
+// mapBasketChangeRoutes - maps the routes which change the basket, their If-Match header is checked after the request is authenticated
+func (h *Handler) mapBasketChangeRoutes() {
+	h.Router.HandleFunc("/api/v1/basket", h.Shopper(IfMatch(h.AddItemToBasket))).Methods("POST")
+	h.Router.HandleFunc("/api/v1/basket/coupon", h.Shopper(IfMatch(h.ApplyCoupon))).Methods("POST")
+	// coupon route is registered before the product route, so "coupon" is not matched as a product id
+	h.Router.HandleFunc("/api/v1/basket/coupon", h.Shopper(IfMatch(h.RemoveCoupon))).Methods("DELETE")
+	h.Router.HandleFunc("/api/v1/basket/shipping", h.Shopper(IfMatch(h.SelectShippingMethod))).Methods("PUT")
+	h.Router.HandleFunc("/api/v1/basket/currency", h.Shopper(IfMatch(h.SetBasketCurrency))).Methods("PUT")
+	h.Router.HandleFunc("/api/v1/basket/{productId}", h.Shopper(IfMatch(h.RemoveItemFromBasket))).Methods("DELETE")
+	h.Router.HandleFunc("/api/v1/basket", h.Shopper(IfMatch(h.UpdateItemInBasket))).Methods("PUT")
+	// guests log in to check out, so the guest basket is merged into the basket of the user first
+	h.Router.HandleFunc("/api/v1/basket/checkout", h.Auth(IfMatch(h.CheckoutBasket))).Methods("GET")
 }
 
 // AliveCheck - checks if service is alive
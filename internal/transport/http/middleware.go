@@ -2,12 +2,20 @@ package http
 
 import (
 	"context"
-	"github.com/gofrs/uuid"
+	"errors"
+	"github.com/erdemcemal/basket-service/internal/auth"
+	"github.com/erdemcemal/basket-service/internal/basket"
+	"github.com/gorilla/mux"
 	log "github.com/siruspen/logrus"
 	"net/http"
+	"strings"
 	"time"
 )
 
+var (
+	ErrForbidden = errors.New("user is not allowed to perform the action")
+)
+
 func JSONMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
@@ -27,32 +35,165 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-func TimeoutMiddleware(next http.Handler) http.Handler {
+// TimeoutMiddleware - cancels the request context after the given timeout
+func TimeoutMiddleware(timeout time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// CurrencyMiddleware - puts the currency selected by the client with the currency header in the request context,
+// the basket is priced in it instead of its current currency
+func CurrencyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if currency := r.Header.Get("currency"); currency != "" {
+			r = r.WithContext(basket.WithCurrency(r.Context(), currency))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// IfMatchMiddleware - puts the basket version of the If-Match header in the request context, the basket is only read or changed
+// if it still has that version, a header which is not a basket entity tag never matches
+func IfMatchMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
-		defer cancel()
-		r = r.WithContext(ctx)
+		if ifMatch := strings.TrimSpace(r.Header.Get("If-Match")); ifMatch != "" && ifMatch != "*" {
+			version, ok := parseBasketETag(ifMatch)
+			if !ok {
+				sendErrorResponse(w, "If-Match header is not a basket ETag", basket.ErrBasketVersionMismatch)
+				return
+			}
+			r = r.WithContext(basket.WithExpectedVersion(r.Context(), version))
+		}
 		next.ServeHTTP(w, r)
 	})
 }
 
-// Auth - checks if user id is present in request header (jwt implementation can be added later)
-func Auth(original func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+// IfMatch - wraps the handler with the If-Match middleware, so the header is checked inside the authentication of the route
+func IfMatch(original func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return IfMatchMiddleware(http.HandlerFunc(original)).ServeHTTP
+}
+
+// Auth - authenticates the request with the bearer token of the authorization header
+// and puts the subject of the token in the request context as the user id
+func (h *Handler) Auth(original func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("user_id")
-		if authHeader == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			log.Error("user_id is not present in request header" + authHeader)
+		principal, ok := h.authenticate(w, r)
+		if !ok {
 			return
-		} else {
-			// if valid uuid is not present in request header, return error
-			if _, err := uuid.FromString(authHeader); err != nil {
-				w.WriteHeader(http.StatusUnauthorized)
-				log.Error("user_id is not a valid uuid" + authHeader)
+		}
+		original(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// Shopper - authenticates the request with the bearer token like Auth, requests without a bearer token are made by guests
+// with the cart token of their basket, the hash of the cart token is put in the request context as the user id
+func (h *Handler) Shopper(original func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, hasBearer := bearerToken(r); !hasBearer {
+			if token, hasCartToken := cartToken(r); hasCartToken {
+				principal, err := auth.GuestPrincipal(token)
+				if err != nil {
+					sendErrorResponse(w, "Failed to authenticate request", err)
+					return
+				}
+				original(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
 				return
 			}
-			original(w, r)
 		}
+		h.Auth(original)(w, r)
+	}
+}
+
+// authenticate - verifies the bearer token of the request and returns the user of its claims,
+// the error response is sent if the request is not authenticated
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (auth.Principal, bool) {
+	token, ok := bearerToken(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="basket-service"`)
+		sendErrorResponse(w, "Failed to authenticate request", auth.ErrMissingToken)
+		return auth.Principal{}, false
+	}
+	claims, err := h.verifier.Verify(r.Context(), token)
+	if err != nil {
+		log.WithField("path", r.URL.Path).Warnf("rejected bearer token: %v", err)
+		if errors.Is(err, auth.ErrInvalidToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="basket-service", error="invalid_token"`)
+		}
+		sendErrorResponse(w, "Failed to authenticate request", err)
+		return auth.Principal{}, false
+	}
+	return auth.NewPrincipal(claims), true
+}
+
+// Authorize - allows the request only for the users with one of the given roles, it is used inside Auth,
+// every privileged request is written to the audit log with the user and the response status
+func (h *Handler) Authorize(original func(w http.ResponseWriter, r *http.Request), roles ...auth.Role) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		principal, _ := auth.PrincipalFrom(r.Context())
+		defer audit(r, principal, recorder)
+		if !principal.HasAnyRole(roles...) {
+			sendErrorResponse(recorder, "Failed to authorize request", ErrForbidden)
+			return
+		}
+		original(recorder, r)
+	}
+}
+
+// audit - writes the privileged request of the user and its response status to the audit log
+func audit(r *http.Request, principal auth.Principal, recorder *statusRecorder) {
+	route := r.URL.Path
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			route = template
+		}
+	}
+	log.WithFields(log.Fields{
+		"audit":  true,
+		"actor":  principal.UserID,
+		"roles":  principal.Roles,
+		"method": r.Method,
+		"route":  route,
+		"target": mux.Vars(r),
+		"status": recorder.status,
+	}).Info("privileged action")
+}
+
+// statusRecorder - keeps the status code written to the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader - keeps the status code and writes it to the response
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// bearerToken - returns the bearer token of the authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// cartToken - returns the cart token of the cart token header or the cart token cookie
+func cartToken(r *http.Request) (string, bool) {
+	if token := strings.TrimSpace(r.Header.Get(cartTokenHeader)); token != "" {
+		return token, true
+	}
+	if cookie, err := r.Cookie(cartTokenCookie); err == nil && cookie.Value != "" {
+		return cookie.Value, true
 	}
+	return "", false
 }
@@ -0,0 +1,137 @@
+package http
+
+import (
+	"context"
+	"github.com/erdemcemal/basket-service/internal/auth"
+	"github.com/erdemcemal/basket-service/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// stubVerifier - accepts the tokens which are the key of its claims
+type stubVerifier map[string]auth.Claims
+
+func (v stubVerifier) Verify(_ context.Context, token string) (auth.Claims, error) {
+	claims, ok := v[token]
+	if !ok {
+		return auth.Claims{}, auth.ErrInvalidSignature
+	}
+	return claims, nil
+}
+
+func TestHandler_AuthAndAuthorize(t *testing.T) {
+	h := &Handler{verifier: stubVerifier{
+		"shopper": {Subject: "shopper-user"},
+		"support": {Subject: "support-user", Roles: []auth.Role{auth.RoleSupport}},
+		"admin":   {Subject: "admin-user", Roles: []auth.Role{auth.RoleAdmin}},
+	}}
+	var userId string
+	handler := h.Auth(h.Authorize(func(w http.ResponseWriter, r *http.Request) {
+		userId = auth.UserID(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}, auth.RoleSupport))
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+		expectedUser   string
+	}{
+		{name: "without token", expectedStatus: http.StatusUnauthorized},
+		{name: "invalid token", authorization: "Bearer forged", expectedStatus: http.StatusUnauthorized},
+		{name: "shopper", authorization: "Bearer shopper", expectedStatus: http.StatusForbidden},
+		{name: "support", authorization: "Bearer support", expectedStatus: http.StatusOK, expectedUser: "support-user"},
+		{name: "admin", authorization: "bearer admin", expectedStatus: http.StatusOK, expectedUser: "admin-user"},
+	}
+	for _, test := range tests {
+		userId = ""
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/admin/baskets/user", nil)
+		if test.authorization != "" {
+			request.Header.Set("Authorization", test.authorization)
+		}
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		if recorder.Code != test.expectedStatus || userId != test.expectedUser {
+			t.Errorf("Expected %s to get %d as %q, got %d as %q", test.name, test.expectedStatus, test.expectedUser, recorder.Code, userId)
+		}
+	}
+}
+
+func TestHandler_DiscountTraceOnlyForMerchandisers(t *testing.T) {
+	h := NewHandler(nil, nil, nil, stubVerifier{"shopper": {Subject: "shopper-user"}}, config.ServerConfig{RequestTimeout: config.Duration(time.Second)})
+	token, err := auth.NewCartToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		authorization  string
+		cartToken      string
+		expectedStatus int
+	}{
+		{name: "guest", cartToken: token, expectedStatus: http.StatusUnauthorized},
+		{name: "shopper", authorization: "Bearer shopper", expectedStatus: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/basket/discount-trace", nil)
+		if test.authorization != "" {
+			request.Header.Set("Authorization", test.authorization)
+		}
+		if test.cartToken != "" {
+			request.Header.Set(cartTokenHeader, test.cartToken)
+		}
+		recorder := httptest.NewRecorder()
+		h.Router.ServeHTTP(recorder, request)
+		if recorder.Code != test.expectedStatus {
+			t.Errorf("Expected %s to get %d, got %d", test.name, test.expectedStatus, recorder.Code)
+		}
+	}
+}
+
+func TestHandler_Shopper(t *testing.T) {
+	h := &Handler{verifier: stubVerifier{"shopper": {Subject: "shopper-user"}}}
+	var principal auth.Principal
+	handler := h.Shopper(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = auth.PrincipalFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	})
+	token, err := auth.NewCartToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	guest, _ := auth.GuestPrincipal(token)
+	tests := []struct {
+		name           string
+		authorization  string
+		cartToken      string
+		cookie         string
+		expectedStatus int
+		expectedUser   string
+	}{
+		{name: "without tokens", expectedStatus: http.StatusUnauthorized},
+		{name: "cart token header", cartToken: token, expectedStatus: http.StatusOK, expectedUser: guest.UserID},
+		{name: "cart token cookie", cookie: token, expectedStatus: http.StatusOK, expectedUser: guest.UserID},
+		{name: "invalid cart token", cartToken: "forged", expectedStatus: http.StatusUnauthorized},
+		{name: "bearer and cart token", authorization: "Bearer shopper", cartToken: token, expectedStatus: http.StatusOK, expectedUser: "shopper-user"},
+		{name: "invalid bearer and cart token", authorization: "Bearer forged", cartToken: token, expectedStatus: http.StatusUnauthorized},
+	}
+	for _, test := range tests {
+		principal = auth.Principal{}
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/basket", nil)
+		if test.authorization != "" {
+			request.Header.Set("Authorization", test.authorization)
+		}
+		if test.cartToken != "" {
+			request.Header.Set(cartTokenHeader, test.cartToken)
+		}
+		if test.cookie != "" {
+			request.AddCookie(&http.Cookie{Name: cartTokenCookie, Value: test.cookie})
+		}
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		if recorder.Code != test.expectedStatus || principal.UserID != test.expectedUser {
+			t.Errorf("Expected %s to get %d as %q, got %d as %q", test.name, test.expectedStatus, test.expectedUser, recorder.Code, principal.UserID)
+		}
+	}
+}